@@ -12,6 +12,8 @@ type Config struct {
 	env string
 	// Server
 	Port string
+	// SiteURL is the address of the website, used in links to book pages
+	SiteURL string
 
 	// Database
 	DBHost     string
@@ -32,6 +34,34 @@ type Config struct {
 	SMTPUsername string
 	SMTPPassword string
 	AdminEmail   string
+
+	// Book metadata import
+	GoogleBooksAPIKey  string
+	MetadataFixtureDir string
+
+	// Book copies
+	// AccessionPrefix and AccessionDigits format the accession numbers drawn
+	// from the book_copy_accession_seq sequence, e.g. PSC000042
+	AccessionPrefix string
+	AccessionDigits int
+
+	// Background jobs
+	JobsEnabled           bool
+	OrphanCleanupSchedule string
+	CoBorrowSchedule      string
+	TrashPurgeSchedule    string
+	TrashRetentionDays    int
+
+	// Blob storage
+	BlobStore     string
+	BlobLocalDir  string
+	BlobPublicURL string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3UseSSL      bool
 }
 
 func (c Config) IsProd() bool {
@@ -48,6 +78,7 @@ func init() {
 	AppConfig.env = getEnv("ENV", "prod")
 	// Server
 	AppConfig.Port = getEnv("PORT", "8080")
+	AppConfig.SiteURL = getEnv("SITE_URL", "http://localhost:3000")
 
 	// Database
 	AppConfig.DBHost = getEnv("DB_HOST", "localhost")
@@ -68,6 +99,35 @@ func init() {
 	AppConfig.SMTPUsername = getEnv("SMTP_USERNAME", "your_email@gmail.com")
 	AppConfig.SMTPPassword = getEnv("SMTP_PASSWORD", "your_app_password")
 	AppConfig.AdminEmail = getEnv("ADMIN_EMAIL", "admin@example.com")
+
+	// Book metadata import
+	AppConfig.GoogleBooksAPIKey = getEnv("GOOGLE_BOOKS_API_KEY", "")
+	AppConfig.MetadataFixtureDir = getEnv("METADATA_FIXTURE_DIR", "")
+
+	// Book copies
+	AppConfig.AccessionPrefix = getEnv("ACCESSION_PREFIX", "PSC")
+	AppConfig.AccessionDigits = getEnvAsInt("ACCESSION_DIGITS", 6)
+
+	// Background jobs
+	AppConfig.JobsEnabled = getEnv("JOBS_ENABLED", "true") == "true"
+	AppConfig.OrphanCleanupSchedule = getEnv("ORPHAN_CLEANUP_SCHEDULE", "0 3 * * *")
+	AppConfig.CoBorrowSchedule = getEnv("CO_BORROW_SCHEDULE", "30 3 * * *")
+	AppConfig.TrashPurgeSchedule = getEnv("TRASH_PURGE_SCHEDULE", "0 4 * * *")
+	AppConfig.TrashRetentionDays = getEnvAsInt("TRASH_RETENTION_DAYS", 30)
+
+	// Blob storage
+	AppConfig.BlobStore = getEnv("BLOB_STORE", "local")
+	AppConfig.BlobLocalDir = getEnv("BLOB_LOCAL_DIR", "./uploads")
+	AppConfig.BlobPublicURL = getEnv("BLOB_PUBLIC_URL", "")
+	if AppConfig.BlobPublicURL == "" && AppConfig.BlobStore == "local" {
+		AppConfig.BlobPublicURL = "http://localhost:" + AppConfig.Port + "/uploads"
+	}
+	AppConfig.S3Endpoint = getEnv("S3_ENDPOINT", "s3.amazonaws.com")
+	AppConfig.S3Region = getEnv("S3_REGION", "ap-southeast-1")
+	AppConfig.S3Bucket = getEnv("S3_BUCKET", "pscit")
+	AppConfig.S3AccessKey = getEnv("S3_ACCESS_KEY", "")
+	AppConfig.S3SecretKey = getEnv("S3_SECRET_KEY", "")
+	AppConfig.S3UseSSL = getEnv("S3_USE_SSL", "true") == "true"
 }
 
 func getEnv(key, defaultValue string) string {
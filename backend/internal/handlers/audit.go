@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLogs browses the change history (admin only)
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	var filters models.AuditLogFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		zap.L().Error("GetAuditLogs: Invalid filters", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs, total, err := h.auditService.GetAuditLogs(filters, page, limit)
+	if err != nil {
+		zap.L().Error("GetAuditLogs: Failed to get audit logs", zap.Error(err))
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":  logs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetEntityHistory lists the changes made to one book, copy, author,
+// category, tag or reservation (admin only)
+func (h *AuditHandler) GetEntityHistory(c *gin.Context) {
+	entityType := c.Param("type")
+	id := c.Param("id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	logs, total, err := h.auditService.GetEntityHistory(entityType, id, page, limit)
+	if err != nil {
+		zap.L().Error("GetEntityHistory: Failed to get entity history", zap.String("type", entityType), zap.String("id", id), zap.Error(err))
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":  logs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// currentActor identifies the authenticated user making a change, for the audit log
+func currentActor(c *gin.Context) models.Actor {
+	actor := models.Actor{Email: c.GetString("email")}
+	if id, err := uuid.Parse(c.GetString("userID")); err == nil {
+		actor.UserID = &id
+	}
+	return actor
+}
+
+func respondAuditError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnknownAuditEntity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -51,7 +51,7 @@ func (h *AuthorHandler) CreateAuthor(c *gin.Context) {
 		return
 	}
 
-	if err := h.authorService.CreateAuthor(&author); err != nil {
+	if err := h.authorService.CreateAuthor(&author, currentActor(c)); err != nil {
 		zap.L().Error("CreateAuthor: Failed to create author", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -69,7 +69,7 @@ func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
 		return
 	}
 
-	if err := h.authorService.UpdateAuthor(id, &author); err != nil {
+	if err := h.authorService.UpdateAuthor(id, &author, currentActor(c)); err != nil {
 		zap.L().Error("UpdateAuthor: Failed to update author", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -80,7 +80,7 @@ func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
 
 func (h *AuthorHandler) DeleteAuthor(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.authorService.DeleteAuthor(id); err != nil {
+	if err := h.authorService.DeleteAuthor(id, currentActor(c)); err != nil {
 		zap.L().Error("DeleteAuthor: Failed to delete author", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -11,6 +12,7 @@ import (
 	"github.com/hungcq/pscit/backend/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -27,40 +29,87 @@ func NewBookHandler(bookService *services.BookService, db *gorm.DB) *BookHandler
 	}
 }
 
+// GetBooks lists books page by page. Passing a "cursor" parameter, empty for
+// the first page, switches from page numbers to keyset pagination.
 func (h *BookHandler) GetBooks(c *gin.Context) {
-	query := c.Query("query")
-	category := c.Query("category")
-	author, err := url.QueryUnescape(c.Query("author"))
+	filters, err := bookFiltersFromQuery(c)
 	if err != nil {
 		zap.L().Error("GetBooks: Invalid author parameter", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid author parameter"})
 		return
 	}
-	language := c.Query("language")
-	tagKey := c.Query("tag_key")
-	availableStr := c.Query("available")
-	available := false
-	if availableStr == "true" {
-		available = true
-	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	sortField := c.Query("sortField")
 	sortOrder := c.Query("sortOrder")
 
-	books, total, err := h.bookService.GetBooks(query, category, author, language, tagKey, available, page, limit, sortField, sortOrder)
+	var response gin.H
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		books, cursors, err := h.bookService.GetBooksByCursor(filters, cursor, limit, sortField, sortOrder)
+		if err != nil {
+			zap.L().Error("GetBooks: Failed to get books by cursor", zap.Error(err))
+			respondCursorError(c, err)
+			return
+		}
+		response = gin.H{
+			"books":       books,
+			"limit":       limit,
+			"next_cursor": cursors.NextCursor,
+			"prev_cursor": cursors.PrevCursor,
+		}
+	} else {
+		books, total, err := h.bookService.GetBooks(filters, page, limit, sortField, sortOrder)
+		if err != nil {
+			zap.L().Error("GetBooks: Failed to get books", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response = gin.H{
+			"books": books,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		}
+	}
+
+	facets, err := h.bookService.GetBookFacets(filters)
 	if err != nil {
-		zap.L().Error("GetBooks: Failed to get books", zap.Error(err))
+		zap.L().Error("GetBooks: Failed to get facets", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	response["facets"] = facets
 
-	c.JSON(http.StatusOK, gin.H{
-		"books": books,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	c.JSON(http.StatusOK, response)
+}
+
+func respondCursorError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// bookFiltersFromQuery reads the catalog filters shared by the listing and export endpoints
+func bookFiltersFromQuery(c *gin.Context) (models.BookFilters, error) {
+	author, err := url.QueryUnescape(c.Query("author"))
+	if err != nil {
+		return models.BookFilters{}, err
+	}
+	return models.BookFilters{
+		Query:     c.Query("query"),
+		Category:  c.Query("category"),
+		Author:    author,
+		Language:  c.Query("language"),
+		TagKey:    c.Query("tag_key"),
+		Format:    c.Query("format"),
+		Available: c.Query("available") == "true",
+		// include_subcategories=true also matches books in subcategories of the category
+		IncludeSubcategories: c.Query("include_subcategories") == "true",
+		// collapse=work shows one edition per work
+		CollapseWorks: c.Query("collapse") == "work",
+	}, nil
 }
 
 func (h *BookHandler) GetBook(c *gin.Context) {
@@ -72,9 +121,41 @@ func (h *BookHandler) GetBook(c *gin.Context) {
 		return
 	}
 
+	editions, err := h.bookService.GetBookEditions(book)
+	if err != nil {
+		zap.L().Error("GetBook: Failed to get editions", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	book.Editions = editions
+
 	c.JSON(http.StatusOK, book)
 }
 
+// GetSimilarBooks lists the books sharing the most authors, categories and
+// tags with a book. "limit" caps the result and "available=true" keeps only
+// books with a copy available.
+func (h *BookHandler) GetSimilarBooks(c *gin.Context) {
+	id := c.Param("id")
+	limit, ok := limitParam(c, services.DefaultSimilarBooksLimit, services.MaxSimilarBooksLimit)
+	if !ok {
+		return
+	}
+
+	books, err := h.bookService.GetSimilarBooks(id, limit, c.Query("available") == "true")
+	if err != nil {
+		zap.L().Error("GetSimilarBooks: Failed to get similar books", zap.String("id", id), zap.Error(err))
+		if err.Error() == "book not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
+
 func (h *BookHandler) CreateBook(c *gin.Context) {
 	var req models.CreateBookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -97,6 +178,13 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		MainImage:      req.MainImage,
 		Format:         req.Format,
 	}
+	workID, err := parseWorkID(req.WorkID)
+	if err != nil {
+		zap.L().Error("CreateBook: Invalid work ID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": gin.H{"work_id": "invalid work ID"}})
+		return
+	}
+	book.WorkID = workID
 
 	// Load authors
 	book.Authors = make([]models.Author, len(req.AuthorIDs))
@@ -134,9 +222,9 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		book.Tags[i] = *tag
 	}
 
-	if err := h.bookService.CreateBook(book); err != nil {
+	if err := h.bookService.CreateBook(book, currentActor(c)); err != nil {
 		zap.L().Error("CreateBook: Failed to create book", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondBookError(c, err)
 		return
 	}
 
@@ -172,6 +260,14 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 	existingBook.GoogleVolumeID = req.GoogleVolumeID
 	existingBook.MainImage = req.MainImage
 	existingBook.Format = req.Format
+	// The book keeps its work unless work_id is sent; an empty one detaches it
+	if req.WorkID != nil {
+		if existingBook.WorkID, err = parseWorkID(req.WorkID); err != nil {
+			zap.L().Error("UpdateBook: Invalid work ID", zap.String("id", id), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": gin.H{"work_id": "invalid work ID"}})
+			return
+		}
+	}
 
 	// Load authors
 	existingBook.Authors = make([]models.Author, len(req.AuthorIDs))
@@ -209,18 +305,64 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		existingBook.Tags[i] = *tag
 	}
 
-	if err := h.bookService.UpdateBook(id, existingBook); err != nil {
+	if err := h.bookService.UpdateBook(id, existingBook, req.WorkID != nil, currentActor(c)); err != nil {
 		zap.L().Error("UpdateBook: Failed to update book", zap.String("id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondBookError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, existingBook)
 }
 
+// BulkCreateBooks creates every book of a JSON array of books, or none of them
+// when one fails. Authors, categories and tags are linked by the IDs on the
+// books. For imports by name with a per-row report, see BookImportHandler.ImportFile.
+func (h *BookHandler) BulkCreateBooks(c *gin.Context) {
+	var books []models.Book
+	if err := c.ShouldBindJSON(&books); err != nil {
+		zap.L().Error("BulkCreateBooks: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.bookService.BulkCreateBooks(books, currentActor(c)); err != nil {
+		zap.L().Error("BulkCreateBooks: Failed to bulk create books", zap.Error(err))
+		respondBookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Books created successfully",
+		"count":   len(books),
+	})
+}
+
+// parseWorkID converts the optional work_id of a book request
+func parseWorkID(workID *string) (*uuid.UUID, error) {
+	if workID == nil || *workID == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(*workID)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// respondBookError reports field validation failures as a 400 with a message
+// per field, and anything else as a server error
+func respondBookError(c *gin.Context, err error) {
+	var fieldErrors services.FieldErrors
+	if errors.As(err, &fieldErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
 func (h *BookHandler) DeleteBook(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.bookService.DeleteBook(id); err != nil {
+	if err := h.bookService.DeleteBook(id, currentActor(c)); err != nil {
 		zap.L().Error("DeleteBook: Failed to delete book", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -231,32 +373,62 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 
 func (h *BookHandler) SearchBooks(c *gin.Context) {
 	query := c.Query("q")
-	books, err := h.bookService.SearchBooks(query)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	results, err := h.bookService.SearchBooks(query, limit)
 	if err != nil {
 		zap.L().Error("SearchBooks: Failed to search books", zap.String("query", query), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, books)
+	c.JSON(http.StatusOK, results)
 }
 
-func (h *BookHandler) BulkCreateBooks(c *gin.Context) {
-	var books []models.Book
-	if err := c.ShouldBindJSON(&books); err != nil {
-		zap.L().Error("BulkCreateBooks: Invalid request body", zap.Error(err))
+// ExportBooks streams the books matching the listing filters as a file download.
+// The export format is chosen with the "export_format" parameter (csv, jsonl,
+// marc or bibtex), since "format" already filters on the book format.
+func (h *BookHandler) ExportBooks(c *gin.Context) {
+	filters, err := bookFiltersFromQuery(c)
+	if err != nil {
+		zap.L().Error("ExportBooks: Invalid author parameter", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid author parameter"})
+		return
+	}
+
+	format := c.DefaultQuery("export_format", services.ExportFormatCSV)
+	contentType, extension, err := services.ExportContentType(format)
+	if err != nil {
+		zap.L().Error("ExportBooks: Unsupported export format", zap.String("format", format))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.bookService.BulkCreateBooks(books); err != nil {
-		zap.L().Error("BulkCreateBooks: Failed to bulk create books", zap.Error(err))
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, services.ExportFileName(extension)))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged and the stream cut short
+	if err := h.bookService.ExportBooks(filters, format, c.Writer); err != nil {
+		zap.L().Error("ExportBooks: Failed to export books", zap.String("format", format), zap.Error(err))
+		c.Abort()
+	}
+}
+
+// NormalizeISBNs rewrites every stored ISBN into its normalized form and
+// reports the books that could not be updated. Pass dry_run=true to only get
+// the report.
+func (h *BookHandler) NormalizeISBNs(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.bookService.NormalizeStoredISBNs(dryRun, currentActor(c))
+	if err != nil {
+		zap.L().Error("NormalizeISBNs: Failed to normalize ISBNs", zap.Bool("dryRun", dryRun), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Books created successfully",
-		"count":   len(books),
-	})
+	c.JSON(http.StatusOK, report)
 }
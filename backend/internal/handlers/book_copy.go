@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/hungcq/pscit/backend/internal/models"
@@ -48,6 +48,24 @@ func (h *BookCopyHandler) GetBookCopy(c *gin.Context) {
 	c.JSON(http.StatusOK, copy)
 }
 
+// GetBookCopyByAccessionNumber looks up a copy by the accession number
+// scanned from its label
+func (h *BookCopyHandler) GetBookCopyByAccessionNumber(c *gin.Context) {
+	number := c.Param("number")
+	copy, err := h.bookCopyService.GetBookCopyByAccessionNumber(number)
+	if err != nil {
+		zap.L().Error("GetBookCopyByAccessionNumber: Failed to get book copy", zap.String("accessionNumber", number), zap.Error(err))
+		if err.Error() == "book copy not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, copy)
+}
+
 // CreateBookCopy creates a new book copy
 func (h *BookCopyHandler) CreateBookCopy(c *gin.Context) {
 	var copy models.BookCopy
@@ -59,9 +77,9 @@ func (h *BookCopyHandler) CreateBookCopy(c *gin.Context) {
 	bookId := c.Param("bookId")
 	copy.BookID = uuid.MustParse(bookId)
 
-	if err := h.bookCopyService.CreateBookCopy(&copy); err != nil {
+	if err := h.bookCopyService.CreateBookCopy(&copy, currentActor(c)); err != nil {
 		zap.L().Error("CreateBookCopy: Failed to create book copy", zap.String("bookID", bookId), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondBookCopyError(c, err)
 		return
 	}
 
@@ -78,7 +96,7 @@ func (h *BookCopyHandler) UpdateBookCopy(c *gin.Context) {
 		return
 	}
 
-	if err := h.bookCopyService.UpdateBookCopy(id, &copy); err != nil {
+	if err := h.bookCopyService.UpdateBookCopy(id, &copy, currentActor(c)); err != nil {
 		zap.L().Error("UpdateBookCopy: Failed to update book copy", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -90,7 +108,7 @@ func (h *BookCopyHandler) UpdateBookCopy(c *gin.Context) {
 // DeleteBookCopy deletes a book copy
 func (h *BookCopyHandler) DeleteBookCopy(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.bookCopyService.DeleteBookCopy(id); err != nil {
+	if err := h.bookCopyService.DeleteBookCopy(id, currentActor(c)); err != nil {
 		zap.L().Error("DeleteBookCopy: Failed to delete book copy", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -118,7 +136,7 @@ func (h *BookCopyHandler) BulkCreateBookCopies(c *gin.Context) {
 		request.Condition = models.ConditionNew
 	}
 
-	if err := h.bookCopyService.BulkCreateBookCopies(bookID, request.Count, request.Condition); err != nil {
+	if err := h.bookCopyService.BulkCreateBookCopies(bookID, request.Count, request.Condition, currentActor(c)); err != nil {
 		zap.L().Error("BulkCreateBookCopies: Failed to bulk create book copies", zap.String("bookID", bookID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -130,21 +148,49 @@ func (h *BookCopyHandler) BulkCreateBookCopies(c *gin.Context) {
 	})
 }
 
-// UpdateBookCopyAvailability updates a book copy's availability status
-func (h *BookCopyHandler) UpdateBookCopyAvailability(c *gin.Context) {
+// ChangeBookCopyStatus moves a copy in or out of circulation
+func (h *BookCopyHandler) ChangeBookCopyStatus(c *gin.Context) {
 	id := c.Param("id")
-	available, err := strconv.ParseBool(c.PostForm("available"))
+	var req models.ChangeBookCopyStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("ChangeBookCopyStatus: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	copy, err := h.bookCopyService.ChangeBookCopyStatus(id, req.Status, req.Reason, currentActor(c))
 	if err != nil {
-		zap.L().Error("UpdateBookCopyAvailability: Invalid availability status", zap.String("id", id), zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid availability status"})
+		zap.L().Error("ChangeBookCopyStatus: Failed to change book copy status", zap.String("id", id), zap.Error(err))
+		respondBookCopyError(c, err)
 		return
 	}
 
-	if err := h.bookCopyService.UpdateBookCopyAvailability(id, available); err != nil {
-		zap.L().Error("UpdateBookCopyAvailability: Failed to update book copy availability", zap.String("id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, copy)
+}
+
+// GetBookCopyStatusHistory retrieves the status changes of a copy
+func (h *BookCopyHandler) GetBookCopyStatusHistory(c *gin.Context) {
+	id := c.Param("id")
+	changes, err := h.bookCopyService.GetBookCopyStatusHistory(id)
+	if err != nil {
+		zap.L().Error("GetBookCopyStatusHistory: Failed to get status history", zap.String("id", id), zap.Error(err))
+		respondBookCopyError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Book copy availability updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+func respondBookCopyError(c *gin.Context, err error) {
+	var fieldErrors services.FieldErrors
+	switch {
+	case errors.As(err, &fieldErrors):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+	case err.Error() == "book copy not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCopyStatusChange):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
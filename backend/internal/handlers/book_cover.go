@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BookCoverHandler struct {
+	bookCoverService *services.BookCoverService
+}
+
+func NewBookCoverHandler(bookCoverService *services.BookCoverService) *BookCoverHandler {
+	return &BookCoverHandler{
+		bookCoverService: bookCoverService,
+	}
+}
+
+// UploadCover accepts a multipart upload in the "file" field and replaces the
+// book's cover with it
+func (h *BookCoverHandler) UploadCover(c *gin.Context) {
+	bookID := c.Param("bookId")
+
+	// Leave room for the multipart envelope around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxCoverUploadBytes+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		zap.L().Error("UploadCover: Invalid upload", zap.String("id", bookID), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a cover image must be uploaded in the \"file\" field"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		zap.L().Error("UploadCover: Failed to open upload", zap.String("id", bookID), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	book, err := h.bookCoverService.UploadCover(c.Request.Context(), bookID, file, currentActor(c))
+	if err != nil {
+		zap.L().Error("UploadCover: Failed to upload cover", zap.String("id", bookID), zap.Error(err))
+		respondCoverError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+// DeleteCover removes the uploaded cover of a book
+func (h *BookCoverHandler) DeleteCover(c *gin.Context) {
+	bookID := c.Param("id")
+	if err := h.bookCoverService.DeleteCover(bookID, currentActor(c)); err != nil {
+		zap.L().Error("DeleteCover: Failed to delete cover", zap.String("id", bookID), zap.Error(err))
+		respondCoverError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondCoverError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCover):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "book not found" || err.Error() == "book has no uploaded cover":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BookImportHandler struct {
+	bookImportService *services.BookImportService
+}
+
+func NewBookImportHandler(bookImportService *services.BookImportService) *BookImportHandler {
+	return &BookImportHandler{
+		bookImportService: bookImportService,
+	}
+}
+
+type ImportISBNsRequest struct {
+	ISBNs    []string `json:"isbns" binding:"required,min=1,max=100"`
+	Provider string   `json:"provider"`
+}
+
+// ImportISBNs looks up ISBNs with the metadata providers and creates the matching books
+func (h *BookImportHandler) ImportISBNs(c *gin.Context) {
+	var req ImportISBNsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("ImportISBNs: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.bookImportService.ImportISBNs(c.Request.Context(), req.ISBNs, req.Provider, currentActor(c))
+	if err != nil {
+		zap.L().Error("ImportISBNs: Failed to import ISBNs", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
+
+// ImportFile runs a bulk import from an uploaded CSV or binary MARC21 file,
+// or from a JSON array of records. The file format is taken from the "format"
+// field or the file extension; with dry_run=true nothing is written and the
+// report shows what would happen.
+func (h *BookImportHandler) ImportFile(c *gin.Context) {
+	if c.ContentType() == "application/json" {
+		h.importRecords(c)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		zap.L().Error("ImportFile: Missing file", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+		case ".csv":
+			format = services.ImportFormatCSV
+		case ".mrc", ".marc":
+			format = services.ImportFormatMARC
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		zap.L().Error("ImportFile: Failed to open uploaded file", zap.String("filename", fileHeader.Filename), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	records, err := services.ParseImportFile(format, file)
+	if err != nil {
+		zap.L().Error("ImportFile: Failed to parse file", zap.String("filename", fileHeader.Filename), zap.String("format", format), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := h.bookImportService.ImportRecords(records, importOptions(c), currentActor(c))
+	c.JSON(http.StatusOK, report)
+}
+
+// importRecords runs a bulk import from a JSON array of records
+func (h *BookImportHandler) importRecords(c *gin.Context) {
+	var records []services.ImportRecord
+	if err := c.ShouldBindJSON(&records); err != nil {
+		zap.L().Error("ImportFile: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range records {
+		if records[i].Row == 0 {
+			records[i].Row = i + 1
+		}
+	}
+
+	report := h.bookImportService.ImportRecords(records, importOptions(c), currentActor(c))
+	c.JSON(http.StatusOK, report)
+}
+
+// importOptions reads dry_run and update_existing from the query string or form
+func importOptions(c *gin.Context) services.ImportOptions {
+	flag := func(name string) bool {
+		value := c.Query(name)
+		if value == "" {
+			value = c.PostForm(name)
+		}
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	return services.ImportOptions{
+		DryRun:         flag("dry_run"),
+		UpdateExisting: flag("update_existing"),
+	}
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BookListHandler struct {
+	bookListService *services.BookListService
+}
+
+func NewBookListHandler(bookListService *services.BookListService) *BookListHandler {
+	return &BookListHandler{
+		bookListService: bookListService,
+	}
+}
+
+// GetBookLists lists the current user's lists
+func (h *BookListHandler) GetBookLists(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	lists, err := h.bookListService.GetBookLists(userID)
+	if err != nil {
+		zap.L().Error("GetBookLists: Failed to get lists", zap.String("userID", userID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+// GetBookList returns one of the current user's lists with its books
+func (h *BookListHandler) GetBookList(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+
+	list, err := h.bookListService.GetBookList(id, userID)
+	if err != nil {
+		zap.L().Error("GetBookList: Failed to get list", zap.String("id", id), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetSharedBookList returns a public list by its share token
+func (h *BookListHandler) GetSharedBookList(c *gin.Context) {
+	list, err := h.bookListService.GetSharedBookList(c.Param("token"))
+	if err != nil {
+		zap.L().Error("GetSharedBookList: Failed to get shared list", zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *BookListHandler) CreateBookList(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req models.BookListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("CreateBookList: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.bookListService.CreateBookList(userID, req)
+	if err != nil {
+		zap.L().Error("CreateBookList: Failed to create list", zap.String("userID", userID), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+func (h *BookListHandler) UpdateBookList(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	var req models.BookListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("UpdateBookList: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.bookListService.UpdateBookList(id, userID, req)
+	if err != nil {
+		zap.L().Error("UpdateBookList: Failed to update list", zap.String("id", id), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *BookListHandler) DeleteBookList(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+
+	if err := h.bookListService.DeleteBookList(id, userID); err != nil {
+		zap.L().Error("DeleteBookList: Failed to delete list", zap.String("id", id), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateShareToken invalidates the list's share link and issues a new one
+func (h *BookListHandler) RegenerateShareToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+
+	list, err := h.bookListService.RegenerateShareToken(id, userID)
+	if err != nil {
+		zap.L().Error("RegenerateShareToken: Failed to regenerate share token", zap.String("id", id), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *BookListHandler) AddBookToList(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	var req models.BookListItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("AddBookToList: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.bookListService.AddBookToList(id, userID, req.BookID); err != nil {
+		zap.L().Error("AddBookToList: Failed to add book", zap.String("id", id), zap.String("bookID", req.BookID), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book added to list"})
+}
+
+func (h *BookListHandler) RemoveBookFromList(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	bookID := c.Param("bookId")
+
+	if err := h.bookListService.RemoveBookFromList(id, userID, bookID); err != nil {
+		zap.L().Error("RemoveBookFromList: Failed to remove book", zap.String("id", id), zap.String("bookID", bookID), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MoveToCart adds an available copy of each book on the list to the cart
+func (h *BookListHandler) MoveToCart(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+
+	result, err := h.bookListService.MoveToCart(id, userID)
+	if err != nil {
+		zap.L().Error("MoveToCart: Failed to move list to cart", zap.String("id", id), zap.Error(err))
+		respondBookListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// currentUserID returns the ID of the authenticated user, answering 401 when there is none
+func currentUserID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		zap.L().Error("currentUserID: User ID not found in context", zap.String("path", c.FullPath()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", false
+	}
+	return userID.(string), true
+}
+
+func respondBookListError(c *gin.Context, err error) {
+	var fieldErrors services.FieldErrors
+	switch {
+	case errors.As(err, &fieldErrors):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+	case errors.Is(err, services.ErrBookListNotFound), err.Error() == "book is not on this list":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/hungcq/pscit/backend/internal/models"
 	"github.com/hungcq/pscit/backend/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// UpdateCategoryRequest is the body of a category update. parent_id is kept
+// raw so that leaving it out keeps the parent while null moves the category
+// to the top level.
+type UpdateCategoryRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	ParentID    json.RawMessage `json:"parent_id"`
+}
+
 type CategoryHandler struct {
 	categoryService *services.CategoryService
 }
@@ -43,6 +55,31 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 	c.JSON(http.StatusOK, category)
 }
 
+// GetCategoryTree returns the whole category taxonomy as nested subcategories
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	categories, err := h.categoryService.GetCategoryTree()
+	if err != nil {
+		zap.L().Error("GetCategoryTree: Failed to get category tree", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// GetCategorySubtree returns a category with its nested subcategories
+func (h *CategoryHandler) GetCategorySubtree(c *gin.Context) {
+	id := c.Param("id")
+	category, err := h.categoryService.GetCategorySubtree(id)
+	if err != nil {
+		zap.L().Error("GetCategorySubtree: Failed to get category subtree", zap.String("id", id), zap.Error(err))
+		respondCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
 func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 	var category models.Category
 	if err := c.ShouldBindJSON(&category); err != nil {
@@ -51,9 +88,9 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	if err := h.categoryService.CreateCategory(&category); err != nil {
+	if err := h.categoryService.CreateCategory(&category, currentActor(c)); err != nil {
 		zap.L().Error("CreateCategory: Failed to create category", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCategoryError(c, err)
 		return
 	}
 
@@ -62,16 +99,27 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 
 func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 	id := c.Param("id")
-	var category models.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		zap.L().Error("UpdateCategory: Invalid request body", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.categoryService.UpdateCategory(id, &category); err != nil {
+	category := models.Category{Name: req.Name, Description: req.Description}
+	updateParent := req.ParentID != nil
+	if updateParent {
+		var err error
+		if category.ParentID, err = parseParentID(req.ParentID); err != nil {
+			zap.L().Error("UpdateCategory: Invalid parent ID", zap.String("id", id), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent_id"})
+			return
+		}
+	}
+
+	if err := h.categoryService.UpdateCategory(id, &category, updateParent, currentActor(c)); err != nil {
 		zap.L().Error("UpdateCategory: Failed to update category", zap.String("id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCategoryError(c, err)
 		return
 	}
 
@@ -80,7 +128,7 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.categoryService.DeleteCategory(id); err != nil {
+	if err := h.categoryService.DeleteCategory(id, currentActor(c)); err != nil {
 		zap.L().Error("DeleteCategory: Failed to delete category", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -88,3 +136,30 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryCycle), errors.Is(err, services.ErrParentCategoryNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "category not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseParentID converts the parent_id of an update; null or "" means no parent
+func parseParentID(raw json.RawMessage) (*uuid.UUID, error) {
+	var parentID *string
+	if err := json.Unmarshal(raw, &parentID); err != nil {
+		return nil, err
+	}
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(*parentID)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
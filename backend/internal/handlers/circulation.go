@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CirculationHandler struct {
+	circulationService *services.CirculationService
+}
+
+func NewCirculationHandler(circulationService *services.CirculationService) *CirculationHandler {
+	return &CirculationHandler{
+		circulationService: circulationService,
+	}
+}
+
+// CheckOut hands out the scanned copy on its approved reservation
+func (h *CirculationHandler) CheckOut(c *gin.Context) {
+	var req models.DeskScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("CheckOut: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.circulationService.CheckOut(req.Code, currentActor(c))
+	if err != nil {
+		zap.L().Error("CheckOut: Failed to check out book copy", zap.String("code", req.Code), zap.Error(err))
+		respondCirculationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CheckIn takes back the scanned copy. Copies that were not expected back
+// are flagged in the response rather than rejected.
+func (h *CirculationHandler) CheckIn(c *gin.Context) {
+	var req models.DeskScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("CheckIn: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.circulationService.CheckIn(req.Code, currentActor(c))
+	if err != nil {
+		zap.L().Error("CheckIn: Failed to check in book copy", zap.String("code", req.Code), zap.Error(err))
+		respondCirculationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func respondCirculationError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "book copy not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoApprovedReservation),
+		errors.Is(err, services.ErrReservationNotApproved),
+		errors.Is(err, services.ErrCopyAlreadyCheckedOut),
+		errors.Is(err, services.ErrCopyNotLendable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
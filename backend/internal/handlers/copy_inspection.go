@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CopyInspectionHandler struct {
+	copyInspectionService *services.CopyInspectionService
+}
+
+func NewCopyInspectionHandler(copyInspectionService *services.CopyInspectionService) *CopyInspectionHandler {
+	return &CopyInspectionHandler{
+		copyInspectionService: copyInspectionService,
+	}
+}
+
+// InspectCopy records the condition of a copy from a multipart form, with
+// optional photos in the "photos" field
+func (h *CopyInspectionHandler) InspectCopy(c *gin.Context) {
+	id := c.Param("id")
+
+	// Leave room for the multipart envelope and the other fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxInspectionPhotos*services.MaxInspectionPhotoBytes+1<<20)
+	var req models.CopyInspectionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		zap.L().Error("InspectCopy: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var photos []io.Reader
+	if form, err := c.MultipartForm(); err == nil {
+		for _, fileHeader := range form.File["photos"] {
+			file, err := fileHeader.Open()
+			if err != nil {
+				zap.L().Error("InspectCopy: Failed to open upload", zap.String("id", id), zap.Error(err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer file.Close()
+			photos = append(photos, file)
+		}
+	}
+
+	inspection, err := h.copyInspectionService.InspectCopy(c.Request.Context(), id, req, photos, currentActor(c))
+	if err != nil {
+		zap.L().Error("InspectCopy: Failed to record inspection", zap.String("id", id), zap.Error(err))
+		respondInspectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, inspection)
+}
+
+// GetCopyInspections retrieves the condition timeline of a copy
+func (h *CopyInspectionHandler) GetCopyInspections(c *gin.Context) {
+	id := c.Param("id")
+	inspections, err := h.copyInspectionService.GetCopyInspections(id)
+	if err != nil {
+		zap.L().Error("GetCopyInspections: Failed to get inspections", zap.String("id", id), zap.Error(err))
+		respondInspectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"inspections": inspections})
+}
+
+// GetUserDamageReports lists the copies a patron returned damaged
+func (h *CopyInspectionHandler) GetUserDamageReports(c *gin.Context) {
+	userID := c.Param("id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	reports, total, err := h.copyInspectionService.GetUserDamageReports(userID, page, limit)
+	if err != nil {
+		zap.L().Error("GetUserDamageReports: Failed to get damage reports", zap.String("userID", userID), zap.Error(err))
+		respondInspectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"damage_reports": reports,
+		"total":          total,
+		"page":           page,
+		"limit":          limit,
+	})
+}
+
+func respondInspectionError(c *gin.Context, err error) {
+	var fieldErrors services.FieldErrors
+	switch {
+	case errors.As(err, &fieldErrors):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+	case errors.Is(err, services.ErrInvalidInspectionPhoto):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "book copy not found" || err.Error() == "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
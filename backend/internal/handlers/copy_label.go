@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CopyLabelHandler struct {
+	copyLabelService *services.CopyLabelService
+}
+
+func NewCopyLabelHandler(copyLabelService *services.CopyLabelService) *CopyLabelHandler {
+	return &CopyLabelHandler{
+		copyLabelService: copyLabelService,
+	}
+}
+
+// GetBarcode returns the Code128 barcode of a copy's accession number as a PNG image
+func (h *CopyLabelHandler) GetBarcode(c *gin.Context) {
+	id := c.Param("id")
+	image, err := h.copyLabelService.GetBarcode(id)
+	if err != nil {
+		zap.L().Error("GetBarcode: Failed to get barcode", zap.String("id", id), zap.Error(err))
+		if err.Error() == "book copy not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// PrintLabels returns a printable PDF sheet of labels for the selected copies
+func (h *CopyLabelHandler) PrintLabels(c *gin.Context) {
+	var request struct {
+		CopyIDs []string `json:"copy_ids"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		zap.L().Error("PrintLabels: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.copyLabelService.WriteLabelSheet(&buf, request.CopyIDs); err != nil {
+		zap.L().Error("PrintLabels: Failed to generate labels", zap.Int("count", len(request.CopyIDs)), zap.Error(err))
+		var fieldErrors services.FieldErrors
+		if errors.As(err, &fieldErrors) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="copy-labels.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
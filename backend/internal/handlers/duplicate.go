@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DuplicateHandler struct {
+	duplicateService *services.DuplicateService
+}
+
+func NewDuplicateHandler(duplicateService *services.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{
+		duplicateService: duplicateService,
+	}
+}
+
+type MergeRequest struct {
+	SurvivorID   string   `json:"survivor_id" binding:"required"`
+	DuplicateIDs []string `json:"duplicate_ids" binding:"required,min=1"`
+}
+
+// GetDuplicateAuthors lists groups of authors that are likely the same person.
+// The optional "threshold" parameter sets the trigram similarity cut-off.
+func (h *DuplicateHandler) GetDuplicateAuthors(c *gin.Context) {
+	threshold, ok := duplicateThreshold(c)
+	if !ok {
+		return
+	}
+
+	groups, err := h.duplicateService.FindDuplicateAuthors(threshold)
+	if err != nil {
+		zap.L().Error("GetDuplicateAuthors: Failed to find duplicates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":    groups,
+		"threshold": threshold,
+	})
+}
+
+// GetDuplicateCategories lists groups of categories that are likely the same
+func (h *DuplicateHandler) GetDuplicateCategories(c *gin.Context) {
+	threshold, ok := duplicateThreshold(c)
+	if !ok {
+		return
+	}
+
+	groups, err := h.duplicateService.FindDuplicateCategories(threshold)
+	if err != nil {
+		zap.L().Error("GetDuplicateCategories: Failed to find duplicates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":    groups,
+		"threshold": threshold,
+	})
+}
+
+// MergeAuthors folds the duplicate authors into the surviving one
+func (h *DuplicateHandler) MergeAuthors(c *gin.Context) {
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("MergeAuthors: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.duplicateService.MergeAuthors(req.SurvivorID, req.DuplicateIDs, currentActor(c))
+	if err != nil {
+		zap.L().Error("MergeAuthors: Failed to merge authors", zap.String("survivorID", req.SurvivorID), zap.Error(err))
+		respondMergeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// MergeCategories folds the duplicate categories into the surviving one
+func (h *DuplicateHandler) MergeCategories(c *gin.Context) {
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("MergeCategories: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.duplicateService.MergeCategories(req.SurvivorID, req.DuplicateIDs, currentActor(c))
+	if err != nil {
+		zap.L().Error("MergeCategories: Failed to merge categories", zap.String("survivorID", req.SurvivorID), zap.Error(err))
+		respondMergeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func duplicateThreshold(c *gin.Context) (float64, bool) {
+	raw := c.Query("threshold")
+	if raw == "" {
+		return services.DefaultDuplicateThreshold, true
+	}
+	threshold, err := strconv.ParseFloat(raw, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be a number between 0 and 1"})
+		return 0, false
+	}
+	return threshold, true
+}
+
+func respondMergeError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidMerge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
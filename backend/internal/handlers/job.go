@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type JobHandler struct {
+	jobScheduler *services.JobScheduler
+}
+
+func NewJobHandler(jobScheduler *services.JobScheduler) *JobHandler {
+	return &JobHandler{
+		jobScheduler: jobScheduler,
+	}
+}
+
+// GetJobs lists the registered jobs with their schedule and latest run
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	jobs, err := h.jobScheduler.ListJobs()
+	if err != nil {
+		zap.L().Error("GetJobs: Failed to list jobs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// TriggerJob starts a job right away; the returned run can be polled through GetJobRuns
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	name := c.Param("name")
+
+	var triggeredBy *uuid.UUID
+	if userID, exists := c.Get("userID"); exists {
+		if id, err := uuid.Parse(userID.(string)); err == nil {
+			triggeredBy = &id
+		}
+	}
+
+	run, err := h.jobScheduler.Trigger(name, triggeredBy)
+	if err != nil {
+		zap.L().Error("TriggerJob: Failed to trigger job", zap.String("job", name), zap.Error(err))
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrJobRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetJobRuns returns the run history of a job, most recent first
+func (h *JobHandler) GetJobRuns(c *gin.Context) {
+	name := c.Param("name")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	runs, total, err := h.jobScheduler.GetRuns(name, page, limit)
+	if err != nil {
+		zap.L().Error("GetJobRuns: Failed to get job runs", zap.String("job", name), zap.Error(err))
+		if errors.Is(err, services.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
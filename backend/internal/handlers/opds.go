@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// opdsPageSize is the number of books per page of an acquisition feed
+const opdsPageSize = 25
+
+// The OPDS 1.2 catalog is served under /api/opds and the same feeds as
+// OPDS 2.0 under /api/opds/v2
+const (
+	opdsBasePath  = "/api/opds"
+	opds2BasePath = "/api/opds/v2"
+)
+
+type OPDSHandler struct {
+	catalogFeedService *services.CatalogFeedService
+}
+
+func NewOPDSHandler(catalogFeedService *services.CatalogFeedService) *OPDSHandler {
+	return &OPDSHandler{
+		catalogFeedService: catalogFeedService,
+	}
+}
+
+// GetRoot is the start of the catalog, linking to the new arrivals and to
+// browsing by category, author and tag
+func (h *OPDSHandler) GetRoot(c *gin.Context) {
+	base := opdsBase(c)
+	newArrivals, err := h.catalogFeedService.GetFeedBooks(services.CatalogFeedFilter{}, 1, 1)
+	if err != nil {
+		zap.L().Error("GetRoot: Failed to get new arrivals", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	feed := &services.OPDSFeed{
+		ID:      "/",
+		Title:   "PSciT Library",
+		Updated: newArrivals.Updated,
+		Links:   services.OPDSLinks{Self: base, Start: base},
+		Navigation: []services.OPDSNavigationEntry{
+			{ID: "/new", Title: "New arrivals", Summary: "The latest books added to the library", Href: base + "/new", Updated: newArrivals.Updated, Acquisition: true, New: true},
+			{ID: "/categories", Title: "By category", Summary: "Browse books by category", Href: base + "/categories", Updated: newArrivals.Updated},
+			{ID: "/authors", Title: "By author", Summary: "Browse books by author", Href: base + "/authors", Updated: newArrivals.Updated},
+			{ID: "/tags", Title: "By tag", Summary: "Browse books by tag", Href: base + "/tags", Updated: newArrivals.Updated},
+		},
+	}
+	respondOPDS(c, feed)
+}
+
+// GetNewArrivals lists the books most recently added to the library
+func (h *OPDSHandler) GetNewArrivals(c *gin.Context) {
+	h.respondBooks(c, "/new", services.CatalogFeedFilter{}, "New arrivals")
+}
+
+// GetCategories lists the categories to browse
+func (h *OPDSHandler) GetCategories(c *gin.Context) {
+	groups, err := h.catalogFeedService.GetFeedCategories()
+	h.respondGroups(c, "/categories", "Categories", groups, err)
+}
+
+// GetCategoryBooks lists the books of a category
+func (h *OPDSHandler) GetCategoryBooks(c *gin.Context) {
+	id := c.Param("id")
+	h.respondBooks(c, "/categories/"+id, services.CatalogFeedFilter{CategoryID: id}, "")
+}
+
+// GetAuthors lists the authors to browse
+func (h *OPDSHandler) GetAuthors(c *gin.Context) {
+	groups, err := h.catalogFeedService.GetFeedAuthors()
+	h.respondGroups(c, "/authors", "Authors", groups, err)
+}
+
+// GetAuthorBooks lists the books of an author
+func (h *OPDSHandler) GetAuthorBooks(c *gin.Context) {
+	id := c.Param("id")
+	h.respondBooks(c, "/authors/"+id, services.CatalogFeedFilter{AuthorID: id}, "")
+}
+
+// GetTags lists the tags to browse
+func (h *OPDSHandler) GetTags(c *gin.Context) {
+	groups, err := h.catalogFeedService.GetFeedTags()
+	h.respondGroups(c, "/tags", "Tags", groups, err)
+}
+
+// GetTagBooks lists the books with a tag
+func (h *OPDSHandler) GetTagBooks(c *gin.Context) {
+	id := c.Param("id")
+	h.respondBooks(c, "/tags/"+id, services.CatalogFeedFilter{TagID: id}, "")
+}
+
+// respondGroups answers with a navigation feed linking to the acquisition
+// feed of each category, author or tag
+func (h *OPDSHandler) respondGroups(c *gin.Context, path, title string, groups []services.CatalogFeedGroup, err error) {
+	if err != nil {
+		zap.L().Error("respondGroups: Failed to get feed groups", zap.String("path", path), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := opdsBase(c)
+	feed := &services.OPDSFeed{
+		ID:         path,
+		Title:      title,
+		Links:      services.OPDSLinks{Self: base + path, Start: base, Up: base},
+		Navigation: []services.OPDSNavigationEntry{},
+	}
+	for _, group := range groups {
+		if group.Updated.After(feed.Updated) {
+			feed.Updated = group.Updated
+		}
+		feed.Navigation = append(feed.Navigation, services.OPDSNavigationEntry{
+			ID:          path + "/" + group.ID.String(),
+			Title:       group.Name,
+			Summary:     fmt.Sprintf("%d books", group.BookCount),
+			Href:        base + path + "/" + group.ID.String(),
+			Updated:     group.Updated,
+			Acquisition: true,
+		})
+	}
+	respondOPDS(c, feed)
+}
+
+// respondBooks answers with a page of the acquisition feed of the books
+// matching the filter. The feed is named after the filter unless a title is given.
+func (h *OPDSHandler) respondBooks(c *gin.Context, path string, filter services.CatalogFeedFilter, title string) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return
+	}
+
+	result, err := h.catalogFeedService.GetFeedBooks(filter, page, opdsPageSize)
+	if err != nil {
+		zap.L().Error("respondBooks: Failed to get feed books", zap.String("path", path), zap.Error(err))
+		if errors.Is(err, services.ErrFeedNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if title == "" {
+		title = result.Name
+	}
+
+	base := opdsBase(c)
+	self := base + path
+	feed := &services.OPDSFeed{
+		ID:      path,
+		Title:   title,
+		Updated: result.Updated,
+		Links:   services.OPDSLinks{Self: self, Start: base, Up: base + parentPath(path)},
+		Books:   result.Books,
+		Total:   result.Total,
+	}
+	if page > 1 {
+		feed.Links.Self = fmt.Sprintf("%s?page=%d", self, page)
+		feed.Links.First = self
+		feed.Links.Previous = fmt.Sprintf("%s?page=%d", self, page-1)
+	}
+	if int64(page*opdsPageSize) < result.Total {
+		feed.Links.Next = fmt.Sprintf("%s?page=%d", self, page+1)
+	}
+	respondOPDS(c, feed)
+}
+
+// respondOPDS writes the feed in the OPDS version of the requested path
+func respondOPDS(c *gin.Context, feed *services.OPDSFeed) {
+	if opdsBase(c) == opds2BasePath {
+		c.Header("Content-Type", services.OPDS2Type)
+		c.JSON(http.StatusOK, services.OPDS2Document(feed))
+		return
+	}
+
+	contentType := services.OPDSAcquisitionType
+	if feed.Navigation != nil {
+		contentType = services.OPDSNavigationType
+	}
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	if err := services.WriteOPDS1(c.Writer, feed); err != nil {
+		zap.L().Error("respondOPDS: Failed to write feed", zap.String("id", feed.ID), zap.Error(err))
+		c.Abort()
+	}
+}
+
+// opdsBase returns the root path of the OPDS version the request was made to
+func opdsBase(c *gin.Context) string {
+	if strings.HasPrefix(c.FullPath(), opds2BasePath+"/") || c.FullPath() == opds2BasePath {
+		return opds2BasePath
+	}
+	return opdsBasePath
+}
+
+// parentPath strips the last segment of a feed path, e.g. "/categories/<id>"
+// becomes "/categories" and "/new" the root
+func parentPath(path string) string {
+	return path[:strings.LastIndex(path, "/")]
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RecommendationHandler struct {
+	recommendationService *services.RecommendationService
+}
+
+func NewRecommendationHandler(recommendationService *services.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{
+		recommendationService: recommendationService,
+	}
+}
+
+// GetCoBorrowedBooks lists the books patrons who borrowed this book also borrowed
+func (h *RecommendationHandler) GetCoBorrowedBooks(c *gin.Context) {
+	id := c.Param("id")
+	limit, ok := limitParam(c, services.DefaultRecommendationLimit, services.MaxRecommendationLimit)
+	if !ok {
+		return
+	}
+
+	books, err := h.recommendationService.GetCoBorrowedBooks(id, limit)
+	if err != nil {
+		zap.L().Error("GetCoBorrowedBooks: Failed to get co-borrowed books", zap.String("id", id), zap.Error(err))
+		if err.Error() == "book not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
+
+// GetUserRecommendations suggests books for the current user from what
+// patrons with a similar borrowing history borrowed
+func (h *RecommendationHandler) GetUserRecommendations(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		zap.L().Error("GetUserRecommendations: User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	limit, ok := limitParam(c, services.DefaultRecommendationLimit, services.MaxRecommendationLimit)
+	if !ok {
+		return
+	}
+
+	books, err := h.recommendationService.GetUserRecommendations(userID.(string), limit)
+	if err != nil {
+		zap.L().Error("GetUserRecommendations: Failed to get recommendations", zap.String("userID", userID.(string)), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
+
+// limitParam reads the "limit" query parameter, answering 400 when it is out of range
+func limitParam(c *gin.Context, defaultLimit, maxLimit int) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+		return 0, false
+	}
+	return limit, true
+}
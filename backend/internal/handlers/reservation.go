@@ -89,7 +89,7 @@ func (h *ReservationHandler) CreateReservation(c *gin.Context) {
 	}
 
 	reservation, err := h.reservationService.CreateReservation(
-		userID.(string), startDate, endDate, req.SuggestedPickupTimeslots, req.SuggestedReturnTimeslots,
+		userID.(string), startDate, endDate, req.SuggestedPickupTimeslots, req.SuggestedReturnTimeslots, currentActor(c),
 	)
 	if err != nil {
 		zap.L().Error("CreateReservation: Failed to create reservation", zap.String("userID", userID.(string)), zap.Error(err))
@@ -178,7 +178,7 @@ func (h *ReservationHandler) CheckoutCart(c *gin.Context) {
 	}
 
 	reservation, err := h.reservationService.CheckoutCart(
-		userID.(string), startDate, endDate, req.SuggestedPickupTimeslots, req.SuggestedReturnTimeslots,
+		userID.(string), startDate, endDate, req.SuggestedPickupTimeslots, req.SuggestedReturnTimeslots, currentActor(c),
 	)
 	if err != nil {
 		zap.L().Error("CheckoutCart: Failed to checkout cart", zap.String("userID", userID.(string)), zap.Error(err))
@@ -196,7 +196,8 @@ func (h *ReservationHandler) CheckoutCart(c *gin.Context) {
 	c.JSON(http.StatusCreated, reservation)
 }
 
-// GetReservations handles getting all reservations (admin only)
+// GetReservations handles getting all reservations (admin only). A "cursor"
+// parameter switches to keyset pagination, as for books.
 func (h *ReservationHandler) GetReservations(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -208,6 +209,22 @@ func (h *ReservationHandler) GetReservations(c *gin.Context) {
 		return
 	}
 
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		reservations, cursors, err := h.reservationService.GetReservationsByCursor(cursor, limit, filters)
+		if err != nil {
+			zap.L().Error("GetReservations: Failed to get reservations by cursor", zap.Error(err), zap.Int("limit", limit))
+			respondCursorError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"reservations": reservations,
+			"limit":        limit,
+			"next_cursor":  cursors.NextCursor,
+			"prev_cursor":  cursors.PrevCursor,
+		})
+		return
+	}
+
 	reservations, total, err := h.reservationService.GetReservations(page, limit, filters)
 	if err != nil {
 		zap.L().Error("GetReservations: Failed to get reservations", zap.Error(err), zap.Int("page", page), zap.Int("limit", limit))
@@ -224,7 +241,8 @@ func (h *ReservationHandler) GetReservations(c *gin.Context) {
 	})
 }
 
-// GetUserReservations handles getting reservations for the current user
+// GetUserReservations handles getting reservations for the current user. A
+// "cursor" parameter switches to keyset pagination, as for books.
 func (h *ReservationHandler) GetUserReservations(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -236,6 +254,22 @@ func (h *ReservationHandler) GetUserReservations(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		reservations, cursors, err := h.reservationService.GetUserReservationsByCursor(userID.(string), cursor, limit)
+		if err != nil {
+			zap.L().Error("GetUserReservations: Failed to get user reservations by cursor", zap.String("userID", userID.(string)), zap.Error(err), zap.Int("limit", limit))
+			respondCursorError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"reservations": reservations,
+			"limit":        limit,
+			"next_cursor":  cursors.NextCursor,
+			"prev_cursor":  cursors.PrevCursor,
+		})
+		return
+	}
+
 	reservations, total, err := h.reservationService.GetUserReservations(userID.(string), page, limit)
 	if err != nil {
 		zap.L().Error("GetUserReservations: Failed to get user reservations", zap.String("userID", userID.(string)), zap.Error(err), zap.Int("page", page), zap.Int("limit", limit))
@@ -296,7 +330,7 @@ func (h *ReservationHandler) UpdateReservationStatus(c *gin.Context) {
 		}
 	}
 
-	reservation, err := h.reservationService.UpdateReservationStatus(id, req.Status, pickupTime, returnTime)
+	reservation, err := h.reservationService.UpdateReservationStatus(id, req.Status, pickupTime, returnTime, currentActor(c))
 	if err != nil {
 		zap.L().Error("UpdateReservationStatus: Failed to update reservation status", zap.String("id", id), zap.String("status", string(req.Status)), zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
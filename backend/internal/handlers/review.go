@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type ReviewHandler struct {
+	reviewService *services.ReviewService
+}
+
+func NewReviewHandler(reviewService *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+	}
+}
+
+// GetBookReviews lists the approved reviews of a book
+func (h *ReviewHandler) GetBookReviews(c *gin.Context) {
+	bookID := c.Param("id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	reviews, total, err := h.reviewService.GetBookReviews(bookID, page, limit)
+	if err != nil {
+		zap.L().Error("GetBookReviews: Failed to get reviews", zap.String("bookID", bookID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews": reviews,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// SaveReview rates and reviews a book as the current user, replacing any
+// earlier review of theirs
+func (h *ReviewHandler) SaveReview(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		zap.L().Error("SaveReview: User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	bookID := c.Param("bookId")
+
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("SaveReview: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.SaveReview(bookID, userID.(string), req)
+	if err != nil {
+		zap.L().Error("SaveReview: Failed to save review", zap.String("bookID", bookID), zap.String("userID", userID.(string)), zap.Error(err))
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview deletes one of the current user's reviews
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		zap.L().Error("DeleteReview: User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id := c.Param("id")
+
+	if err := h.reviewService.DeleteReview(id, userID.(string)); err != nil {
+		zap.L().Error("DeleteReview: Failed to delete review", zap.String("id", id), zap.Error(err))
+		respondReviewError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetReviews lists reviews for moderation (admin only). The optional "status"
+// parameter narrows the list, e.g. to pending reviews.
+func (h *ReviewHandler) GetReviews(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+
+	reviews, total, err := h.reviewService.GetReviews(status, page, limit)
+	if err != nil {
+		zap.L().Error("GetReviews: Failed to get reviews", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews": reviews,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// UpdateReviewStatus approves or hides a review (admin only)
+func (h *ReviewHandler) UpdateReviewStatus(c *gin.Context) {
+	id := c.Param("id")
+	var req models.UpdateReviewStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("UpdateReviewStatus: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var moderatorID *uuid.UUID
+	if userID, exists := c.Get("userID"); exists {
+		if parsed, err := uuid.Parse(userID.(string)); err == nil {
+			moderatorID = &parsed
+		}
+	}
+
+	review, err := h.reviewService.UpdateReviewStatus(id, req.Status, moderatorID)
+	if err != nil {
+		zap.L().Error("UpdateReviewStatus: Failed to update review status", zap.String("id", id), zap.Error(err))
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func respondReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReviewNotFound), err.Error() == "book not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReviewNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
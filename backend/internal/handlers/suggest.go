@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SuggestHandler struct {
+	suggestService *services.SuggestService
+}
+
+func NewSuggestHandler(suggestService *services.SuggestService) *SuggestHandler {
+	return &SuggestHandler{
+		suggestService: suggestService,
+	}
+}
+
+// Suggest returns typeahead suggestions for the search box: book titles,
+// author names, categories and tags close to the "q" parameter
+func (h *SuggestHandler) Suggest(c *gin.Context) {
+	query := c.Query("q")
+	limit, ok := limitParam(c, services.DefaultSuggestionLimit, services.MaxSuggestionLimit)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.suggestService.Suggest(query, limit)
+	if err != nil {
+		zap.L().Error("Suggest: Failed to get suggestions", zap.String("query", query), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Keystrokes repeat the same prefixes, let the browser reuse answers briefly
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// syndicationFeedSize is the number of newest books listed in a feed
+const syndicationFeedSize = 50
+
+// Feeds are served as Atom under /api/feeds/atom and as RSS under rssFeedPath
+const rssFeedPath = "/api/feeds/rss"
+
+type SyndicationHandler struct {
+	catalogFeedService *services.CatalogFeedService
+}
+
+func NewSyndicationHandler(catalogFeedService *services.CatalogFeedService) *SyndicationHandler {
+	return &SyndicationHandler{
+		catalogFeedService: catalogFeedService,
+	}
+}
+
+// GetNewArrivals is the feed of the books most recently added to the library
+func (h *SyndicationHandler) GetNewArrivals(c *gin.Context) {
+	h.respondFeed(c, "", services.CatalogFeedFilter{}, "New arrivals")
+}
+
+// GetCategoryNewArrivals is the feed of the newest books of a category
+func (h *SyndicationHandler) GetCategoryNewArrivals(c *gin.Context) {
+	id := c.Param("id")
+	h.respondFeed(c, "/categories/"+id, services.CatalogFeedFilter{CategoryID: id}, "New in %s")
+}
+
+// GetTagNewArrivals is the feed of the newest books with a tag
+func (h *SyndicationHandler) GetTagNewArrivals(c *gin.Context) {
+	id := c.Param("id")
+	h.respondFeed(c, "/tags/"+id, services.CatalogFeedFilter{TagID: id}, "New in %s")
+}
+
+// GetAuthorNewArrivals is the feed of the newest books of an author
+func (h *SyndicationHandler) GetAuthorNewArrivals(c *gin.Context) {
+	id := c.Param("id")
+	h.respondFeed(c, "/authors/"+id, services.CatalogFeedFilter{AuthorID: id}, "New by %s")
+}
+
+// GetLanguageNewArrivals is the feed of the newest books in a language
+func (h *SyndicationHandler) GetLanguageNewArrivals(c *gin.Context) {
+	language := strings.ToLower(c.Param("language"))
+	h.respondFeed(c, "/languages/"+language, services.CatalogFeedFilter{Language: language}, "New in language %s")
+}
+
+// respondFeed answers with the newest books matching the filter, or with
+// 304 Not Modified when the client already has the current feed. title may
+// contain a %s for the name of the category, tag, author or language.
+func (h *SyndicationHandler) respondFeed(c *gin.Context, path string, filter services.CatalogFeedFilter, title string) {
+	// The validators come from a summary of the feed so that clients with a
+	// current copy are answered without loading any book
+	version, err := h.catalogFeedService.GetFeedVersion(filter)
+	if err != nil {
+		zap.L().Error("respondFeed: Failed to get feed version", zap.String("path", path), zap.Error(err))
+		respondFeedError(c, err)
+		return
+	}
+	rss := strings.HasPrefix(c.FullPath(), rssFeedPath)
+	lastModified := version.LastModified()
+	etag := feedETag(rss, version)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=300")
+	if notModified(c, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	result, err := h.catalogFeedService.GetFeedBooks(filter, 1, syndicationFeedSize)
+	if err != nil {
+		zap.L().Error("respondFeed: Failed to get feed books", zap.String("path", path), zap.Error(err))
+		respondFeedError(c, err)
+		return
+	}
+	if strings.Contains(title, "%s") {
+		title = fmt.Sprintf(title, result.Name)
+	}
+
+	feed := &services.SyndicationFeed{
+		ID:      path,
+		Title:   title,
+		SelfURL: requestURL(c),
+		Updated: version.Modified,
+		Books:   result.Books,
+	}
+
+	write, contentType := services.WriteAtomFeed, services.AtomType
+	if rss {
+		write, contentType = services.WriteRSSFeed, services.RSSType
+	}
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	if err := write(c.Writer, feed); err != nil {
+		zap.L().Error("respondFeed: Failed to write feed", zap.String("path", path), zap.Error(err))
+		c.Abort()
+	}
+}
+
+// feedETag changes whenever a book is added to, edited in or removed from the feed
+func feedETag(rss bool, version *services.CatalogFeedVersion) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%t|%s|%d|%d|%d", rss, version.Name, version.Total,
+		version.Updated.UnixNano(), version.Modified.UnixNano())
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+}
+
+func respondFeedError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrFeedNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// notModified evaluates the conditional GET headers of the request. As in
+// RFC 9110, If-Modified-Since is ignored when If-None-Match is present.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil {
+			return !lastModified.After(t)
+		}
+	}
+	return false
+}
+
+// requestURL rebuilds the absolute address the request was made to, as seen
+// by the client behind any proxy
+func requestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.RequestURI()
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/hungcq/pscit/backend/internal/models"
@@ -57,9 +58,9 @@ func (h *TagHandler) CreateTag(c *gin.Context) {
 		Description: req.Description,
 	}
 
-	if err := h.tagService.CreateTag(tag); err != nil {
+	if err := h.tagService.CreateTag(tag, currentActor(c)); err != nil {
 		zap.L().Error("CreateTag: Failed to create tag", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondTagError(c, err)
 		return
 	}
 
@@ -88,9 +89,9 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 	existingTag.Name = req.Name
 	existingTag.Description = req.Description
 
-	if err := h.tagService.UpdateTag(id, existingTag); err != nil {
+	if err := h.tagService.UpdateTag(id, existingTag, currentActor(c)); err != nil {
 		zap.L().Error("UpdateTag: Failed to update tag", zap.String("id", id), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondTagError(c, err)
 		return
 	}
 
@@ -99,7 +100,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 
 func (h *TagHandler) DeleteTag(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.tagService.DeleteTag(id); err != nil {
+	if err := h.tagService.DeleteTag(id, currentActor(c)); err != nil {
 		zap.L().Error("DeleteTag: Failed to delete tag", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -107,3 +108,12 @@ func (h *TagHandler) DeleteTag(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func respondTagError(c *gin.Context, err error) {
+	var fieldErrors services.FieldErrors
+	if errors.As(err, &fieldErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
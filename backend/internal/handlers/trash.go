@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TrashHandler struct {
+	trashService *services.TrashService
+}
+
+func NewTrashHandler(trashService *services.TrashService) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+	}
+}
+
+// GetTrash lists deleted books, authors, categories and tags. The optional
+// "type" parameter narrows the list to one of them.
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	kind := c.Query("type")
+
+	items, total, err := h.trashService.GetTrash(kind, page, limit)
+	if err != nil {
+		zap.L().Error("GetTrash: Failed to get trash", zap.String("type", kind), zap.Error(err))
+		respondTrashError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// RestoreFromTrash restores a deleted item
+func (h *TrashHandler) RestoreFromTrash(c *gin.Context) {
+	kind := c.Param("type")
+	id := c.Param("id")
+
+	if err := h.trashService.Restore(kind, id, currentActor(c)); err != nil {
+		zap.L().Error("RestoreFromTrash: Failed to restore item", zap.String("type", kind), zap.String("id", id), zap.Error(err))
+		respondTrashError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Item restored"})
+}
+
+func respondTrashError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownTrashType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTrashItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+	"github.com/hungcq/pscit/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type WorkHandler struct {
+	workService *services.WorkService
+}
+
+func NewWorkHandler(workService *services.WorkService) *WorkHandler {
+	return &WorkHandler{
+		workService: workService,
+	}
+}
+
+type EditionsRequest struct {
+	BookIDs []string `json:"book_ids" binding:"required,min=1"`
+}
+
+// GetWork returns a work with every edition and its availability
+func (h *WorkHandler) GetWork(c *gin.Context) {
+	id := c.Param("id")
+	work, err := h.workService.GetWork(id)
+	if err != nil {
+		zap.L().Error("GetWork: Failed to get work", zap.String("id", id), zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, work)
+}
+
+func (h *WorkHandler) CreateWork(c *gin.Context) {
+	var req models.WorkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("CreateWork: Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	work := &models.Work{
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	if err := h.workService.CreateWork(work, req.BookIDs, currentActor(c)); err != nil {
+		zap.L().Error("CreateWork: Failed to create work", zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+
+	created, err := h.workService.GetWork(work.ID.String())
+	if err != nil {
+		zap.L().Error("CreateWork: Failed to reload work", zap.String("id", work.ID.String()), zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *WorkHandler) UpdateWork(c *gin.Context) {
+	id := c.Param("id")
+	var req models.WorkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("UpdateWork: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	work := &models.Work{
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	if err := h.workService.UpdateWork(id, work); err != nil {
+		zap.L().Error("UpdateWork: Failed to update work", zap.String("id", id), zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, work)
+}
+
+func (h *WorkHandler) DeleteWork(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.workService.DeleteWork(id, currentActor(c)); err != nil {
+		zap.L().Error("DeleteWork: Failed to delete work", zap.String("id", id), zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddEditions attaches books to the work as editions
+func (h *WorkHandler) AddEditions(c *gin.Context) {
+	id := c.Param("id")
+	var req EditionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		zap.L().Error("AddEditions: Invalid request body", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.workService.AddEditions(id, req.BookIDs, currentActor(c)); err != nil {
+		zap.L().Error("AddEditions: Failed to add editions", zap.String("id", id), zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+
+	work, err := h.workService.GetWork(id)
+	if err != nil {
+		zap.L().Error("AddEditions: Failed to reload work", zap.String("id", id), zap.Error(err))
+		respondWorkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, work)
+}
+
+// RemoveEdition detaches a book from the work
+func (h *WorkHandler) RemoveEdition(c *gin.Context) {
+	id := c.Param("id")
+	bookID := c.Param("bookId")
+	if err := h.workService.RemoveEdition(id, bookID, currentActor(c)); err != nil {
+		zap.L().Error("RemoveEdition: Failed to remove edition", zap.String("id", id), zap.String("bookID", bookID), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondWorkError(c *gin.Context, err error) {
+	var fieldErrors services.FieldErrors
+	switch {
+	case errors.Is(err, services.ErrWorkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &fieldErrors):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErrors})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
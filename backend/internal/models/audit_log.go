@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditAction string
+
+const (
+	AuditActionCreate  AuditAction = "create"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+)
+
+// Audited entity types
+const (
+	AuditEntityBooks        = "books"
+	AuditEntityBookCopies   = "book_copies"
+	AuditEntityAuthors      = "authors"
+	AuditEntityCategories   = "categories"
+	AuditEntityTags         = "tags"
+	AuditEntityReservations = "reservations"
+)
+
+// Actor identifies the user making a change, as found in their JWT claims.
+// The zero value stands for the system itself, e.g. a background job.
+type Actor struct {
+	UserID *uuid.UUID
+	Email  string
+}
+
+// AuditLog is one entry of the append-only change history. Entries are never
+// updated or deleted, and outlive both the entity and the acting user.
+type AuditLog struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	EntityType string       `gorm:"type:varchar(30);index:idx_audit_logs_entity,priority:1;not null" json:"entity_type"`
+	EntityID   uuid.UUID    `gorm:"type:uuid;index:idx_audit_logs_entity,priority:2;not null" json:"entity_id"`
+	Action     AuditAction  `gorm:"type:varchar(20);not null" json:"action"`
+	ActorID    *uuid.UUID   `gorm:"type:uuid;index:idx_audit_logs_actor_id" json:"actor_id"`
+	ActorEmail string       `json:"actor_email"`
+	Changes    AuditChanges `gorm:"type:jsonb" json:"changes"`
+	CreatedAt  time.Time    `gorm:"index:idx_audit_logs_created_at" json:"created_at"`
+}
+
+// AuditChange is the value of one field before and after a change. Old is nil
+// for a created entity and New is nil for a deleted one.
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditChanges maps a field name to its change
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("unsupported audit changes value %T", value)
+}
+
+type AuditLogFilters struct {
+	EntityType string `form:"entity_type"`
+	Action     string `form:"action"`
+	ActorEmail string `form:"actor_email"`
+}
+
+func (l *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
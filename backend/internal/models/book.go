@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,15 +29,19 @@ type Author struct {
 	Books     []Book         `gorm:"many2many:book_authors;" json:"books,omitempty"`
 }
 
-// Category represents a book category
+// Category represents a book category. Categories form a tree through their
+// parent, e.g. "Science > Physics > Quantum".
 type Category struct {
 	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
 	Name        string         `gorm:"uniqueIndex:idx_categories_name" json:"name"`
 	Description string         `json:"description"`
+	ParentID    *uuid.UUID     `gorm:"type:uuid;index:idx_categories_parent_id" json:"parent_id"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 	Books       []Book         `gorm:"many2many:book_categories;" json:"books,omitempty"`
+	// Children holds the subcategories in category trees
+	Children []Category `gorm:"-" json:"children,omitempty"`
 }
 
 type Book struct {
@@ -49,14 +56,47 @@ type Book struct {
 	Publisher      string         `json:"publisher"`
 	GoogleVolumeID string         `json:"google_volume_id"`
 	MainImage      string         `json:"main_image"`
+	CoverKey       string         `json:"-"`
+	CoverImages    CoverImages    `gorm:"type:jsonb" json:"cover_images"`
 	Format         BookFormat     `gorm:"type:varchar(20);default:'paperback'" json:"format"`
 	Language       string         `gorm:"type:varchar(2);default:'en';index:idx_books_language" json:"language"`
+	WorkID         *uuid.UUID     `gorm:"type:uuid;index:idx_books_work_id" json:"work_id"`
+	AverageRating  float64        `gorm:"type:numeric(3,2);not null;default:0" json:"average_rating"`
+	ReviewCount    int64          `gorm:"not null;default:0" json:"review_count"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 	Authors        []Author       `gorm:"many2many:book_authors;" json:"authors"`
 	Categories     []Category     `gorm:"many2many:book_categories;" json:"categories"`
 	Tags           []Tag          `gorm:"many2many:book_tags;" json:"tags"`
+	// EditionCount is the number of editions of the book's work, set when the listing is collapsed by work
+	EditionCount int64 `gorm:"-" json:"edition_count,omitempty"`
+	// Editions lists the other editions of the same work on the book detail
+	Editions []BookEdition `gorm:"-" json:"editions,omitempty"`
+}
+
+// CoverImages maps a cover size ("small", "medium", "large") to the URL of an
+// uploaded cover resized to that size
+type CoverImages map[string]string
+
+func (c CoverImages) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *CoverImages) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("unsupported cover images value %T", value)
 }
 
 type CreateBookRequest struct {
@@ -75,6 +115,56 @@ type CreateBookRequest struct {
 	AuthorIDs      []string   `json:"author_ids" binding:"required"`
 	CategoryIDs    []string   `json:"category_ids"`
 	TagIDs         []string   `json:"tag_ids"`
+	WorkID         *string    `json:"work_id"`
+}
+
+// BookFilters holds the catalog filters accepted by the book listing
+type BookFilters struct {
+	Query     string `json:"query,omitempty"`
+	Category  string `json:"category,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Language  string `json:"language,omitempty"`
+	TagKey    string `json:"tag_key,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Available bool   `json:"available,omitempty"`
+	// IncludeSubcategories extends the category filter to the subcategories of the matching categories
+	IncludeSubcategories bool `json:"include_subcategories,omitempty"`
+	// CollapseWorks shows one edition per work; it affects the listing and
+	// its facet counts but not which books match
+	CollapseWorks bool `json:"collapse_works,omitempty"`
+}
+
+// FacetBucket is one value of a facet and the number of books that have it
+type FacetBucket struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// BookFacets groups the facet buckets returned with the book listing
+type BookFacets struct {
+	Categories   []FacetBucket `json:"categories"`
+	Authors      []FacetBucket `json:"authors"`
+	Languages    []FacetBucket `json:"languages"`
+	Tags         []FacetBucket `json:"tags"`
+	Formats      []FacetBucket `json:"formats"`
+	Availability []FacetBucket `json:"availability"`
+}
+
+// BookSearchResult is a book matched by full-text search, with its relevance
+// and highlighted fragments of the matching fields
+type BookSearchResult struct {
+	Book         Book    `json:"book"`
+	Rank         float64 `json:"rank"`
+	TitleSnippet string  `json:"title_snippet"`
+	Snippet      string  `json:"snippet"`
+}
+
+// SimilarBook is a book similar to another one, with a score weighing the
+// authors, categories and tags they share
+type SimilarBook struct {
+	Book  Book `json:"book"`
+	Score int  `json:"score"`
 }
 
 func (b *Book) BeforeCreate(tx *gorm.DB) error {
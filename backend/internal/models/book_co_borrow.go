@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookCoBorrow records how many patrons who borrowed a book also borrowed a
+// related one. Rows are rebuilt from the reservation history by a background job.
+type BookCoBorrow struct {
+	BookID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"book_id"`
+	RelatedBookID uuid.UUID `gorm:"type:uuid;primaryKey" json:"related_book_id"`
+	Score         int       `gorm:"not null" json:"score"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RecommendedBook is a book recommended from the borrowing history, with a
+// score counting the patrons who borrowed it along with the books it is
+// recommended for
+type RecommendedBook struct {
+	Book  Book `json:"book"`
+	Score int  `json:"score"`
+}
@@ -17,24 +17,118 @@ const (
 	ConditionPoor    BookCondition = "poor"
 )
 
+// conditionGrades orders the conditions from worst to best
+var conditionGrades = map[BookCondition]int{
+	ConditionPoor:    1,
+	ConditionFair:    2,
+	ConditionGood:    3,
+	ConditionLikeNew: 4,
+	ConditionNew:     5,
+}
+
+// Valid reports whether c is a known condition
+func (c BookCondition) Valid() bool {
+	_, ok := conditionGrades[c]
+	return ok
+}
+
+// WorseThan reports whether c is a lower grade than previous. Nothing is
+// worse than an unknown previous condition.
+func (c BookCondition) WorseThan(previous BookCondition) bool {
+	grade, previousGrade := conditionGrades[c], conditionGrades[previous]
+	return previousGrade > 0 && grade < previousGrade
+}
+
 type BookCopyStatus string
 
 const (
 	BookCopyStatusAvailable BookCopyStatus = "available"
 	BookCopyStatusBorrowed  BookCopyStatus = "borrowed"
 	BookCopyStatusReserved  BookCopyStatus = "reserved"
+	BookCopyStatusLost      BookCopyStatus = "lost"
+	BookCopyStatusDamaged   BookCopyStatus = "damaged"
+	BookCopyStatusInRepair  BookCopyStatus = "in_repair"
+	BookCopyStatusWithdrawn BookCopyStatus = "withdrawn"
 )
 
+// LendableCopyStatuses are the statuses of copies in circulation. Copies
+// that are lost, damaged, in repair or withdrawn cannot be reserved and do
+// not count towards a book's copies.
+var LendableCopyStatuses = []BookCopyStatus{BookCopyStatusAvailable, BookCopyStatusReserved, BookCopyStatusBorrowed}
+
+// copyStatusTransitions lists the status changes staff can make by hand.
+// Moves between available, reserved and borrowed are made by reservations
+// and the desk instead. Withdrawn copies are gone for good.
+var copyStatusTransitions = map[BookCopyStatus][]BookCopyStatus{
+	BookCopyStatusAvailable: {BookCopyStatusLost, BookCopyStatusDamaged, BookCopyStatusInRepair, BookCopyStatusWithdrawn},
+	BookCopyStatusReserved:  {BookCopyStatusLost, BookCopyStatusDamaged, BookCopyStatusWithdrawn},
+	BookCopyStatusBorrowed:  {BookCopyStatusLost},
+	BookCopyStatusLost:      {BookCopyStatusAvailable, BookCopyStatusWithdrawn},
+	BookCopyStatusDamaged:   {BookCopyStatusAvailable, BookCopyStatusInRepair, BookCopyStatusWithdrawn},
+	BookCopyStatusInRepair:  {BookCopyStatusAvailable, BookCopyStatusDamaged, BookCopyStatusWithdrawn},
+}
+
+// Valid reports whether s is a known copy status
+func (s BookCopyStatus) Valid() bool {
+	_, ok := copyStatusTransitions[s]
+	return ok || s == BookCopyStatusWithdrawn
+}
+
+// Lendable reports whether a copy with status s is in circulation
+func (s BookCopyStatus) Lendable() bool {
+	for _, status := range LendableCopyStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanChangeTo reports whether staff can move a copy from status s to next
+func (s BookCopyStatus) CanChangeTo(next BookCopyStatus) bool {
+	for _, status := range copyStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
 type BookCopy struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	BookID    uuid.UUID      `gorm:"type:uuid;index:idx_book_copies_book_id" json:"book_id"`
-	Condition BookCondition  `gorm:"type:varchar(20)" json:"condition"`
-	Status    BookCopyStatus `gorm:"type:varchar(20);index:idx_book_copies_status;default:'available'" json:"status"`
-	Notes     string         `json:"notes"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	Book      Book           `json:"book,omitempty"`
+	ID              uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	BookID          uuid.UUID      `gorm:"type:uuid;index:idx_book_copies_book_id" json:"book_id"`
+	AccessionNumber string         `gorm:"type:varchar(30);uniqueIndex:idx_book_copies_accession_number;not null" json:"accession_number"`
+	Condition       BookCondition  `gorm:"type:varchar(20)" json:"condition"`
+	Status          BookCopyStatus `gorm:"type:varchar(20);index:idx_book_copies_status;default:'available'" json:"status"`
+	Notes           string         `json:"notes"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	Book            Book           `json:"book,omitempty"`
+}
+
+// BookCopyStatusChange is one entry of a copy's status history
+type BookCopyStatusChange struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	BookCopyID uuid.UUID      `gorm:"type:uuid;index:idx_book_copy_status_changes_copy,priority:1;not null" json:"book_copy_id"`
+	FromStatus BookCopyStatus `gorm:"type:varchar(20);not null" json:"from_status"`
+	ToStatus   BookCopyStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	Reason     string         `gorm:"not null" json:"reason"`
+	ActorID    *uuid.UUID     `gorm:"type:uuid" json:"actor_id"`
+	ActorEmail string         `json:"actor_email"`
+	CreatedAt  time.Time      `gorm:"index:idx_book_copy_status_changes_copy,priority:2" json:"created_at"`
+}
+
+type ChangeBookCopyStatusRequest struct {
+	Status BookCopyStatus `json:"status" binding:"required"`
+	Reason string         `json:"reason" binding:"required"`
+}
+
+func (c *BookCopyStatusChange) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
 }
 
 func (bc *BookCopy) BeforeCreate(tx *gorm.DB) error {
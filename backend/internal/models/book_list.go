@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookList is a named, persistent list of books kept by a user, such as
+// "Want to read" or "Favourites". Public lists can be opened by anyone
+// holding the share token.
+type BookList struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID      `gorm:"type:uuid;uniqueIndex:idx_book_lists_user_name,priority:1;not null" json:"user_id"`
+	Name        string         `gorm:"uniqueIndex:idx_book_lists_user_name,priority:2;not null" json:"name"`
+	Description string         `json:"description"`
+	IsPublic    bool           `gorm:"not null;default:false" json:"is_public"`
+	ShareToken  string         `gorm:"uniqueIndex:idx_book_lists_share_token;not null" json:"share_token"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Items       []BookListItem `json:"items,omitempty"`
+	User        *User          `json:"user,omitempty"`
+	// BookCount is the number of books on the list, set on the list overview
+	BookCount int64 `gorm:"-" json:"book_count"`
+}
+
+// BookListItem is a book on a list
+type BookListItem struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	BookListID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_book_list_items_list_book,priority:1;not null" json:"book_list_id"`
+	BookID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_book_list_items_list_book,priority:2;not null" json:"book_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Book       Book      `json:"book,omitempty"`
+}
+
+type BookListRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type BookListItemRequest struct {
+	BookID string `json:"book_id" binding:"required"`
+}
+
+func (l *BookList) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+func (i *BookListItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+// DeskScanRequest identifies a copy scanned at the desk, by the accession
+// number on its barcode or by its ID
+type DeskScanRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CheckOutResult is a copy handed out at the desk and the reservation it was lent on
+type CheckOutResult struct {
+	BookCopy    BookCopy    `json:"book_copy"`
+	Reservation Reservation `json:"reservation"`
+}
+
+// CheckInResult is a copy brought back to the desk. A copy that was not out
+// on an approved reservation is flagged as unexpected and left unchanged.
+type CheckInResult struct {
+	BookCopy    BookCopy     `json:"book_copy"`
+	Reservation *Reservation `json:"reservation,omitempty"`
+	// ReservationClosed is set when this was the last copy of the
+	// reservation to come back, marking it returned
+	ReservationClosed bool   `json:"reservation_closed"`
+	Unexpected        bool   `json:"unexpected"`
+	Message           string `json:"message,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// CopyInspection records the condition a copy was found in, normally when it
+// comes back from a reservation. The copy's Condition follows the latest
+// inspection, while the inspections keep its history.
+type CopyInspection struct {
+	ID                uuid.UUID     `gorm:"type:uuid;primary_key" json:"id"`
+	BookCopyID        uuid.UUID     `gorm:"type:uuid;index:idx_copy_inspections_copy,priority:1;not null" json:"book_copy_id"`
+	ReservationID     *uuid.UUID    `gorm:"type:uuid" json:"reservation_id"`
+	UserID            *uuid.UUID    `gorm:"type:uuid;index:idx_copy_inspections_user_id" json:"user_id"`
+	PreviousCondition BookCondition `gorm:"type:varchar(20)" json:"previous_condition"`
+	Condition         BookCondition `gorm:"type:varchar(20);not null" json:"condition"`
+	// Damaged is set when the copy came back in a worse condition, or when
+	// the inspector reported damage the condition grade does not show
+	Damaged        bool           `gorm:"not null;default:false" json:"damaged"`
+	Notes          string         `json:"notes"`
+	Photos         pq.StringArray `gorm:"type:text[]" json:"photos"`
+	InspectorID    *uuid.UUID     `gorm:"type:uuid" json:"inspector_id"`
+	InspectorEmail string         `json:"inspector_email"`
+	CreatedAt      time.Time      `gorm:"index:idx_copy_inspections_copy,priority:2" json:"created_at"`
+	BookCopy       *BookCopy      `json:"book_copy,omitempty"`
+}
+
+// CopyInspectionRequest is the form of an inspection, sent along with
+// optional photos
+type CopyInspectionRequest struct {
+	Condition BookCondition `form:"condition" binding:"required"`
+	Notes     string        `form:"notes"`
+	Damaged   bool          `form:"damaged"`
+	// ReservationID attributes the inspection to a reservation. Without it,
+	// only a copy that came back in the last week is attributed, to the
+	// reservation it came back from.
+	ReservationID string `form:"reservation_id"`
+}
+
+func (i *CopyInspection) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type JobRunStatus string
+
+const (
+	JobRunStatusRunning   JobRunStatus = "running"
+	JobRunStatusSucceeded JobRunStatus = "succeeded"
+	JobRunStatusFailed    JobRunStatus = "failed"
+)
+
+type JobTrigger string
+
+const (
+	JobTriggerSchedule JobTrigger = "schedule"
+	JobTriggerManual   JobTrigger = "manual"
+)
+
+// JobRun records one execution of a scheduled job
+type JobRun struct {
+	ID           uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	JobName      string       `gorm:"type:varchar(100);index:idx_job_runs_job_name_started_at,priority:1;not null" json:"job_name"`
+	Trigger      JobTrigger   `gorm:"type:varchar(20);not null" json:"trigger"`
+	TriggeredBy  *uuid.UUID   `gorm:"type:uuid" json:"triggered_by,omitempty"`
+	Status       JobRunStatus `gorm:"type:varchar(20);not null" json:"status"`
+	StartedAt    time.Time    `gorm:"index:idx_job_runs_job_name_started_at,priority:2" json:"started_at"`
+	FinishedAt   *time.Time   `json:"finished_at"`
+	RowsAffected int64        `json:"rows_affected"`
+	Error        string       `json:"error,omitempty"`
+}
+
+func (r *JobRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"
+	ReviewStatusApproved ReviewStatus = "approved"
+	ReviewStatusHidden   ReviewStatus = "hidden"
+)
+
+// Review is a patron's rating of a book with an optional written review. Only
+// approved reviews are shown and counted in the book's rating.
+type Review struct {
+	ID          uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	BookID      uuid.UUID    `gorm:"type:uuid;uniqueIndex:idx_reviews_book_user,priority:1;not null" json:"book_id"`
+	UserID      uuid.UUID    `gorm:"type:uuid;uniqueIndex:idx_reviews_book_user,priority:2;not null" json:"user_id"`
+	Rating      int          `gorm:"not null" json:"rating"`
+	Body        string       `json:"body"`
+	Status      ReviewStatus `gorm:"type:varchar(20);index:idx_reviews_status;not null" json:"status"`
+	ModeratedBy *uuid.UUID   `gorm:"type:uuid" json:"moderated_by,omitempty"`
+	ModeratedAt *time.Time   `json:"moderated_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	User        *User        `json:"user,omitempty"`
+	Book        *Book        `json:"book,omitempty"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+type UpdateReviewStatusRequest struct {
+	Status ReviewStatus `json:"status" binding:"required,oneof=approved hidden"`
+}
+
+func (r *Review) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import "github.com/google/uuid"
+
+type SuggestionType string
+
+const (
+	SuggestionTypeBook     SuggestionType = "book"
+	SuggestionTypeAuthor   SuggestionType = "author"
+	SuggestionTypeCategory SuggestionType = "category"
+	SuggestionTypeTag      SuggestionType = "tag"
+)
+
+// Suggestion is one typeahead completion for the search box
+type Suggestion struct {
+	Type  SuggestionType `json:"type"`
+	ID    uuid.UUID      `json:"id"`
+	Label string         `json:"label"`
+	// Key is the tag key used by the tag_key filter, set for tags only
+	Key   string  `json:"key,omitempty"`
+	Score float64 `json:"score"`
+}
@@ -18,6 +18,10 @@ type User struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 	Reservations []Reservation  `json:"reservations,omitempty"`
+	// DamageReportCount is the number of inspections that found a copy
+	// damaged after this user borrowed it. The reports themselves are listed
+	// by GET /users/:id/damage-reports.
+	DamageReportCount int64 `gorm:"-" json:"damage_report_count,omitempty"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
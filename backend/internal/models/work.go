@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Work groups the editions of the same title, e.g. its paperback and
+// hardcover or its English and Vietnamese printings
+type Work struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Title       string         `gorm:"not null" json:"title"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Editions    []BookEdition  `gorm:"-" json:"editions"`
+}
+
+// BookEdition is a summary of one edition of a work with its availability
+type BookEdition struct {
+	ID              uuid.UUID  `json:"id"`
+	Title           string     `json:"title"`
+	Subtitle        string     `json:"subtitle"`
+	ISBN13          *string    `json:"isbn13"`
+	PublishedYear   int        `json:"published_year"`
+	Publisher       string     `json:"publisher"`
+	MainImage       string     `json:"main_image"`
+	Format          BookFormat `json:"format"`
+	Language        string     `json:"language"`
+	TotalCopies     int64      `json:"total_copies"`
+	AvailableCopies int64      `json:"available_copies"`
+}
+
+type WorkRequest struct {
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description"`
+	BookIDs     []string `json:"book_ids"`
+}
+
+func (w *Work) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/hungcq/pscit/backend/internal/config"
 	handlers2 "github.com/hungcq/pscit/backend/internal/handlers"
 	"github.com/hungcq/pscit/backend/internal/middleware"
 	services2 "github.com/hungcq/pscit/backend/internal/services"
@@ -14,22 +17,81 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	authService := services2.NewAuthService(db)
 	bookService := services2.NewBookService(db)
 	bookCopyService := services2.NewBookCopyService(db)
+	copyLabelService := services2.NewCopyLabelService(db)
 	reservationService := services2.NewReservationService(db, services2.NewEmailService())
 	emailService := services2.NewEmailService()
+	circulationService := services2.NewCirculationService(db, emailService)
 	authorService := services2.NewAuthorService(db)
 	categoryService := services2.NewCategoryService(db)
 	cartService := services2.NewCartService(db)
 	tagService := services2.NewTagService(db)
+	bookImportService := services2.NewBookImportService(db, services2.NewMetadataProviders())
+	duplicateService := services2.NewDuplicateService(db)
+	maintenanceService := services2.NewMaintenanceService(db)
+	workService := services2.NewWorkService(db)
+	blobStore, err := services2.NewBlobStore()
+	if err != nil {
+		zap.L().Fatal("Failed to initialize blob store", zap.String("store", config.AppConfig.BlobStore), zap.Error(err))
+	}
+	bookCoverService := services2.NewBookCoverService(db, blobStore)
+	copyInspectionService := services2.NewCopyInspectionService(db, blobStore)
+	recommendationService := services2.NewRecommendationService(db)
+	reviewService := services2.NewReviewService(db)
+	bookListService := services2.NewBookListService(db, cartService)
+	auditService := services2.NewAuditService(db)
+	suggestService := services2.NewSuggestService(db)
+	catalogFeedService := services2.NewCatalogFeedService(db)
+	trashService := services2.NewTrashService(db, time.Duration(config.AppConfig.TrashRetentionDays)*24*time.Hour)
+
+	// Background jobs
+	jobScheduler := services2.NewJobScheduler(db)
+	registerJob(jobScheduler, services2.Job{
+		Name:        "orphan_cleanup",
+		Description: "Delete join rows pointing at missing records, then authors and categories without books",
+		Schedule:    config.AppConfig.OrphanCleanupSchedule,
+		Run:         maintenanceService.CleanOrphanedRecords,
+	})
+	registerJob(jobScheduler, services2.Job{
+		Name:        "co_borrow_refresh",
+		Description: "Rebuild the \"patrons who borrowed this also borrowed\" recommendations from the reservation history",
+		Schedule:    config.AppConfig.CoBorrowSchedule,
+		Run:         recommendationService.RefreshCoBorrows,
+	})
+	registerJob(jobScheduler, services2.Job{
+		Name:        "trash_purge",
+		Description: "Permanently delete books, authors, categories and tags that have been in the trash longer than the retention period",
+		Schedule:    config.AppConfig.TrashPurgeSchedule,
+		Run:         trashService.PurgeExpired,
+	})
+	if config.AppConfig.JobsEnabled {
+		jobScheduler.Start()
+	}
 
 	// Initialize handlers
 	authHandler := handlers2.NewAuthHandler(authService)
 	bookHandler := handlers2.NewBookHandler(bookService, db)
 	bookCopyHandler := handlers2.NewBookCopyHandler(bookCopyService)
+	copyLabelHandler := handlers2.NewCopyLabelHandler(copyLabelService)
+	copyInspectionHandler := handlers2.NewCopyInspectionHandler(copyInspectionService)
 	reservationHandler := handlers2.NewReservationHandler(reservationService, emailService)
+	circulationHandler := handlers2.NewCirculationHandler(circulationService)
 	authorHandler := handlers2.NewAuthorHandler(authorService)
 	categoryHandler := handlers2.NewCategoryHandler(categoryService)
 	cartHandler := handlers2.NewCartHandler(cartService)
 	tagHandler := handlers2.NewTagHandler(tagService)
+	bookImportHandler := handlers2.NewBookImportHandler(bookImportService)
+	duplicateHandler := handlers2.NewDuplicateHandler(duplicateService)
+	jobHandler := handlers2.NewJobHandler(jobScheduler)
+	workHandler := handlers2.NewWorkHandler(workService)
+	bookCoverHandler := handlers2.NewBookCoverHandler(bookCoverService)
+	recommendationHandler := handlers2.NewRecommendationHandler(recommendationService)
+	reviewHandler := handlers2.NewReviewHandler(reviewService)
+	bookListHandler := handlers2.NewBookListHandler(bookListService)
+	trashHandler := handlers2.NewTrashHandler(trashService)
+	auditHandler := handlers2.NewAuditHandler(auditService)
+	suggestHandler := handlers2.NewSuggestHandler(suggestService)
+	opdsHandler := handlers2.NewOPDSHandler(catalogFeedService)
+	syndicationHandler := handlers2.NewSyndicationHandler(catalogFeedService)
 
 	// Custom 404 handler
 	r.NoRoute(func(c *gin.Context) {
@@ -41,6 +103,11 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		})
 	})
 
+	// Uploaded files are served by the API itself when stored locally
+	if config.AppConfig.BlobStore == services2.BlobStoreLocal {
+		r.Static("/uploads", config.AppConfig.BlobLocalDir)
+	}
+
 	// Public routes
 	api := r.Group("/api")
 	api.GET("/health", func(c *gin.Context) {
@@ -55,23 +122,58 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	api.GET("/books", bookHandler.GetBooks)
 	api.GET("/books/search", bookHandler.SearchBooks)
 	api.GET("/books/:id", bookHandler.GetBook)
+	api.GET("/books/:id/similar", bookHandler.GetSimilarBooks)
+	api.GET("/books/:id/also-borrowed", recommendationHandler.GetCoBorrowedBooks)
+	api.GET("/books/:id/reviews", reviewHandler.GetBookReviews)
+
+	// Typeahead suggestions
+	api.GET("/suggest", suggestHandler.Suggest)
 
 	// Book copy routes
 	api.GET("/books/:id/copies", bookCopyHandler.GetBookCopies)
 	api.GET("/books/copies/:id", bookCopyHandler.GetBookCopy)
 
+	// Work routes
+	api.GET("/works/:id", workHandler.GetWork)
+
 	// Author routes
 	api.GET("/authors", authorHandler.GetAuthors)
 	api.GET("/authors/:id", authorHandler.GetAuthor)
 
 	// Categories routes
 	api.GET("/categories", categoryHandler.GetCategories)
+	api.GET("/categories/tree", categoryHandler.GetCategoryTree)
 	api.GET("/categories/:id", categoryHandler.GetCategory)
+	api.GET("/categories/:id/tree", categoryHandler.GetCategorySubtree)
 
 	// Tag routes
 	api.GET("/tags", tagHandler.GetTags)
 	api.GET("/tags/:id", tagHandler.GetTag)
 
+	// OPDS catalog for e-reader apps, as OPDS 1.2 and under /v2 as OPDS 2.0
+	for _, prefix := range []string{"/opds", "/opds/v2"} {
+		api.GET(prefix, opdsHandler.GetRoot)
+		api.GET(prefix+"/new", opdsHandler.GetNewArrivals)
+		api.GET(prefix+"/categories", opdsHandler.GetCategories)
+		api.GET(prefix+"/categories/:id", opdsHandler.GetCategoryBooks)
+		api.GET(prefix+"/authors", opdsHandler.GetAuthors)
+		api.GET(prefix+"/authors/:id", opdsHandler.GetAuthorBooks)
+		api.GET(prefix+"/tags", opdsHandler.GetTags)
+		api.GET(prefix+"/tags/:id", opdsHandler.GetTagBooks)
+	}
+
+	// New arrivals feeds for feed readers, as Atom and RSS
+	for _, prefix := range []string{"/feeds/atom", "/feeds/rss"} {
+		api.GET(prefix, syndicationHandler.GetNewArrivals)
+		api.GET(prefix+"/categories/:id", syndicationHandler.GetCategoryNewArrivals)
+		api.GET(prefix+"/tags/:id", syndicationHandler.GetTagNewArrivals)
+		api.GET(prefix+"/authors/:id", syndicationHandler.GetAuthorNewArrivals)
+		api.GET(prefix+"/languages/:language", syndicationHandler.GetLanguageNewArrivals)
+	}
+
+	// Shared book lists
+	api.GET("/lists/shared/:token", bookListHandler.GetSharedBookList)
+
 	// Protected routes
 	authenticatedApi := api.Use(middleware.AuthMiddleware())
 
@@ -86,24 +188,59 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	authenticatedApi.GET("/reservations/user", reservationHandler.GetUserReservations)
 	authenticatedApi.GET("/reservations/:id", reservationHandler.GetReservation)
 
+	// Recommendation routes
+	authenticatedApi.GET("/recommendations", recommendationHandler.GetUserRecommendations)
+
+	// Book list routes
+	authenticatedApi.GET("/lists", bookListHandler.GetBookLists)
+	authenticatedApi.POST("/lists", bookListHandler.CreateBookList)
+	authenticatedApi.GET("/lists/:id", bookListHandler.GetBookList)
+	authenticatedApi.PUT("/lists/:id", bookListHandler.UpdateBookList)
+	authenticatedApi.DELETE("/lists/:id", bookListHandler.DeleteBookList)
+	authenticatedApi.POST("/lists/:id/share-token", bookListHandler.RegenerateShareToken)
+	authenticatedApi.POST("/lists/:id/books", bookListHandler.AddBookToList)
+	authenticatedApi.DELETE("/lists/:id/books/:bookId", bookListHandler.RemoveBookFromList)
+	authenticatedApi.POST("/lists/:id/cart", bookListHandler.MoveToCart)
+
+	// Review routes
+	authenticatedApi.POST("/books/:bookId/reviews", reviewHandler.SaveReview)
+	authenticatedApi.DELETE("/reviews/:id", reviewHandler.DeleteReview)
+
 	// Admin routes
 	admin := authenticatedApi.Use(middleware.AdminMiddleware())
 	{
 		// Book management
 		admin.POST("/books", bookHandler.CreateBook)
 		admin.POST("/books/bulk", bookHandler.BulkCreateBooks)
+		admin.POST("/books/import", bookImportHandler.ImportFile)
+		admin.GET("/books/export", bookHandler.ExportBooks)
+		admin.POST("/books/isbn/normalize", bookHandler.NormalizeISBNs)
+		admin.POST("/books/import/isbn", bookImportHandler.ImportISBNs)
 		admin.PUT("/books/:id", bookHandler.UpdateBook)
 		admin.DELETE("/books/:id", bookHandler.DeleteBook)
+		admin.POST("/books/:bookId/cover", bookCoverHandler.UploadCover)
+		admin.DELETE("/books/:id/cover", bookCoverHandler.DeleteCover)
+
+		// Work management
+		admin.POST("/works", workHandler.CreateWork)
+		admin.PUT("/works/:id", workHandler.UpdateWork)
+		admin.DELETE("/works/:id", workHandler.DeleteWork)
+		admin.POST("/works/:id/books", workHandler.AddEditions)
+		admin.DELETE("/works/:id/books/:bookId", workHandler.RemoveEdition)
 
 		// Author management
 		admin.POST("/authors", authorHandler.CreateAuthor)
 		admin.PUT("/authors/:id", authorHandler.UpdateAuthor)
 		admin.DELETE("/authors/:id", authorHandler.DeleteAuthor)
+		admin.GET("/authors/duplicates", duplicateHandler.GetDuplicateAuthors)
+		admin.POST("/authors/merge", duplicateHandler.MergeAuthors)
 
 		// Category management
 		admin.POST("/categories", categoryHandler.CreateCategory)
 		admin.PUT("/categories/:id", categoryHandler.UpdateCategory)
 		admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		admin.GET("/categories/duplicates", duplicateHandler.GetDuplicateCategories)
+		admin.POST("/categories/merge", duplicateHandler.MergeCategories)
 
 		// Tag management
 		admin.POST("/tags", tagHandler.CreateTag)
@@ -113,12 +250,44 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		// Reservation management
 		admin.GET("/reservations", reservationHandler.GetReservations)
 		admin.PUT("/reservations/:id/status", reservationHandler.UpdateReservationStatus)
+		admin.POST("/circulation/checkout", circulationHandler.CheckOut)
+		admin.POST("/circulation/checkin", circulationHandler.CheckIn)
+
+		// Review moderation
+		admin.GET("/reviews", reviewHandler.GetReviews)
+		admin.PUT("/reviews/:id/status", reviewHandler.UpdateReviewStatus)
 
 		// Book copy management
 		admin.POST("/books/:bookId/copies", bookCopyHandler.CreateBookCopy)
 		admin.POST("/books/:bookId/copies/bulk", bookCopyHandler.BulkCreateBookCopies)
 		admin.PUT("/books/copies/:id", bookCopyHandler.UpdateBookCopy)
 		admin.DELETE("/books/copies/:id", bookCopyHandler.DeleteBookCopy)
-		admin.PUT("/books/copies/:id/availability", bookCopyHandler.UpdateBookCopyAvailability)
+		admin.PUT("/books/copies/:id/status", bookCopyHandler.ChangeBookCopyStatus)
+		admin.GET("/books/copies/:id/status-history", bookCopyHandler.GetBookCopyStatusHistory)
+		admin.POST("/books/copies/:id/inspections", copyInspectionHandler.InspectCopy)
+		admin.GET("/books/copies/:id/inspections", copyInspectionHandler.GetCopyInspections)
+		admin.GET("/users/:id/damage-reports", copyInspectionHandler.GetUserDamageReports)
+		admin.GET("/books/copies/accession/:number", bookCopyHandler.GetBookCopyByAccessionNumber)
+		admin.GET("/books/copies/:id/barcode", copyLabelHandler.GetBarcode)
+		admin.POST("/books/copies/labels", copyLabelHandler.PrintLabels)
+
+		// Trash
+		admin.GET("/trash", trashHandler.GetTrash)
+		admin.POST("/trash/:type/:id/restore", trashHandler.RestoreFromTrash)
+
+		// Audit log
+		admin.GET("/audit-logs", auditHandler.GetAuditLogs)
+		admin.GET("/audit-logs/:type/:id", auditHandler.GetEntityHistory)
+
+		// Background jobs
+		admin.GET("/jobs", jobHandler.GetJobs)
+		admin.POST("/jobs/:name/trigger", jobHandler.TriggerJob)
+		admin.GET("/jobs/:name/runs", jobHandler.GetJobRuns)
+	}
+}
+
+func registerJob(scheduler *services2.JobScheduler, job services2.Job) {
+	if err := scheduler.Register(job); err != nil {
+		zap.L().Fatal("Failed to register job", zap.String("job", job.Name), zap.Error(err))
 	}
 }
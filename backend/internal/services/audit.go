@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrUnknownAuditEntity = errors.New("unknown audit entity type")
+
+var auditEntityTypes = map[string]bool{
+	models.AuditEntityBooks:        true,
+	models.AuditEntityBookCopies:   true,
+	models.AuditEntityAuthors:      true,
+	models.AuditEntityCategories:   true,
+	models.AuditEntityTags:         true,
+	models.AuditEntityReservations: true,
+}
+
+// auditIgnoredFields are already on the entry or change on every write
+var auditIgnoredFields = []string{"id", "created_at", "updated_at"}
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// GetAuditLogs retrieves the change history, newest first
+func (s *AuditService) GetAuditLogs(filters models.AuditLogFilters, page, limit int) ([]models.AuditLog, int64, error) {
+	if filters.EntityType != "" && !auditEntityTypes[filters.EntityType] {
+		return nil, 0, ErrUnknownAuditEntity
+	}
+
+	query := s.db.Model(&models.AuditLog{})
+	if filters.EntityType != "" {
+		query = query.Where("entity_type = ?", filters.EntityType)
+	}
+	if filters.Action != "" {
+		query = query.Where("action = ?", filters.Action)
+	}
+	if filters.ActorEmail != "" {
+		query = query.Where("actor_email ILIKE ?", "%"+filters.ActorEmail+"%")
+	}
+	return s.findAuditLogs(query, page, limit)
+}
+
+// GetEntityHistory retrieves the changes made to one entity, newest first
+func (s *AuditService) GetEntityHistory(entityType, entityID string, page, limit int) ([]models.AuditLog, int64, error) {
+	if !auditEntityTypes[entityType] {
+		return nil, 0, ErrUnknownAuditEntity
+	}
+	id, err := uuid.Parse(entityID)
+	if err != nil {
+		return []models.AuditLog{}, 0, nil
+	}
+	query := s.db.Model(&models.AuditLog{}).Where("entity_type = ? AND entity_id = ?", entityType, id)
+	return s.findAuditLogs(query, page, limit)
+}
+
+func (s *AuditService) findAuditLogs(query *gorm.DB, page, limit int) ([]models.AuditLog, int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		zap.L().Error("findAuditLogs: Failed to count audit logs", zap.Error(err))
+		return nil, 0, err
+	}
+	logs := []models.AuditLog{}
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error; err != nil {
+		zap.L().Error("findAuditLogs: Failed to get audit logs", zap.Error(err))
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// recordAudit appends an entry to the audit log, normally inside the
+// transaction making the change so that both are committed together. before
+// is nil for a created entity and after is nil for a deleted one. Updates
+// that change nothing are not recorded.
+func recordAudit(tx *gorm.DB, actor models.Actor, action models.AuditAction, entityType string, entityID uuid.UUID, before, after interface{}) error {
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	if action == models.AuditActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&models.AuditLog{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		ActorID:    actor.UserID,
+		ActorEmail: actor.Email,
+		Changes:    changes,
+	}).Error
+}
+
+// auditDiff compares the fields of two snapshots of an entity
+func auditDiff(before, after interface{}) (models.AuditChanges, error) {
+	old, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.AuditChanges{}
+	for field, value := range old {
+		if newValue, ok := updated[field]; !ok || !sameAuditValue(value, newValue) {
+			changes[field] = models.AuditChange{Old: value, New: newValue}
+		}
+	}
+	for field, value := range updated {
+		if _, ok := old[field]; !ok {
+			changes[field] = models.AuditChange{New: value}
+		}
+	}
+	return changes, nil
+}
+
+// sameAuditValue compares two snapshot values. An association that was not
+// loaded (null) and one without records are the same.
+func sameAuditValue(a, b interface{}) bool {
+	if ids, ok := a.([]string); ok && len(ids) == 0 && b == nil {
+		return true
+	}
+	if ids, ok := b.([]string); ok && len(ids) == 0 && a == nil {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// auditSnapshot flattens an entity to its JSON fields. Associations are
+// reduced to the IDs of the linked records, except single related records
+// which are dropped since their foreign key is already a field.
+func auditSnapshot(entity interface{}) (map[string]interface{}, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(entity); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	for _, field := range auditIgnoredFields {
+		delete(snapshot, field)
+	}
+	for field, value := range snapshot {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if _, ok := v["id"]; ok {
+				delete(snapshot, field)
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				snapshot[field] = []string{}
+				continue
+			}
+			if _, ok := v[0].(map[string]interface{}); !ok {
+				continue
+			}
+			// Association order depends on the query, so it is not compared
+			ids := make([]string, 0, len(v))
+			for _, item := range v {
+				if record, ok := item.(map[string]interface{}); ok {
+					ids = append(ids, fmt.Sprint(record["id"]))
+				}
+			}
+			sort.Strings(ids)
+			snapshot[field] = ids
+		}
+	}
+	return snapshot, nil
+}
@@ -42,8 +42,13 @@ func (s *AuthorService) GetAuthor(id string) (*models.Author, error) {
 	return &author, nil
 }
 
-func (s *AuthorService) CreateAuthor(author *models.Author) error {
-	if err := s.db.Create(author).Error; err != nil {
+func (s *AuthorService) CreateAuthor(author *models.Author, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(author).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityAuthors, author.ID, nil, author)
+	}); err != nil {
 		zap.L().Error("CreateAuthor: Failed to create author", zap.String("name", author.Name), zap.Error(err))
 		return err
 	}
@@ -51,33 +56,53 @@ func (s *AuthorService) CreateAuthor(author *models.Author) error {
 	return nil
 }
 
-func (s *AuthorService) UpdateAuthor(id string, author *models.Author) error {
-	result := s.db.Model(&models.Author{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"name":      author.Name,
-		"biography": author.Biography,
-	})
-	if result.Error != nil {
-		zap.L().Error("UpdateAuthor: Failed to update author", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("UpdateAuthor: Author not found for update", zap.String("id", id))
-		return errors.New("author not found")
+func (s *AuthorService) UpdateAuthor(id string, author *models.Author, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing, updated models.Author
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("UpdateAuthor: Author not found for update", zap.String("id", id))
+				return errors.New("author not found")
+			}
+			return err
+		}
+		if err := tx.Model(&models.Author{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"name":      author.Name,
+			"biography": author.Biography,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&updated, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityAuthors, updated.ID, &existing, &updated)
+	}); err != nil {
+		zap.L().Error("UpdateAuthor: Failed to update author", zap.String("id", id), zap.Error(err))
+		return err
 	}
 	zap.L().Info("UpdateAuthor: Author updated successfully", zap.String("id", id), zap.String("name", author.Name))
 	return nil
 }
 
-func (s *AuthorService) DeleteAuthor(id string) error {
-	result := s.db.Unscoped().Delete(&models.Author{}, "id = ?", id)
-	if result.Error != nil {
-		zap.L().Error("DeleteAuthor: Failed to delete author", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("DeleteAuthor: Author not found for deletion", zap.String("id", id))
-		return errors.New("author not found")
+func (s *AuthorService) DeleteAuthor(id string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Author
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("DeleteAuthor: Author not found for deletion", zap.String("id", id))
+				return errors.New("author not found")
+			}
+			return err
+		}
+		// Soft delete keeps the book links so that restoring from the trash brings them back
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionDelete, models.AuditEntityAuthors, existing.ID, &existing, nil)
+	}); err != nil {
+		zap.L().Error("DeleteAuthor: Failed to delete author", zap.String("id", id), zap.Error(err))
+		return err
 	}
-	zap.L().Info("DeleteAuthor: Author deleted successfully", zap.String("id", id))
+	zap.L().Info("DeleteAuthor: Author moved to trash", zap.String("id", id))
 	return nil
 }
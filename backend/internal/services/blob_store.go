@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hungcq/pscit/backend/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/zap"
+)
+
+// Supported blob store backends
+const (
+	BlobStoreLocal = "local"
+	BlobStoreS3    = "s3"
+)
+
+// BlobStore keeps uploaded files and hands out the public URL of each one.
+// Keys are slash-separated relative paths such as "book-covers/<id>/small.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewBlobStore returns the blob store selected by BLOB_STORE
+func NewBlobStore() (BlobStore, error) {
+	cfg := config.AppConfig
+	switch cfg.BlobStore {
+	case BlobStoreLocal:
+		return NewLocalBlobStore(cfg.BlobLocalDir, cfg.BlobPublicURL), nil
+	case BlobStoreS3:
+		return NewS3BlobStore(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3UseSSL, cfg.BlobPublicURL)
+	}
+	return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore)
+}
+
+// LocalBlobStore writes blobs under a directory that the server also serves
+type LocalBlobStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalBlobStore(dir, baseURL string) *LocalBlobStore {
+	return &LocalBlobStore{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// path maps a key into the store directory, refusing keys that escape it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(cleaned) || cleaned == "." || strings.HasPrefix(cleaned, "..") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, cleaned), nil
+}
+
+// S3BlobStore stores blobs in a bucket of any S3-compatible service (AWS S3, MinIO)
+type S3BlobStore struct {
+	client  *minio.Client
+	bucket  string
+	baseURL string
+}
+
+// NewS3BlobStore connects to the endpoint ("s3.amazonaws.com", "localhost:9000").
+// URLs point at baseURL, typically a CDN in front of the bucket, or at the
+// bucket itself when baseURL is empty.
+func NewS3BlobStore(endpoint, region, bucket, accessKey, secretKey string, useSSL bool, baseURL string) (*S3BlobStore, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		bucketURL := url.URL{Scheme: "http", Host: endpoint, Path: "/" + bucket}
+		if useSSL {
+			bucketURL.Scheme = "https"
+		}
+		baseURL = bucketURL.String()
+	}
+	return &S3BlobStore{
+		client:  client,
+		bucket:  bucket,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+		// Keys are never overwritten with different content, so caches may keep them forever
+		CacheControl: "public, max-age=31536000, immutable",
+	})
+	if err != nil {
+		zap.L().Error("S3BlobStore: Failed to put object", zap.String("bucket", s.bucket), zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		zap.L().Error("S3BlobStore: Failed to remove object", zap.String("bucket", s.bucket), zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+func (s *S3BlobStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
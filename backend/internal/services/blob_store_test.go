@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStorePutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalBlobStore(dir, "http://localhost:8080/uploads/")
+	ctx := context.Background()
+	key := "book-covers/abc/small.jpg"
+
+	if err := store.Put(ctx, key, strings.NewReader("cover"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "book-covers", "abc", "small.jpg"))
+	if err != nil || string(data) != "cover" {
+		t.Fatalf("got %q, %v after put, want the blob content", data, err)
+	}
+	if got, want := store.URL(key), "http://localhost:8080/uploads/book-covers/abc/small.jpg"; got != want {
+		t.Errorf("got URL %q, want %q", got, want)
+	}
+
+	// Overwriting replaces the content without leaving temporary files behind
+	if err := store.Put(ctx, key, strings.NewReader("new cover"), 9, "image/jpeg"); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "book-covers", "abc"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("got %v, %v in the blob directory, want only the blob", entries, err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "book-covers", "abc", "small.jpg")); !os.IsNotExist(err) {
+		t.Errorf("got %v after delete, want the blob to be gone", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestLocalBlobStoreRejectsEscapingKeys(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir(), "")
+	for _, key := range []string{"", ".", "../outside.jpg", "covers/../../outside.jpg", "/etc/passwd"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("put %q: got no error, want the key rejected", key)
+		}
+		if err := store.Delete(context.Background(), key); err == nil {
+			t.Errorf("delete %q: got no error, want the key rejected", key)
+		}
+	}
+}
@@ -2,13 +2,17 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/hungcq/pscit/backend/internal/models"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookService struct {
@@ -20,67 +24,20 @@ func NewBookService(db *gorm.DB) *BookService {
 }
 
 // GetBooks retrieves books with pagination and filtering
-func (s *BookService) GetBooks(query, category, author, language, tagKey string, available bool, page, limit int, sortField, sortOrder string) ([]models.Book, int64, error) {
+func (s *BookService) GetBooks(filters models.BookFilters, page, limit int, sortField, sortOrder string) ([]models.Book, int64, error) {
 	var books []models.Book
 	var total int64
 
-	// Build base query
-	baseQuery := s.db.Model(&models.Book{})
-
-	// Apply filters
-	if query != "" {
-		query = "%" + query + "%"
-		baseQuery = baseQuery.Where(
-			"unaccent(title) ILIKE unaccent(?) OR unaccent(subtitle) ILIKE unaccent(?) OR unaccent(isbn10) ILIKE unaccent(?) OR unaccent(isbn13) ILIKE unaccent(?)",
-			query, query, query, query,
-		)
-	}
-
-	// Create subquery for distinct book IDs
-	subQuery := baseQuery.Table("books").Select("books.id")
-
-	if category != "" {
-		subQuery = subQuery.Distinct("books.id").
-			Joins("JOIN book_categories ON books.id = book_categories.book_id").
-			Joins("JOIN categories ON book_categories.category_id = categories.id").
-			Where("unaccent(categories.name) ILIKE unaccent(?)", "%"+category+"%")
-	}
-
-	if author != "" {
-		subQuery = subQuery.Distinct("books.id").
-			Joins("JOIN book_authors ON books.id = book_authors.book_id").
-			Joins("JOIN authors ON book_authors.author_id = authors.id").
-			Where("unaccent(authors.name) ILIKE unaccent(?)", "%"+author+"%")
-	}
-
-	if language != "" {
-		subQuery = subQuery.Where("books.language = ?", language)
-	}
-
-	if tagKey != "" {
-		subQuery = subQuery.Distinct("books.id").
-			Joins("JOIN book_tags ON books.id = book_tags.book_id").
-			Joins("JOIN tags ON book_tags.tag_id = tags.id").
-			Where("tags.key = ?", tagKey)
-	}
-
-	if available {
-		subQuery = subQuery.Distinct("books.id").
-			Joins("JOIN book_copies bc ON bc.book_id = books.id").
-			Where("bc.status = ? AND bc.deleted_at IS NULL", "available")
-	}
-
-	// Build final query with preloading
-	dbQuery := s.db.Model(&models.Book{}).
-		Preload("Authors").
-		Preload("Categories").
-		Preload("Tags").
-		Where("id IN (?)", subQuery)
+	tsQuery := buildSearchQuery(filters.Query)
+	dbQuery := s.bookListQuery(filters)
 
 	// Apply sorting
 	if sortField == "" {
 		sortField = "created_at"
 		sortOrder = "ascend"
+		if filters.Query != "" {
+			sortField = "relevance"
+		}
 	}
 
 	order := "ASC"
@@ -98,6 +55,15 @@ func (s *BookService) GetBooks(query, category, author, language, tagKey string,
 			Order("MIN(authors.name) " + order)
 	case "created_at":
 		dbQuery = dbQuery.Order("created_at " + order)
+	case "rating":
+		// Among equally rated books, the one with more reviews ranks first
+		dbQuery = dbQuery.Order("average_rating " + order).Order("review_count DESC")
+	case "relevance":
+		dbQuery = dbQuery.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank_cd(books.search_vector, to_tsquery('simple_unaccent', ?)) DESC, books.created_at DESC",
+			Vars:               []interface{}{tsQuery},
+			WithoutParentheses: true,
+		}})
 	}
 
 	// Get total count
@@ -112,10 +78,173 @@ func (s *BookService) GetBooks(query, category, author, language, tagKey string,
 		zap.L().Error("GetBooks: Failed to retrieve books with pagination", zap.Int("page", page), zap.Int("limit", limit), zap.Error(err))
 		return nil, 0, err
 	}
-	zap.L().Info("GetBooks: Successfully retrieved books", zap.Int64("total", total), zap.Int("page", page), zap.Int("limit", limit), zap.String("query", query), zap.String("category", category), zap.String("author", author), zap.String("language", language), zap.String("tagKey", tagKey))
+	if filters.CollapseWorks {
+		if err := s.setEditionCounts(books); err != nil {
+			return nil, 0, err
+		}
+	}
+	zap.L().Info("GetBooks: Successfully retrieved books", zap.Int64("total", total), zap.Int("page", page), zap.Int("limit", limit), zap.Any("filters", filters))
 	return books, total, nil
 }
 
+// bookSortKeys are the sort expressions usable with cursor pagination
+var bookSortKeys = map[string]string{
+	"title":      "books.title",
+	"authors":    "COALESCE((SELECT MIN(a.name) FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = books.id), '')",
+	"created_at": "books.created_at",
+}
+
+// GetBooksByCursor retrieves a page of books after or before cursor, which is
+// empty for the first page. Unlike GetBooks it does not count the matches and
+// its pages stay stable while books are added or removed.
+func (s *BookService) GetBooksByCursor(filters models.BookFilters, cursor string, limit int, sortField, sortOrder string) ([]models.Book, CursorPage, error) {
+	if sortField == "" {
+		sortField = "created_at"
+	}
+	expr, ok := bookSortKeys[sortField]
+	if !ok {
+		return nil, CursorPage{}, fmt.Errorf("%w: cursor pagination supports sorting by title, authors or created_at", ErrInvalidCursor)
+	}
+	keys, err := newKeyset(cursor, sortField, expr, "books.id", sortOrder == "descend", sortField == "created_at")
+	if err != nil {
+		return nil, CursorPage{}, err
+	}
+	limit = cursorLimit(limit)
+
+	var books []models.Book
+	if err := keys.apply(s.bookListQuery(filters), limit).Find(&books).Error; err != nil {
+		zap.L().Error("GetBooksByCursor: Failed to retrieve books", zap.String("sortField", sortField), zap.Int("limit", limit), zap.Error(err))
+		return nil, CursorPage{}, err
+	}
+
+	// The authors key is computed in SQL, where names compare by the
+	// database collation, so it is read back rather than derived in Go
+	var authorKeys map[uuid.UUID]string
+	if sortField == "authors" && len(books) > 0 {
+		authorKeys, err = s.bookSortKeyValues(expr, books[0].ID, books[len(books)-1].ID)
+		if err != nil {
+			zap.L().Error("GetBooksByCursor: Failed to read sort keys", zap.Error(err))
+			return nil, CursorPage{}, err
+		}
+	}
+	books, page := finishKeysetPage(keys, books, limit, func(book models.Book) (string, uuid.UUID) {
+		switch sortField {
+		case "title":
+			return book.Title, book.ID
+		case "authors":
+			return authorKeys[book.ID], book.ID
+		}
+		return timeCursorKey(book.CreatedAt), book.ID
+	})
+
+	if filters.CollapseWorks {
+		if err := s.setEditionCounts(books); err != nil {
+			return nil, CursorPage{}, err
+		}
+	}
+	zap.L().Info("GetBooksByCursor: Successfully retrieved books", zap.Int("count", len(books)), zap.Int("limit", limit), zap.Any("filters", filters))
+	return books, page, nil
+}
+
+// bookListQuery selects the books matching the filters with their relations preloaded
+func (s *BookService) bookListQuery(filters models.BookFilters) *gorm.DB {
+	subQuery := s.filteredBookIDs(filters, "")
+	if filters.CollapseWorks {
+		subQuery = s.collapseByWork(subQuery)
+	}
+	return s.db.Model(&models.Book{}).
+		Preload("Authors").
+		Preload("Categories").
+		Preload("Tags").
+		Where("id IN (?)", subQuery)
+}
+
+func (s *BookService) bookSortKeyValues(expr string, ids ...uuid.UUID) (map[uuid.UUID]string, error) {
+	var rows []struct {
+		ID  uuid.UUID
+		Key string
+	}
+	if err := s.db.Model(&models.Book{}).
+		Select("books.id, "+expr+" AS key").
+		Where("books.id IN ?", ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	keys := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		keys[row.ID] = row.Key
+	}
+	return keys, nil
+}
+
+// filteredBookIDs builds a subquery selecting the IDs of books that match the
+// filters. The filter named by skip ("category", "author", "language", "tag",
+// "format" or "available") is left out; facet counting uses this to count the
+// alternatives for a dimension the user has already narrowed down.
+func (s *BookService) filteredBookIDs(filters models.BookFilters, skip string) *gorm.DB {
+	subQuery := s.db.Model(&models.Book{}).Table("books").Select("books.id")
+
+	if filters.Query != "" {
+		isbn := strings.ReplaceAll(filters.Query, "-", "")
+		// Only a query with digits can be part of an ISBN, anything else
+		// would match every book
+		if strings.ContainsAny(isbn, "0123456789") {
+			isbnQuery := "%" + isbn + "%"
+			subQuery = subQuery.Where(
+				"books.search_vector @@ to_tsquery('simple_unaccent', ?) OR books.isbn10 ILIKE ? OR books.isbn13 ILIKE ?",
+				buildSearchQuery(filters.Query), isbnQuery, isbnQuery,
+			)
+		} else {
+			subQuery = subQuery.Where("books.search_vector @@ to_tsquery('simple_unaccent', ?)", buildSearchQuery(filters.Query))
+		}
+	}
+
+	if filters.Category != "" && skip != "category" {
+		if filters.IncludeSubcategories {
+			subQuery = subQuery.Where(
+				"books.id IN (SELECT book_id FROM book_categories WHERE category_id IN ("+
+					categorySubtreeSQL("unaccent(name) ILIKE unaccent(?)", false)+"))",
+				"%"+filters.Category+"%",
+			)
+		} else {
+			subQuery = subQuery.Distinct("books.id").
+				Joins("JOIN book_categories ON books.id = book_categories.book_id").
+				Joins("JOIN categories ON book_categories.category_id = categories.id AND categories.deleted_at IS NULL").
+				Where("unaccent(categories.name) ILIKE unaccent(?)", "%"+filters.Category+"%")
+		}
+	}
+
+	if filters.Author != "" && skip != "author" {
+		subQuery = subQuery.Distinct("books.id").
+			Joins("JOIN book_authors ON books.id = book_authors.book_id").
+			Joins("JOIN authors ON book_authors.author_id = authors.id AND authors.deleted_at IS NULL").
+			Where("unaccent(authors.name) ILIKE unaccent(?)", "%"+filters.Author+"%")
+	}
+
+	if filters.Language != "" && skip != "language" {
+		subQuery = subQuery.Where("books.language = ?", filters.Language)
+	}
+
+	if filters.TagKey != "" && skip != "tag" {
+		subQuery = subQuery.Distinct("books.id").
+			Joins("JOIN book_tags ON books.id = book_tags.book_id").
+			Joins("JOIN tags ON book_tags.tag_id = tags.id AND tags.deleted_at IS NULL").
+			Where("tags.key = ?", filters.TagKey)
+	}
+
+	if filters.Format != "" && skip != "format" {
+		subQuery = subQuery.Where("books.format = ?", filters.Format)
+	}
+
+	if filters.Available && skip != "available" {
+		subQuery = subQuery.Distinct("books.id").
+			Joins("JOIN book_copies bc ON bc.book_id = books.id").
+			Where("bc.status = ? AND bc.deleted_at IS NULL", "available")
+	}
+
+	return subQuery
+}
+
 // GetBook retrieves a single book by ID
 func (s *BookService) GetBook(id string) (*models.Book, error) {
 	var book models.Book
@@ -132,25 +261,66 @@ func (s *BookService) GetBook(id string) (*models.Book, error) {
 }
 
 // CreateBook creates a new book
-func (s *BookService) CreateBook(book *models.Book) error {
-	if err := s.db.Create(book).Error; err != nil {
-		zap.L().Error("CreateBook: Failed to create book", zap.String("title", book.Title), zap.Error(err))
+func (s *BookService) CreateBook(book *models.Book, actor models.Actor) error {
+	if err := normalizeBookISBNs(book); err != nil {
+		zap.L().Warn("CreateBook: Invalid ISBN", zap.String("title", book.Title), zap.Error(err))
 		return err
 	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return createBook(tx, book, actor)
+	}); err != nil {
+		zap.L().Error("CreateBook: Failed to create book", zap.String("title", book.Title), zap.Error(err))
+		return explainISBNConflict(s.db, book.ID, book, err)
+	}
 	zap.L().Info("CreateBook: Book created successfully", zap.String("id", book.ID.String()), zap.String("title", book.Title))
 	return nil
 }
 
-// UpdateBook updates an existing book
-func (s *BookService) UpdateBook(id string, book *models.Book) error {
+// createBook inserts a book with normalized ISBNs within tx
+func createBook(tx *gorm.DB, book *models.Book, actor models.Actor) error {
+	if err := checkISBNConflicts(tx, book.ID, book); err != nil {
+		zap.L().Warn("createBook: ISBN already in use", zap.String("title", book.Title), zap.Error(err))
+		return err
+	}
+	if err := checkWorkExists(tx, book.WorkID); err != nil {
+		return err
+	}
+	if err := tx.Create(book).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityBooks, book.ID, nil, book)
+}
+
+// UpdateBook updates an existing book. The book is only moved to book.WorkID
+// when updateWork is set, since most edits do not concern its work.
+func (s *BookService) UpdateBook(id string, book *models.Book, updateWork bool, actor models.Actor) error {
+	if err := normalizeBookISBNs(book); err != nil {
+		zap.L().Warn("UpdateBook: Invalid ISBN", zap.String("id", id), zap.Error(err))
+		return err
+	}
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		// Get existing book
 		var existingBook models.Book
-		if err := tx.Preload("Authors").Preload("Categories").First(&existingBook, "id = ?", id).Error; err != nil {
+		if err := tx.Preload("Authors").Preload("Categories").Preload("Tags").First(&existingBook, "id = ?", id).Error; err != nil {
 			zap.L().Error("UpdateBook: Existing book not found for update", zap.String("id", id), zap.Error(err))
 			return err
 		}
 
+		// The updates below modify existingBook, keep the old values for the audit log
+		before := existingBook
+		before.Authors = append([]models.Author(nil), existingBook.Authors...)
+		before.Categories = append([]models.Category(nil), existingBook.Categories...)
+		before.Tags = append([]models.Tag(nil), existingBook.Tags...)
+
+		if err := checkISBNConflicts(tx, existingBook.ID, book); err != nil {
+			return err
+		}
+		if updateWork {
+			if err := checkWorkExists(tx, book.WorkID); err != nil {
+				return err
+			}
+		}
+
 		// Update authors
 		if err := tx.Model(&existingBook).Association("Authors").Replace(book.Authors); err != nil {
 			zap.L().Error("UpdateBook: Failed to update authors association", zap.String("id", id), zap.Error(err))
@@ -170,7 +340,7 @@ func (s *BookService) UpdateBook(id string, book *models.Book) error {
 		}
 
 		// Update book fields
-		if err := tx.Model(&existingBook).Updates(map[string]interface{}{
+		updates := map[string]interface{}{
 			"title":            book.Title,
 			"subtitle":         book.Subtitle,
 			"description":      book.Description,
@@ -182,70 +352,151 @@ func (s *BookService) UpdateBook(id string, book *models.Book) error {
 			"google_volume_id": book.GoogleVolumeID,
 			"main_image":       book.MainImage,
 			"format":           book.Format,
-		}).Error; err != nil {
+		}
+		if updateWork {
+			updates["work_id"] = book.WorkID
+		}
+		if err := tx.Model(&existingBook).Updates(updates).Error; err != nil {
 			zap.L().Error("UpdateBook: Failed to update book fields", zap.String("id", id), zap.Error(err))
 			return err
 		}
 
-		return nil
+		var updatedBook models.Book
+		if err := tx.Preload("Authors").Preload("Categories").Preload("Tags").First(&updatedBook, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, existingBook.ID, &before, &updatedBook)
 	}); err != nil {
 		zap.L().Error("UpdateBook: Transaction failed", zap.String("id", id), zap.Error(err))
-		return err
+		return explainISBNConflict(s.db, book.ID, book, err)
 	}
 	zap.L().Info("UpdateBook: Book updated successfully", zap.String("id", id), zap.String("title", book.Title))
 	return nil
 }
 
 // DeleteBook deletes a book
-func (s *BookService) DeleteBook(id string) error {
+func (s *BookService) DeleteBook(id string, actor models.Actor) error {
+	// The book and its copies share a deletion time so that restoring the
+	// book brings back exactly the copies deleted with it
+	now := time.Now()
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
-		// Delete book associations first
-		if err := tx.Model(&models.Book{ID: uuid.MustParse(id)}).Association("Authors").Clear(); err != nil {
-			zap.L().Error("DeleteBook: Failed to clear authors association", zap.String("id", id), zap.Error(err))
+		var book models.Book
+		if err := tx.Preload("Authors").Preload("Categories").Preload("Tags").First(&book, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("DeleteBook: Book not found for deletion", zap.String("id", id))
+				return errors.New("book not found")
+			}
 			return err
 		}
-		if err := tx.Model(&models.Book{ID: uuid.MustParse(id)}).Association("Categories").Clear(); err != nil {
-			zap.L().Error("DeleteBook: Failed to clear categories association", zap.String("id", id), zap.Error(err))
+		if err := tx.Model(&models.Book{}).Where("id = ?", id).UpdateColumn("deleted_at", now).Error; err != nil {
+			zap.L().Error("DeleteBook: Failed to delete book", zap.String("id", id), zap.Error(err))
 			return err
 		}
-		if err := tx.Model(&models.Book{ID: uuid.MustParse(id)}).Association("Tags").Clear(); err != nil {
-			zap.L().Error("DeleteBook: Failed to clear tags association", zap.String("id", id), zap.Error(err))
+
+		var copies []models.BookCopy
+		if err := tx.Where("book_id = ?", id).Find(&copies).Error; err != nil {
 			return err
 		}
 
-		// Delete the book
-		result := tx.Unscoped().Delete(&models.Book{}, "id = ?", id)
-		if result.Error != nil {
-			zap.L().Error("DeleteBook: Failed to delete book", zap.String("id", id), zap.Error(result.Error))
-			return result.Error
+		// Copies of a trashed book can no longer be borrowed
+		if err := tx.Unscoped().Where("book_copy_id IN (?)", tx.Model(&models.BookCopy{}).Select("id").Where("book_id = ?", id)).
+			Delete(&models.CartItem{}).Error; err != nil {
+			zap.L().Error("DeleteBook: Failed to remove copies from carts", zap.String("id", id), zap.Error(err))
+			return err
 		}
-		if result.RowsAffected == 0 {
-			zap.L().Warn("DeleteBook: Book not found for deletion", zap.String("id", id))
-			return errors.New("book not found")
+		if err := tx.Model(&models.BookCopy{}).Where("book_id = ?", id).UpdateColumn("deleted_at", now).Error; err != nil {
+			zap.L().Error("DeleteBook: Failed to delete copies", zap.String("id", id), zap.Error(err))
+			return err
 		}
-		return nil
+		for _, copy := range copies {
+			if err := recordAudit(tx, actor, models.AuditActionDelete, models.AuditEntityBookCopies, copy.ID, &copy, nil); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, actor, models.AuditActionDelete, models.AuditEntityBooks, book.ID, &book, nil)
 	}); err != nil {
 		zap.L().Error("DeleteBook: Transaction failed", zap.String("id", id), zap.Error(err))
 		return err
 	}
-	zap.L().Info("DeleteBook: Book deleted successfully", zap.String("id", id))
+	zap.L().Info("DeleteBook: Book moved to trash", zap.String("id", id))
 	return nil
 }
 
-// SearchBooks performs a full-text search on books
-func (s *BookService) SearchBooks(query string) ([]models.Book, error) {
-	var books []models.Book
-	query = "%" + query + "%"
+// SearchBooks performs a ranked full-text search on books and returns the best
+// matches together with highlighted snippets
+func (s *BookService) SearchBooks(query string, limit int) ([]models.BookSearchResult, error) {
+	tsQuery := buildSearchQuery(query)
+	if tsQuery == "" {
+		return []models.BookSearchResult{}, nil
+	}
 
-	if err := s.db.Preload("Authors").Preload("Categories").Where(
-		"unaccent(LOWER(title)) LIKE unaccent(?) OR unaccent(LOWER(subtitle)) LIKE unaccent(?)",
-		query, query,
-	).Find(&books).Error; err != nil {
+	var rows []struct {
+		ID           uuid.UUID
+		Rank         float64
+		TitleSnippet string
+		Snippet      string
+	}
+	if err := s.db.Raw(`
+		SELECT b.id,
+		       ts_rank_cd(b.search_vector, q.query) AS rank,
+		       ts_headline('simple_unaccent', b.title, q.query, 'HighlightAll=true') AS title_snippet,
+		       ts_headline('simple_unaccent', coalesce(b.description, ''), q.query,
+		                   'MaxFragments=2, MinWords=5, MaxWords=20, FragmentDelimiter=" … "') AS snippet
+		FROM books b, to_tsquery('simple_unaccent', ?) AS q(query)
+		WHERE b.deleted_at IS NULL AND b.search_vector @@ q.query
+		ORDER BY rank DESC, b.created_at DESC
+		LIMIT ?
+	`, tsQuery, limit).Scan(&rows).Error; err != nil {
 		zap.L().Error("SearchBooks: Failed to search books", zap.String("query", query), zap.Error(err))
 		return nil, err
 	}
-	zap.L().Info("SearchBooks: Successfully searched books", zap.String("query", query), zap.Int("count", len(books)))
-	return books, nil
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	var books []models.Book
+	if len(ids) > 0 {
+		if err := s.db.Preload("Authors").Preload("Categories").Preload("Tags").
+			Where("id IN ?", ids).Find(&books).Error; err != nil {
+			zap.L().Error("SearchBooks: Failed to load matched books", zap.String("query", query), zap.Error(err))
+			return nil, err
+		}
+	}
+	booksByID := make(map[uuid.UUID]models.Book, len(books))
+	for _, book := range books {
+		booksByID[book.ID] = book
+	}
+
+	results := make([]models.BookSearchResult, 0, len(rows))
+	for _, row := range rows {
+		book, ok := booksByID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.BookSearchResult{
+			Book:         book,
+			Rank:         row.Rank,
+			TitleSnippet: row.TitleSnippet,
+			Snippet:      row.Snippet,
+		})
+	}
+	zap.L().Info("SearchBooks: Successfully searched books", zap.String("query", query), zap.Int("count", len(results)))
+	return results, nil
+}
+
+// buildSearchQuery turns free user input into a to_tsquery expression where
+// every word is prefix-matched and all words are required, e.g.
+// "harry pott" -> "harry:* & pott:*". Returns "" if there is nothing to search.
+func buildSearchQuery(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = strings.ToLower(word) + ":*"
+	}
+	return strings.Join(words, " & ")
 }
 
 // GetBooksByCategory retrieves books by category
@@ -278,23 +529,42 @@ func (s *BookService) GetBooksByAuthor(author string) ([]models.Book, error) {
 	return books, nil
 }
 
-func (s *BookService) BulkCreateBooks(books []models.Book) error {
+// BulkCreateBooks creates the books in a single transaction, so that either
+// all of them are saved or none is. The error names the book that failed.
+func (s *BookService) BulkCreateBooks(books []models.Book, actor models.Actor) error {
+	for i := range books {
+		if err := normalizeBookISBNs(&books[i]); err != nil {
+			zap.L().Warn("BulkCreateBooks: Invalid ISBN", zap.String("title", books[i].Title), zap.Error(err))
+			return bulkBookError(i, &books[i], err)
+		}
+	}
+	failed := -1
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
-		for _, book := range books {
-			if err := s.CreateBook(&book); err != nil {
-				zap.L().Error("BulkCreateBooks: Failed to create book in transaction", zap.String("title", book.Title), zap.Error(err))
+		for i := range books {
+			if err := createBook(tx, &books[i], actor); err != nil {
+				failed = i
 				return err
 			}
 		}
 		return nil
 	}); err != nil {
-		zap.L().Error("BulkCreateBooks: Transaction failed", zap.Error(err))
-		return err
+		if failed < 0 {
+			zap.L().Error("BulkCreateBooks: Failed to create books", zap.Error(err))
+			return err
+		}
+		book := &books[failed]
+		zap.L().Error("BulkCreateBooks: Failed to create book", zap.Int("index", failed), zap.String("title", book.Title), zap.Error(err))
+		return bulkBookError(failed, book, explainISBNConflict(s.db, book.ID, book, err))
 	}
 	zap.L().Info("BulkCreateBooks: Successfully bulk created books", zap.Int("count", len(books)))
 	return nil
 }
 
+// bulkBookError names the book of a bulk create that err is about, counting from 1
+func bulkBookError(index int, book *models.Book, err error) error {
+	return fmt.Errorf("book %d (%q): %w", index+1, book.Title, err)
+}
+
 // GetAuthor retrieves an author by ID
 func (s *BookService) GetAuthor(id string) (*models.Author, error) {
 	var author models.Author
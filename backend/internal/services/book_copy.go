@@ -2,14 +2,20 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/hungcq/pscit/backend/internal/config"
 	"github.com/hungcq/pscit/backend/internal/models"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrInvalidCopyStatusChange = errors.New("invalid book copy status change")
+
 type BookCopyService struct {
 	db *gorm.DB
 }
@@ -44,9 +50,43 @@ func (s *BookCopyService) GetBookCopy(id string) (*models.BookCopy, error) {
 	return &copy, nil
 }
 
-// CreateBookCopy creates a new book copy
-func (s *BookCopyService) CreateBookCopy(copy *models.BookCopy) error {
-	if err := s.db.Create(copy).Error; err != nil {
+// GetBookCopyByAccessionNumber retrieves the book copy with the given
+// accession number, as scanned from its label
+func (s *BookCopyService) GetBookCopyByAccessionNumber(number string) (*models.BookCopy, error) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	var copy models.BookCopy
+	if err := s.db.Preload("Book").First(&copy, "accession_number = ?", number).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			zap.L().Warn("GetBookCopyByAccessionNumber: Book copy not found", zap.String("accessionNumber", number))
+			return nil, errors.New("book copy not found")
+		}
+		zap.L().Error("GetBookCopyByAccessionNumber: Failed to retrieve book copy", zap.String("accessionNumber", number), zap.Error(err))
+		return nil, err
+	}
+	return &copy, nil
+}
+
+// CreateBookCopy creates a new book copy. New copies are available unless
+// they are added straight out of circulation, e.g. as damaged.
+func (s *BookCopyService) CreateBookCopy(copy *models.BookCopy, actor models.Actor) error {
+	if copy.Status == "" {
+		copy.Status = models.BookCopyStatusAvailable
+	}
+	if !copy.Status.Valid() || copy.Status == models.BookCopyStatusReserved || copy.Status == models.BookCopyStatusBorrowed {
+		return FieldErrors{"status": fmt.Sprintf("a new copy cannot be %s", copy.Status)}
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		number, err := nextAccessionNumber(tx)
+		if err != nil {
+			return err
+		}
+		copy.AccessionNumber = number
+		if err := tx.Create(copy).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityBookCopies, copy.ID, nil, copy)
+	}); err != nil {
 		zap.L().Error("CreateBookCopy: Failed to create book copy", zap.String("bookID", copy.BookID.String()), zap.Error(err))
 		return err
 	}
@@ -55,37 +95,70 @@ func (s *BookCopyService) CreateBookCopy(copy *models.BookCopy) error {
 }
 
 // UpdateBookCopy updates an existing book copy
-func (s *BookCopyService) UpdateBookCopy(id string, copy *models.BookCopy) error {
-	result := s.db.Model(&models.BookCopy{}).Where("id = ?", id).Updates(copy)
-	if result.Error != nil {
-		zap.L().Error("UpdateBookCopy: Failed to update book copy", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("UpdateBookCopy: Book copy not found for update", zap.String("id", id))
-		return errors.New("book copy not found")
+func (s *BookCopyService) UpdateBookCopy(id string, copy *models.BookCopy, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing, updated models.BookCopy
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("UpdateBookCopy: Book copy not found for update", zap.String("id", id))
+				return errors.New("book copy not found")
+			}
+			return err
+		}
+		if err := tx.Model(&models.BookCopy{}).Where("id = ?", id).Omit("accession_number", "status").Updates(copy).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&updated, "id = ?", id).Error; err != nil {
+			return err
+		}
+		// Keep the condition timeline complete when staff correct the condition by hand
+		if updated.Condition != existing.Condition {
+			if err := tx.Create(&models.CopyInspection{
+				BookCopyID:        updated.ID,
+				PreviousCondition: existing.Condition,
+				Condition:         updated.Condition,
+				Notes:             "condition changed by editing the copy",
+				Photos:            []string{},
+				InspectorID:       actor.UserID,
+				InspectorEmail:    actor.Email,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBookCopies, updated.ID, &existing, &updated)
+	}); err != nil {
+		zap.L().Error("UpdateBookCopy: Failed to update book copy", zap.String("id", id), zap.Error(err))
+		return err
 	}
 	zap.L().Info("UpdateBookCopy: Book copy updated successfully", zap.String("id", id))
 	return nil
 }
 
 // DeleteBookCopy deletes a book copy
-func (s *BookCopyService) DeleteBookCopy(id string) error {
-	result := s.db.Delete(&models.BookCopy{}, "id = ?", id)
-	if result.Error != nil {
-		zap.L().Error("DeleteBookCopy: Failed to delete book copy", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("DeleteBookCopy: Book copy not found for deletion", zap.String("id", id))
-		return errors.New("book copy not found")
+func (s *BookCopyService) DeleteBookCopy(id string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.BookCopy
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("DeleteBookCopy: Book copy not found for deletion", zap.String("id", id))
+				return errors.New("book copy not found")
+			}
+			return err
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionDelete, models.AuditEntityBookCopies, existing.ID, &existing, nil)
+	}); err != nil {
+		zap.L().Error("DeleteBookCopy: Failed to delete book copy", zap.String("id", id), zap.Error(err))
+		return err
 	}
 	zap.L().Info("DeleteBookCopy: Book copy deleted successfully", zap.String("id", id))
 	return nil
 }
 
 // BulkCreateBookCopies creates multiple copies of a book
-func (s *BookCopyService) BulkCreateBookCopies(bookID string, count int, condition models.BookCondition) error {
+func (s *BookCopyService) BulkCreateBookCopies(bookID string, count int, condition models.BookCondition, actor models.Actor) error {
 	bookUUID, err := uuid.Parse(bookID)
 	if err != nil {
 		zap.L().Error("BulkCreateBookCopies: Invalid book ID", zap.String("bookID", bookID), zap.Error(err))
@@ -94,15 +167,23 @@ func (s *BookCopyService) BulkCreateBookCopies(bookID string, count int, conditi
 
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		for i := 0; i < count; i++ {
+			number, err := nextAccessionNumber(tx)
+			if err != nil {
+				return err
+			}
 			copy := &models.BookCopy{
-				BookID:    bookUUID,
-				Condition: condition,
-				Status:    models.BookCopyStatusAvailable,
+				BookID:          bookUUID,
+				AccessionNumber: number,
+				Condition:       condition,
+				Status:          models.BookCopyStatusAvailable,
 			}
 			if err := tx.Create(copy).Error; err != nil {
 				zap.L().Error("BulkCreateBookCopies: Failed to create book copy in transaction", zap.String("bookID", bookID), zap.Int("index", i), zap.Error(err))
 				return err
 			}
+			if err := recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityBookCopies, copy.ID, nil, copy); err != nil {
+				return err
+			}
 		}
 		return nil
 	}); err != nil {
@@ -113,17 +194,86 @@ func (s *BookCopyService) BulkCreateBookCopies(bookID string, count int, conditi
 	return nil
 }
 
-// UpdateBookCopyAvailability updates a book copy's availability status
-func (s *BookCopyService) UpdateBookCopyAvailability(id string, available bool) error {
-	result := s.db.Model(&models.BookCopy{}).Where("id = ?", id).Update("available", available)
-	if result.Error != nil {
-		zap.L().Error("UpdateBookCopyAvailability: Failed to update book copy availability", zap.String("id", id), zap.Bool("available", available), zap.Error(result.Error))
-		return result.Error
+// ChangeBookCopyStatus moves a copy in or out of circulation, e.g. when it
+// is lost, sent for repair or withdrawn, recording why in its status history.
+// Copies leaving circulation are taken out of patrons' carts.
+func (s *BookCopyService) ChangeBookCopyStatus(id string, status models.BookCopyStatus, reason string, actor models.Actor) (*models.BookCopy, error) {
+	reason = strings.TrimSpace(reason)
+	if !status.Valid() {
+		return nil, FieldErrors{"status": fmt.Sprintf("unknown status %q", status)}
 	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("UpdateBookCopyAvailability: Book copy not found for availability update", zap.String("id", id))
-		return errors.New("book copy not found")
+	if reason == "" {
+		return nil, FieldErrors{"reason": "a reason is required"}
 	}
-	zap.L().Info("UpdateBookCopyAvailability: Successfully updated book copy availability", zap.String("id", id), zap.Bool("available", available))
-	return nil
+
+	var updated models.BookCopy
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.BookCopy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("book copy not found")
+			}
+			return err
+		}
+		if !existing.Status.CanChangeTo(status) {
+			return fmt.Errorf("%w from %s to %s", ErrInvalidCopyStatusChange, existing.Status, status)
+		}
+
+		if err := tx.Model(&models.BookCopy{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&updated, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := recordCopyStatusChange(tx, updated.ID, existing.Status, status, reason, actor); err != nil {
+			return err
+		}
+		if !status.Lendable() {
+			if err := tx.Unscoped().Where("book_copy_id = ?", updated.ID).Delete(&models.CartItem{}).Error; err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBookCopies, updated.ID, &existing, &updated)
+	}); err != nil {
+		zap.L().Error("ChangeBookCopyStatus: Failed to change book copy status", zap.String("id", id), zap.String("status", string(status)), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("ChangeBookCopyStatus: Book copy status changed", zap.String("id", id), zap.String("status", string(status)))
+	return &updated, nil
+}
+
+// GetBookCopyStatusHistory retrieves the status changes of a copy, oldest first
+func (s *BookCopyService) GetBookCopyStatusHistory(id string) ([]models.BookCopyStatusChange, error) {
+	if _, err := s.GetBookCopy(id); err != nil {
+		return nil, err
+	}
+	changes := []models.BookCopyStatusChange{}
+	if err := s.db.Where("book_copy_id = ?", id).Order("created_at").Find(&changes).Error; err != nil {
+		zap.L().Error("GetBookCopyStatusHistory: Failed to get status history", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	return changes, nil
+}
+
+// recordCopyStatusChange appends an entry to a copy's status history
+func recordCopyStatusChange(tx *gorm.DB, copyID uuid.UUID, from, to models.BookCopyStatus, reason string, actor models.Actor) error {
+	return tx.Create(&models.BookCopyStatusChange{
+		BookCopyID: copyID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		ActorID:    actor.UserID,
+		ActorEmail: actor.Email,
+	}).Error
+}
+
+// nextAccessionNumber allocates the accession number of a new copy from the
+// book_copy_accession_seq sequence. Numbers of rolled back copies are skipped.
+func nextAccessionNumber(tx *gorm.DB) (string, error) {
+	var n int64
+	if err := tx.Raw("SELECT nextval('book_copy_accession_seq')").Scan(&n).Error; err != nil {
+		zap.L().Error("nextAccessionNumber: Failed to allocate accession number", zap.Error(err))
+		return "", err
+	}
+	return fmt.Sprintf("%s%0*d", strings.ToUpper(config.AppConfig.AccessionPrefix), config.AppConfig.AccessionDigits, n), nil
 }
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"go.uber.org/zap"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+	"gorm.io/gorm"
+)
+
+// Cover upload limits
+const (
+	MaxCoverUploadBytes = 10 << 20
+	minCoverDimension   = 100
+	// maxCoverPixels guards against decompression bombs
+	maxCoverPixels   = 40_000_000
+	coverJPEGQuality = 85
+)
+
+// coverSizes are the widths covers are resized to. Images narrower than a
+// size are kept at their own width rather than upscaled.
+var coverSizes = []struct {
+	Name  string
+	Width int
+}{
+	{"small", 160},
+	{"medium", 320},
+	{"large", 800},
+}
+
+// coverContentTypes are the accepted upload types, detected from the file content
+var coverContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ErrInvalidCover is returned for uploads that are not a usable cover image
+var ErrInvalidCover = errors.New("invalid cover image")
+
+type BookCoverService struct {
+	db    *gorm.DB
+	store BlobStore
+}
+
+func NewBookCoverService(db *gorm.DB, store BlobStore) *BookCoverService {
+	return &BookCoverService{
+		db:    db,
+		store: store,
+	}
+}
+
+// UploadCover validates an uploaded image, stores it resized to every cover
+// size and points the book at the new files. The previous cover files are
+// removed once the book is updated.
+func (s *BookCoverService) UploadCover(ctx context.Context, bookID string, r io.Reader, actor models.Actor) (*models.Book, error) {
+	var book models.Book
+	if err := s.db.First(&book, "id = ?", bookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book not found")
+		}
+		zap.L().Error("UploadCover: Failed to retrieve book", zap.String("id", bookID), zap.Error(err))
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxCoverUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	img, err := decodeCover(data)
+	if err != nil {
+		zap.L().Warn("UploadCover: Rejected cover", zap.String("id", bookID), zap.Error(err))
+		return nil, err
+	}
+
+	// A fresh prefix per upload keeps CDN caches from serving the old cover
+	prefix := fmt.Sprintf("book-covers/%s/%d", book.ID, time.Now().UnixNano())
+	images := models.CoverImages{}
+	var stored []string
+	for _, size := range coverSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resizeToWidth(img, size.Width), &jpeg.Options{Quality: coverJPEGQuality}); err != nil {
+			return nil, err
+		}
+		key := prefix + "/" + size.Name + ".jpg"
+		if err := s.store.Put(ctx, key, &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			zap.L().Error("UploadCover: Failed to store cover", zap.String("id", bookID), zap.String("key", key), zap.Error(err))
+			s.deleteBlobs(stored)
+			return nil, err
+		}
+		stored = append(stored, key)
+		images[size.Name] = s.store.URL(key)
+	}
+
+	before := book
+	book.CoverKey = prefix
+	book.CoverImages = images
+	book.MainImage = images["large"]
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&book).Updates(map[string]interface{}{
+			"cover_key":    prefix,
+			"cover_images": images,
+			"main_image":   images["large"],
+		}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, book.ID, &before, &book)
+	}); err != nil {
+		zap.L().Error("UploadCover: Failed to update book", zap.String("id", bookID), zap.Error(err))
+		s.deleteBlobs(stored)
+		return nil, err
+	}
+	if before.CoverKey != "" {
+		s.deleteBlobs(coverKeys(before.CoverKey))
+	}
+
+	zap.L().Info("UploadCover: Cover uploaded successfully", zap.String("id", bookID), zap.String("key", prefix))
+	return &book, nil
+}
+
+// DeleteCover removes the uploaded cover of a book
+func (s *BookCoverService) DeleteCover(bookID string, actor models.Actor) error {
+	var book models.Book
+	if err := s.db.First(&book, "id = ?", bookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("book not found")
+		}
+		zap.L().Error("DeleteCover: Failed to retrieve book", zap.String("id", bookID), zap.Error(err))
+		return err
+	}
+	if book.CoverKey == "" {
+		return errors.New("book has no uploaded cover")
+	}
+
+	before := book
+	book.CoverKey = ""
+	book.CoverImages = nil
+	book.MainImage = ""
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&book).Updates(map[string]interface{}{
+			"cover_key":    "",
+			"cover_images": nil,
+			"main_image":   "",
+		}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, book.ID, &before, &book)
+	}); err != nil {
+		zap.L().Error("DeleteCover: Failed to update book", zap.String("id", bookID), zap.Error(err))
+		return err
+	}
+	s.deleteBlobs(coverKeys(before.CoverKey))
+
+	zap.L().Info("DeleteCover: Cover deleted successfully", zap.String("id", bookID))
+	return nil
+}
+
+// deleteBlobs removes blobs on a best-effort basis; a leftover file only costs storage
+func (s *BookCoverService) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(context.Background(), key); err != nil {
+			zap.L().Warn("deleteBlobs: Failed to delete blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func coverKeys(prefix string) []string {
+	keys := make([]string, len(coverSizes))
+	for i, size := range coverSizes {
+		keys[i] = prefix + "/" + size.Name + ".jpg"
+	}
+	return keys
+}
+
+// decodeCover checks the type, size and dimensions of an upload before decoding it
+func decodeCover(data []byte) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidCover)
+	}
+	if len(data) > MaxCoverUploadBytes {
+		return nil, fmt.Errorf("%w: file is larger than %d MB", ErrInvalidCover, MaxCoverUploadBytes>>20)
+	}
+	if contentType := http.DetectContentType(data); !coverContentTypes[contentType] {
+		return nil, fmt.Errorf("%w: unsupported file type %s", ErrInvalidCover, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCover, err)
+	}
+	if cfg.Width < minCoverDimension || cfg.Height < minCoverDimension {
+		return nil, fmt.Errorf("%w: image must be at least %dx%d pixels", ErrInvalidCover, minCoverDimension, minCoverDimension)
+	}
+	if cfg.Width*cfg.Height > maxCoverPixels {
+		return nil, fmt.Errorf("%w: image is too large (%dx%d)", ErrInvalidCover, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCover, err)
+	}
+	return img, nil
+}
+
+// resizeToWidth scales img down to width, keeping its aspect ratio. The result
+// is always an opaque RGBA image so transparent covers get a white background.
+func resizeToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
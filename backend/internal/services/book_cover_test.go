@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// gifBomb is a tiny GIF whose header claims a huge logical screen
+func gifBomb(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 1, 1), []color.Color{color.White})
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode GIF: %v", err)
+	}
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint16(data[6:8], 65535)
+	binary.LittleEndian.PutUint16(data[8:10], 65535)
+	return data
+}
+
+func TestDecodeCover(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"valid PNG", encodePNG(t, image.NewRGBA(image.Rect(0, 0, 200, 300))), ""},
+		{"empty", nil, "file is empty"},
+		{"oversize", make([]byte, MaxCoverUploadBytes+1), "file is larger than 10 MB"},
+		{"wrong content type", []byte("%PDF-1.7\n..."), "unsupported file type application/pdf"},
+		{"HTML", []byte("<html><body>cover</body></html>"), "unsupported file type text/html"},
+		{"too small", encodePNG(t, image.NewRGBA(image.Rect(0, 0, 99, 300))), "image must be at least 100x100 pixels"},
+		{"decompression bomb", gifBomb(t), "image is too large (65535x65535)"},
+		{"truncated", encodePNG(t, image.NewRGBA(image.Rect(0, 0, 200, 300)))[:60], "png: invalid format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeCover(tt.data)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 300 {
+					t.Errorf("got bounds %v, want 200x300", img.Bounds())
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCover) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want an invalid cover error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResizeToWidth(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        image.Rectangle
+		width      int
+		wantWidth  int
+		wantHeight int
+	}{
+		{"scales down keeping the aspect ratio", image.Rect(0, 0, 1000, 1500), 320, 320, 480},
+		{"never upscales", image.Rect(0, 0, 120, 180), 320, 120, 180},
+		{"keeps at least one row", image.Rect(0, 0, 2000, 1), 160, 160, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resizeToWidth(image.NewRGBA(tt.src), tt.width)
+			if got.Bounds().Dx() != tt.wantWidth || got.Bounds().Dy() != tt.wantHeight {
+				t.Errorf("got %v, want %dx%d", got.Bounds(), tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestResizeToWidthFillsTransparencyWithWhite(t *testing.T) {
+	// A fully transparent source
+	got := resizeToWidth(image.NewNRGBA(image.Rect(0, 0, 200, 200)), 160)
+	if r, g, b, a := got.At(80, 80).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff || a != 0xffff {
+		t.Errorf("got color %v, want opaque white", got.At(80, 80))
+	}
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// Supported catalog export formats
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatJSONL  = "jsonl"
+	ExportFormatMARC   = "marc"
+	ExportFormatBibTeX = "bibtex"
+)
+
+// exportBatchSize is the number of books loaded per query while streaming an export
+const exportBatchSize = 200
+
+// CopyCounts summarises the physical copies of a book
+type CopyCounts struct {
+	Total     int64 `json:"total"`
+	Available int64 `json:"available"`
+}
+
+// ExportedBook is a book as written to a catalog export
+type ExportedBook struct {
+	models.Book
+	Copies CopyCounts `json:"copies"`
+}
+
+type bookExportWriter interface {
+	Write(book *ExportedBook) error
+	Flush() error
+}
+
+// ExportContentType returns the MIME type and file extension of an export format
+func ExportContentType(format string) (string, string, error) {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case ExportFormatJSONL:
+		return "application/x-ndjson", "jsonl", nil
+	case ExportFormatMARC:
+		return "application/marc", "mrc", nil
+	case ExportFormatBibTeX:
+		return "application/x-bibtex; charset=utf-8", "bib", nil
+	}
+	return "", "", fmt.Errorf("unsupported export format %q", format)
+}
+
+// ExportBooks streams every book matching the filters to w in the given
+// format. Books are loaded in batches so memory use does not grow with the
+// size of the catalog.
+func (s *BookService) ExportBooks(filters models.BookFilters, format string, w io.Writer) error {
+	var writer bookExportWriter
+	switch format {
+	case ExportFormatCSV:
+		writer = newCSVExportWriter(w)
+	case ExportFormatJSONL:
+		writer = &jsonlExportWriter{enc: json.NewEncoder(w)}
+	case ExportFormatMARC:
+		writer = &marcExportWriter{w: newMARCWriter(w)}
+	case ExportFormatBibTeX:
+		writer = &bibtexExportWriter{w: w, keys: make(map[string]int)}
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	var batch []models.Book
+	count := 0
+	result := s.db.Model(&models.Book{}).
+		Preload("Authors").
+		Preload("Categories").
+		Preload("Tags").
+		Where("id IN (?)", s.filteredBookIDs(filters, "")).
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			copies, err := countCopies(s.db, batch)
+			if err != nil {
+				return err
+			}
+			for i := range batch {
+				if err := writer.Write(&ExportedBook{Book: batch[i], Copies: copies[batch[i].ID]}); err != nil {
+					return err
+				}
+			}
+			count += len(batch)
+			return writer.Flush()
+		})
+	if result.Error != nil {
+		zap.L().Error("ExportBooks: Failed to export books", zap.String("format", format), zap.Int("exported", count), zap.Error(result.Error))
+		return result.Error
+	}
+	zap.L().Info("ExportBooks: Successfully exported books", zap.String("format", format), zap.Int("count", count), zap.Any("filters", filters))
+	return nil
+}
+
+// countCopies returns the total and available copy counts of each book
+func countCopies(db *gorm.DB, books []models.Book) (map[uuid.UUID]CopyCounts, error) {
+	ids := make([]uuid.UUID, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+
+	var rows []struct {
+		BookID    uuid.UUID
+		Total     int64
+		Available int64
+	}
+	if err := db.Model(&models.BookCopy{}).
+		Select("book_id, COUNT(*) FILTER (WHERE status IN ?) AS total, COUNT(*) FILTER (WHERE status = ?) AS available",
+			models.LendableCopyStatuses, models.BookCopyStatusAvailable).
+		Where("book_id IN ?", ids).
+		Group("book_id").
+		Scan(&rows).Error; err != nil {
+		zap.L().Error("countCopies: Failed to count book copies", zap.Error(err))
+		return nil, err
+	}
+
+	counts := make(map[uuid.UUID]CopyCounts, len(rows))
+	for _, row := range rows {
+		counts[row.BookID] = CopyCounts{Total: row.Total, Available: row.Available}
+	}
+	return counts, nil
+}
+
+// csvExportWriter writes the columns understood by the CSV import, plus the
+// book ID and copy counts
+type csvExportWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+var csvExportHeader = []string{
+	"id", "title", "subtitle", "description", "isbn10", "isbn13", "published_year", "page_count",
+	"publisher", "main_image", "language", "format", "authors", "categories", "tags",
+	"total_copies", "available_copies",
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{w: csv.NewWriter(w)}
+}
+
+func (e *csvExportWriter) Write(book *ExportedBook) error {
+	if !e.headerWritten {
+		if err := e.w.Write(csvExportHeader); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+	return e.w.Write([]string{
+		book.ID.String(),
+		book.Title,
+		book.Subtitle,
+		book.Description,
+		stringValue(book.ISBN10),
+		stringValue(book.ISBN13),
+		strconv.Itoa(book.PublishedYear),
+		strconv.Itoa(book.PageCount),
+		book.Publisher,
+		book.MainImage,
+		book.Language,
+		string(book.Format),
+		strings.Join(authorNames(book.Authors), ";"),
+		strings.Join(categoryNames(book.Categories), ";"),
+		strings.Join(tagNames(book.Tags), ";"),
+		strconv.FormatInt(book.Copies.Total, 10),
+		strconv.FormatInt(book.Copies.Available, 10),
+	})
+}
+
+func (e *csvExportWriter) Flush() error {
+	if !e.headerWritten {
+		// Keep the header even when nothing matched
+		if err := e.w.Write(csvExportHeader); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlExportWriter) Write(book *ExportedBook) error {
+	return e.enc.Encode(book)
+}
+
+func (e *jsonlExportWriter) Flush() error {
+	return nil
+}
+
+// marcExportWriter writes one MARC21 bibliographic record per book. Copy
+// counts go into the local 999 field ($a total, $b available).
+type marcExportWriter struct {
+	w *marcWriter
+}
+
+func (e *marcExportWriter) Write(book *ExportedBook) error {
+	record := &marcRecord{}
+	add := func(tag, indicators string, subfields ...marcSubfield) {
+		var kept []marcSubfield
+		for _, sf := range subfields {
+			if sf.Value != "" {
+				kept = append(kept, sf)
+			}
+		}
+		if len(kept) > 0 {
+			record.Fields = append(record.Fields, marcField{Tag: tag, Indicators: indicators, Subfields: kept})
+		}
+	}
+
+	record.Fields = append(record.Fields,
+		marcField{Tag: "001", Value: book.ID.String()},
+		marcField{Tag: "008", Value: marc008(book)},
+	)
+	for _, isbn := range []*string{book.ISBN13, book.ISBN10} {
+		add("020", "  ", marcSubfield{Code: 'a', Value: stringValue(isbn)})
+	}
+	for i, author := range book.Authors {
+		// First indicator 0: names are stored in direct order
+		tag := "700"
+		if i == 0 {
+			tag = "100"
+		}
+		add(tag, "0 ", marcSubfield{Code: 'a', Value: author.Name})
+	}
+	add("245", "00",
+		marcSubfield{Code: 'a', Value: book.Title},
+		marcSubfield{Code: 'b', Value: book.Subtitle},
+	)
+	add("264", " 1",
+		marcSubfield{Code: 'b', Value: book.Publisher},
+		marcSubfield{Code: 'c', Value: positiveInt(book.PublishedYear)},
+	)
+	if book.PageCount > 0 {
+		add("300", "  ", marcSubfield{Code: 'a', Value: fmt.Sprintf("%d pages", book.PageCount)})
+	}
+	add("520", "  ", marcSubfield{Code: 'a', Value: book.Description})
+	for _, category := range book.Categories {
+		add("650", " 4", marcSubfield{Code: 'a', Value: category.Name})
+	}
+	for _, tag := range book.Tags {
+		add("653", "  ", marcSubfield{Code: 'a', Value: tag.Name})
+	}
+	add("856", "42", marcSubfield{Code: 'u', Value: book.MainImage}, marcSubfield{Code: '3', Value: "Cover image"})
+	add("999", "  ",
+		marcSubfield{Code: 'a', Value: strconv.FormatInt(book.Copies.Total, 10)},
+		marcSubfield{Code: 'b', Value: strconv.FormatInt(book.Copies.Available, 10)},
+	)
+	return e.w.Write(record)
+}
+
+func (e *marcExportWriter) Flush() error {
+	return nil
+}
+
+// marc008 builds the fixed-length data elements: date entered, publication
+// year and language
+func marc008(book *ExportedBook) string {
+	year := "    "
+	if book.PublishedYear > 0 {
+		year = fmt.Sprintf("%04d", book.PublishedYear)
+	}
+	language := marcLanguageCode(book.Language)
+	field := book.CreatedAt.Format("060102") + "s" + year + "    " + "xx "
+	return field + strings.Repeat(" ", 35-len(field)) + language + " d"
+}
+
+// marcLanguageCode maps the two-letter codes stored on books to MARC language codes
+func marcLanguageCode(language string) string {
+	switch language {
+	case "en":
+		return "eng"
+	case "vi":
+		return "vie"
+	}
+	return "und"
+}
+
+// bibtexExportWriter writes one @book entry per book
+type bibtexExportWriter struct {
+	w io.Writer
+	// keys counts the uses of each key, base keys and suffixed ones alike
+	keys map[string]int
+}
+
+func (e *bibtexExportWriter) Write(book *ExportedBook) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "@book{%s,\n", e.citationKey(book))
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "  %s = {%s},\n", name, escapeBibTeX(value))
+		}
+	}
+
+	title := book.Title
+	if book.Subtitle != "" {
+		title += ": " + book.Subtitle
+	}
+	field("title", title)
+	field("author", strings.Join(authorNames(book.Authors), " and "))
+	field("publisher", book.Publisher)
+	field("year", positiveInt(book.PublishedYear))
+	field("isbn", firstNonEmpty(stringValue(book.ISBN13), stringValue(book.ISBN10)))
+	field("pages", positiveInt(book.PageCount))
+	field("language", book.Language)
+	field("keywords", strings.Join(append(categoryNames(book.Categories), tagNames(book.Tags)...), ", "))
+	field("abstract", book.Description)
+	b.WriteString("}\n\n")
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *bibtexExportWriter) Flush() error {
+	return nil
+}
+
+// citationKey builds keys such as "martin2008clean", adding a, b, ..., z, aa,
+// ab, ... when the same key comes up again
+func (e *bibtexExportWriter) citationKey(book *ExportedBook) string {
+	var surname string
+	if len(book.Authors) > 0 {
+		if names := strings.Fields(book.Authors[0].Name); len(names) > 0 {
+			surname = names[len(names)-1]
+		}
+	}
+	var firstWord string
+	if words := strings.Fields(book.Title); len(words) > 0 {
+		firstWord = words[0]
+	}
+
+	key := asciiKey(surname) + positiveInt(book.PublishedYear) + asciiKey(firstWord)
+	if key == "" {
+		key = "book" + strings.ReplaceAll(book.ID.String()[:8], "-", "")
+	}
+	// A suffixed key may already be the key of another book, e.g. for a
+	// title starting with "Cleana", so skip the suffixes in use
+	n := e.keys[key]
+	candidate := key
+	for n > 0 {
+		candidate = key + citationKeySuffix(n)
+		if e.keys[candidate] == 0 {
+			break
+		}
+		n++
+	}
+	e.keys[key] = n + 1
+	if candidate != key {
+		e.keys[candidate] = 1
+	}
+	return candidate
+}
+
+// citationKeySuffix numbers keys a to z, then aa to zz and so on
+func citationKeySuffix(n int) string {
+	var suffix []byte
+	for ; n > 0; n = (n - 1) / 26 {
+		suffix = append([]byte{byte('a' + (n-1)%26)}, suffix...)
+	}
+	return string(suffix)
+}
+
+// asciiKey strips diacritics and keeps only lowercase ASCII letters and digits
+func asciiKey(value string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(value)) {
+		if r == 'đ' {
+			r = 'd'
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+)
+
+func escapeBibTeX(value string) string {
+	return bibtexEscaper.Replace(strings.ReplaceAll(value, "\n", " "))
+}
+
+func authorNames(authors []models.Author) []string {
+	names := make([]string, len(authors))
+	for i, author := range authors {
+		names[i] = author.Name
+	}
+	return names
+}
+
+func categoryNames(categories []models.Category) []string {
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = category.Name
+	}
+	return names
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func positiveInt(value int) string {
+	if value <= 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// ExportFileName names an export download, e.g. "pscit-catalog-20250101.csv"
+func ExportFileName(extension string) string {
+	return fmt.Sprintf("pscit-catalog-%s.%s", time.Now().Format("20060102"), extension)
+}
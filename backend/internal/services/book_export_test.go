@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+)
+
+func TestCitationKeySuffix(t *testing.T) {
+	for n, want := range map[int]string{1: "a", 2: "b", 26: "z", 27: "aa", 28: "ab", 52: "az", 53: "ba", 702: "zz", 703: "aaa"} {
+		if got := citationKeySuffix(n); got != want {
+			t.Errorf("citationKeySuffix(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCitationKeysAreUnique(t *testing.T) {
+	writer := &bibtexExportWriter{keys: make(map[string]int)}
+	book := func(title string) *ExportedBook {
+		return &ExportedBook{Book: models.Book{
+			Title:         title,
+			PublishedYear: 2008,
+			Authors:       []models.Author{{Name: "Robert C. Martin"}},
+		}}
+	}
+
+	seen := map[string]bool{}
+	check := func(key string) {
+		t.Helper()
+		if seen[key] {
+			t.Fatalf("duplicate citation key %q", key)
+		}
+		seen[key] = true
+	}
+	// Takes the key the first suffixed "Clean Code" would get
+	check(writer.citationKey(book("Cleana")))
+	for i := 0; i < 60; i++ {
+		check(writer.citationKey(book("Clean Code")))
+	}
+	if !seen["martin2008cleanaa"] || !seen["martin2008cleanbh"] {
+		t.Errorf("got keys %v, want suffixes past z", seen)
+	}
+}
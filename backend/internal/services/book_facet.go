@@ -0,0 +1,102 @@
+package services
+
+import (
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// facetBucketLimit caps the number of buckets returned per facet
+const facetBucketLimit = 50
+
+// GetBookFacets counts the books matching the filters for every value of each
+// facet. Each facet is counted with its own filter left out, so the counts show
+// how many results selecting a different value would give.
+func (s *BookService) GetBookFacets(filters models.BookFilters) (*models.BookFacets, error) {
+	facets := &models.BookFacets{
+		Categories: []models.FacetBucket{},
+		Authors:    []models.FacetBucket{},
+		Languages:  []models.FacetBucket{},
+		Tags:       []models.FacetBucket{},
+		Formats:    []models.FacetBucket{},
+	}
+
+	if err := s.facetQuery(filters, "category").
+		Select("categories.name AS value, categories.name AS label, COUNT(DISTINCT books.id) AS count").
+		Joins("JOIN book_categories fbc ON fbc.book_id = books.id").
+		Joins("JOIN categories ON categories.id = fbc.category_id AND categories.deleted_at IS NULL").
+		Group("categories.name").
+		Order("count DESC, categories.name ASC").
+		Limit(facetBucketLimit).
+		Scan(&facets.Categories).Error; err != nil {
+		zap.L().Error("GetBookFacets: Failed to count categories", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.facetQuery(filters, "author").
+		Select("authors.name AS value, authors.name AS label, COUNT(DISTINCT books.id) AS count").
+		Joins("JOIN book_authors fba ON fba.book_id = books.id").
+		Joins("JOIN authors ON authors.id = fba.author_id AND authors.deleted_at IS NULL").
+		Group("authors.name").
+		Order("count DESC, authors.name ASC").
+		Limit(facetBucketLimit).
+		Scan(&facets.Authors).Error; err != nil {
+		zap.L().Error("GetBookFacets: Failed to count authors", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.facetQuery(filters, "language").
+		Select("books.language AS value, books.language AS label, COUNT(*) AS count").
+		Group("books.language").
+		Order("count DESC, books.language ASC").
+		Scan(&facets.Languages).Error; err != nil {
+		zap.L().Error("GetBookFacets: Failed to count languages", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.facetQuery(filters, "tag").
+		Select("tags.key AS value, tags.name AS label, COUNT(DISTINCT books.id) AS count").
+		Joins("JOIN book_tags fbt ON fbt.book_id = books.id").
+		Joins("JOIN tags ON tags.id = fbt.tag_id AND tags.deleted_at IS NULL").
+		Group("tags.key, tags.name").
+		Order("count DESC, tags.name ASC").
+		Limit(facetBucketLimit).
+		Scan(&facets.Tags).Error; err != nil {
+		zap.L().Error("GetBookFacets: Failed to count tags", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.facetQuery(filters, "format").
+		Select("books.format AS value, books.format AS label, COUNT(*) AS count").
+		Group("books.format").
+		Order("count DESC, books.format ASC").
+		Scan(&facets.Formats).Error; err != nil {
+		zap.L().Error("GetBookFacets: Failed to count formats", zap.Error(err))
+		return nil, err
+	}
+
+	var available int64
+	if err := s.facetQuery(filters, "available").
+		Where("EXISTS (SELECT 1 FROM book_copies bc WHERE bc.book_id = books.id AND bc.status = ? AND bc.deleted_at IS NULL)",
+			models.BookCopyStatusAvailable).
+		Count(&available).Error; err != nil {
+		zap.L().Error("GetBookFacets: Failed to count available books", zap.Error(err))
+		return nil, err
+	}
+	facets.Availability = []models.FacetBucket{{Value: "true", Label: "Available", Count: available}}
+
+	return facets, nil
+}
+
+// facetQuery starts a query over the books matching every filter except skip,
+// keeping only the edition listed for each work when the listing is collapsed
+func (s *BookService) facetQuery(filters models.BookFilters, skip string) *gorm.DB {
+	ids := s.filteredBookIDs(filters, skip)
+	if filters.CollapseWorks {
+		ids = s.collapseByWork(ids)
+	}
+	return s.db.Table("books").
+		Where("books.deleted_at IS NULL").
+		Where("books.id IN (?)", ids)
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+)
+
+func TestGetBookFacetsCountsCollapsedWorks(t *testing.T) {
+	for _, collapse := range []bool{false, true} {
+		db, stub := newStubDB(t)
+		if _, err := NewBookService(db).GetBookFacets(models.BookFilters{CollapseWorks: collapse}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		statements := stub.Statements()
+		if len(statements) != 6 {
+			t.Fatalf("got %d statements, want one per facet", len(statements))
+		}
+		for _, statement := range statements {
+			if got := strings.Contains(statement, "DISTINCT ON (COALESCE(books.work_id, books.id))"); got != collapse {
+				t.Errorf("collapse=%t: got statement %q", collapse, statement)
+			}
+		}
+	}
+}
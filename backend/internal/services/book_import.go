@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BookImportService struct {
+	db        *gorm.DB
+	providers []MetadataProvider
+}
+
+func NewBookImportService(db *gorm.DB, providers []MetadataProvider) *BookImportService {
+	return &BookImportService{
+		db:        db,
+		providers: providers,
+	}
+}
+
+// ImportStatus is the outcome of importing a single record
+type ImportStatus string
+
+const (
+	ImportStatusCreated  ImportStatus = "created"
+	ImportStatusExists   ImportStatus = "exists"
+	ImportStatusNotFound ImportStatus = "not_found"
+	ImportStatusError    ImportStatus = "error"
+)
+
+// ISBNImportResult reports what happened to one ISBN of an import request
+type ISBNImportResult struct {
+	ISBN   string       `json:"isbn"`
+	Status ImportStatus `json:"status"`
+	Source string       `json:"source,omitempty"`
+	Book   *models.Book `json:"book,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ImportISBNs looks up each ISBN with the metadata providers and creates the
+// book, linking existing authors and categories by name and creating missing
+// ones. Books already in the catalog are left untouched. When provider is
+// set, only the provider with that name is queried.
+func (s *BookImportService) ImportISBNs(ctx context.Context, isbns []string, provider string, actor models.Actor) ([]ISBNImportResult, error) {
+	providers := s.providers
+	if provider != "" {
+		providers = nil
+		for _, p := range s.providers {
+			if p.Name() == provider {
+				providers = append(providers, p)
+			}
+		}
+		if len(providers) == 0 {
+			return nil, errors.New("unknown metadata provider: " + provider)
+		}
+	}
+
+	results := make([]ISBNImportResult, 0, len(isbns))
+	for _, raw := range isbns {
+		results = append(results, s.importISBN(ctx, cleanISBN(raw), providers, actor))
+	}
+	zap.L().Info("ImportISBNs: Finished importing ISBNs", zap.Int("count", len(isbns)))
+	return results, nil
+}
+
+func (s *BookImportService) importISBN(ctx context.Context, isbn string, providers []MetadataProvider, actor models.Actor) ISBNImportResult {
+	result := ISBNImportResult{ISBN: isbn}
+
+	isbn10, isbn13, err := normalizeSingleISBN(isbn)
+	if err != nil {
+		zap.L().Warn("ImportISBNs: Invalid ISBN", zap.String("isbn", isbn), zap.Error(err))
+		result.Status = ImportStatusError
+		result.Error = "invalid ISBN: " + err.Error()
+		return result
+	}
+
+	existing, err := findExistingBook(s.db, isbn10, isbn13)
+	if err != nil {
+		zap.L().Error("ImportISBNs: Failed to check existing book", zap.String("isbn", isbn), zap.Error(err))
+		result.Status = ImportStatusError
+		result.Error = err.Error()
+		return result
+	}
+	if existing != nil && existing.DeletedAt.Valid {
+		result.Status = ImportStatusError
+		result.Error = trashedBookMessage
+		return result
+	}
+	if existing != nil {
+		result.Status = ImportStatusExists
+		result.Book = existing
+		return result
+	}
+
+	metadata, err := lookupMetadata(ctx, providers, isbn)
+	if err != nil {
+		if errors.Is(err, ErrMetadataNotFound) {
+			zap.L().Warn("ImportISBNs: No metadata found", zap.String("isbn", isbn))
+			result.Status = ImportStatusNotFound
+			return result
+		}
+		result.Status = ImportStatusError
+		result.Error = err.Error()
+		return result
+	}
+	result.Source = metadata.Source
+
+	book := metadata.toBook()
+	// Store the requested ISBN pair rather than whatever the provider returned
+	book.ISBN10, book.ISBN13 = optionalString(isbn10), optionalString(isbn13)
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if book.Authors, err = findOrCreateAuthors(tx, metadata.Authors, actor); err != nil {
+			return err
+		}
+		if book.Categories, err = findOrCreateCategories(tx, metadata.Categories, actor); err != nil {
+			return err
+		}
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityBooks, book.ID, nil, book)
+	}); err != nil {
+		zap.L().Error("ImportISBNs: Failed to create book", zap.String("isbn", isbn), zap.Error(err))
+		result.Status = ImportStatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	zap.L().Info("ImportISBNs: Book imported", zap.String("isbn", isbn), zap.String("id", book.ID.String()), zap.String("source", metadata.Source))
+	result.Status = ImportStatusCreated
+	result.Book = book
+	return result
+}
+
+// lookupMetadata asks each provider in turn and returns the first match
+func lookupMetadata(ctx context.Context, providers []MetadataProvider, isbn string) (*BookMetadata, error) {
+	var lastErr error = ErrMetadataNotFound
+	for _, provider := range providers {
+		metadata, err := provider.LookupISBN(ctx, isbn)
+		if err == nil {
+			return metadata, nil
+		}
+		if !errors.Is(err, ErrMetadataNotFound) {
+			zap.L().Warn("lookupMetadata: Provider lookup failed", zap.String("provider", provider.Name()), zap.String("isbn", isbn), zap.Error(err))
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+func (m *BookMetadata) toBook() *models.Book {
+	book := &models.Book{
+		Title:          m.Title,
+		Subtitle:       m.Subtitle,
+		Description:    m.Description,
+		PublishedYear:  m.PublishedYear,
+		PageCount:      m.PageCount,
+		Publisher:      m.Publisher,
+		GoogleVolumeID: m.GoogleVolumeID,
+		MainImage:      m.MainImage,
+		Language:       m.Language,
+		Format:         m.Format,
+	}
+	if m.ISBN10 != "" {
+		isbn10 := m.ISBN10
+		book.ISBN10 = &isbn10
+	}
+	if m.ISBN13 != "" {
+		isbn13 := m.ISBN13
+		book.ISBN13 = &isbn13
+	}
+	if book.Format == "" {
+		book.Format = models.FormatPaperback
+	}
+	return book
+}
+
+// findBookByISBN returns the book with the given ISBN-10 or ISBN-13, or nil
+func findBookByISBN(db *gorm.DB, isbn string) (*models.Book, error) {
+	var book models.Book
+	// Trashed books still hold their ISBNs
+	err := db.Unscoped().Preload("Authors").Preload("Categories").Preload("Tags").
+		Where("isbn10 = ? OR isbn13 = ?", isbn, isbn).
+		First(&book).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// trashedBookMessage explains why an import skips a book that is in the trash
+const trashedBookMessage = "a book with this ISBN is in the trash; restore it instead"
+
+// findOrCreateAuthors resolves author names case-insensitively, creating the
+// missing ones. A trashed author with the name is restored. Both are
+// recorded in the audit log.
+func findOrCreateAuthors(tx *gorm.DB, names []string, actor models.Actor) ([]models.Author, error) {
+	authors := make([]models.Author, 0, len(names))
+	for _, name := range uniqueNames(names) {
+		var author models.Author
+		err := tx.Unscoped().Where("LOWER(name) = LOWER(?)", name).First(&author).Error
+		if err == nil && author.DeletedAt.Valid {
+			if err = restoreTrashed(tx, &author, &author.DeletedAt); err == nil {
+				err = recordAudit(tx, actor, models.AuditActionRestore, models.AuditEntityAuthors, author.ID, nil, nil)
+			}
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			author = models.Author{Name: name}
+			if err = tx.Create(&author).Error; err == nil {
+				err = recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityAuthors, author.ID, nil, &author)
+			}
+		}
+		if err != nil {
+			zap.L().Error("findOrCreateAuthors: Failed to resolve author", zap.String("name", name), zap.Error(err))
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, nil
+}
+
+// findOrCreateCategories resolves category names case-insensitively, creating
+// the missing ones. A trashed category with the name is restored. Both are
+// recorded in the audit log.
+func findOrCreateCategories(tx *gorm.DB, names []string, actor models.Actor) ([]models.Category, error) {
+	categories := make([]models.Category, 0, len(names))
+	for _, name := range uniqueNames(names) {
+		var category models.Category
+		err := tx.Unscoped().Where("LOWER(name) = LOWER(?)", name).First(&category).Error
+		if err == nil && category.DeletedAt.Valid {
+			if err = restoreTrashed(tx, &category, &category.DeletedAt); err == nil {
+				err = recordAudit(tx, actor, models.AuditActionRestore, models.AuditEntityCategories, category.ID, nil, nil)
+			}
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			category = models.Category{Name: name}
+			if err = tx.Create(&category).Error; err == nil {
+				err = recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityCategories, category.ID, nil, &category)
+			}
+		}
+		if err != nil {
+			zap.L().Error("findOrCreateCategories: Failed to resolve category", zap.String("name", name), zap.Error(err))
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// uniqueNames trims names and drops blanks and case-insensitive duplicates
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, name)
+	}
+	return unique
+}
+
+// ImportRecord is one book row of a bulk import, with authors, categories and
+// tags given by name
+type ImportRecord struct {
+	Row           int               `json:"row"`
+	Title         string            `json:"title"`
+	Subtitle      string            `json:"subtitle"`
+	Description   string            `json:"description"`
+	ISBN10        string            `json:"isbn10"`
+	ISBN13        string            `json:"isbn13"`
+	PublishedYear int               `json:"published_year"`
+	PageCount     int               `json:"page_count"`
+	Publisher     string            `json:"publisher"`
+	MainImage     string            `json:"main_image"`
+	Language      string            `json:"language"`
+	Format        models.BookFormat `json:"format"`
+	Authors       []string          `json:"authors"`
+	Categories    []string          `json:"categories"`
+	Tags          []string          `json:"tags"`
+	// ParseError is set when the source row could not be read
+	ParseError string `json:"-"`
+}
+
+// ImportOptions controls how a bulk import treats its rows
+type ImportOptions struct {
+	// DryRun validates and resolves every row, then rolls everything back
+	DryRun bool
+	// UpdateExisting overwrites books whose ISBN is already in the catalog
+	// instead of skipping them
+	UpdateExisting bool
+}
+
+const (
+	ImportStatusUpdated ImportStatus = "updated"
+	ImportStatusSkipped ImportStatus = "skipped"
+)
+
+// ImportRowResult reports what happened to one row of a bulk import
+type ImportRowResult struct {
+	Row     int          `json:"row"`
+	ISBN    string       `json:"isbn,omitempty"`
+	Title   string       `json:"title,omitempty"`
+	Status  ImportStatus `json:"status"`
+	BookID  *uuid.UUID   `json:"book_id,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
+
+// ImportReport summarises a bulk import
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Errors  int               `json:"errors"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// errDryRunRollback aborts a row transaction after a successful dry run
+var errDryRunRollback = errors.New("dry run rollback")
+
+// ImportRecords creates or updates one book per record. Each row runs in its
+// own transaction, so a bad row is reported without affecting the others.
+// Books are de-duplicated on ISBN, both against the catalog and within the batch.
+func (s *BookImportService) ImportRecords(records []ImportRecord, opts ImportOptions, actor models.Actor) *ImportReport {
+	report := &ImportReport{DryRun: opts.DryRun, Rows: make([]ImportRowResult, 0, len(records))}
+	seenISBNs := make(map[string]int)
+
+	for _, record := range records {
+		result := s.importRecord(record, opts, seenISBNs, actor)
+		switch result.Status {
+		case ImportStatusCreated:
+			report.Created++
+		case ImportStatusUpdated:
+			report.Updated++
+		case ImportStatusSkipped:
+			report.Skipped++
+		default:
+			report.Errors++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	zap.L().Info("ImportRecords: Finished bulk import",
+		zap.Bool("dryRun", opts.DryRun), zap.Int("rows", len(records)),
+		zap.Int("created", report.Created), zap.Int("updated", report.Updated),
+		zap.Int("skipped", report.Skipped), zap.Int("errors", report.Errors))
+	return report
+}
+
+func (s *BookImportService) importRecord(record ImportRecord, opts ImportOptions, seenISBNs map[string]int, actor models.Actor) ImportRowResult {
+	result := ImportRowResult{Row: record.Row, Title: record.Title, ISBN: firstNonEmpty(cleanISBN(record.ISBN13), cleanISBN(record.ISBN10))}
+
+	fail := func(message string) ImportRowResult {
+		result.Status = ImportStatusError
+		result.Message = message
+		return result
+	}
+
+	if record.ParseError != "" {
+		return fail(record.ParseError)
+	}
+	if err := record.validate(); err != nil {
+		return fail(err.Error())
+	}
+	// Report the normalized ISBN, preferring the ISBN-13
+	result.ISBN = firstNonEmpty(record.ISBN13, record.ISBN10)
+
+	for _, isbn := range []string{record.ISBN10, record.ISBN13} {
+		if isbn == "" {
+			continue
+		}
+		if row, ok := seenISBNs[isbn]; ok {
+			result.Status = ImportStatusSkipped
+			result.Message = fmt.Sprintf("duplicate of row %d", row)
+			return result
+		}
+	}
+	for _, isbn := range []string{record.ISBN10, record.ISBN13} {
+		if isbn != "" {
+			seenISBNs[isbn] = record.Row
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		existing, err := findExistingBook(tx, record.ISBN10, record.ISBN13)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.DeletedAt.Valid {
+			result.Status = ImportStatusSkipped
+			result.BookID = &existing.ID
+			result.Message = trashedBookMessage
+			return nil
+		}
+		if existing != nil && !opts.UpdateExisting {
+			result.Status = ImportStatusSkipped
+			result.BookID = &existing.ID
+			result.Message = "a book with this ISBN already exists"
+			return nil
+		}
+
+		authors, err := findOrCreateAuthors(tx, record.Authors, actor)
+		if err != nil {
+			return err
+		}
+		categories, err := findOrCreateCategories(tx, record.Categories, actor)
+		if err != nil {
+			return err
+		}
+		tags, err := findOrCreateTags(tx, record.Tags, actor)
+		if err != nil {
+			return err
+		}
+
+		if existing == nil {
+			book := record.toBook()
+			book.Authors = authors
+			book.Categories = categories
+			book.Tags = tags
+			if err := tx.Create(book).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityBooks, book.ID, nil, book); err != nil {
+				return err
+			}
+			result.Status = ImportStatusCreated
+			result.BookID = &book.ID
+		} else {
+			// The updates below modify existing, keep the old values for the audit log
+			before := *existing
+			before.Authors = append([]models.Author(nil), existing.Authors...)
+			before.Categories = append([]models.Category(nil), existing.Categories...)
+			before.Tags = append([]models.Tag(nil), existing.Tags...)
+
+			if err := tx.Model(existing).Updates(record.updates()).Error; err != nil {
+				return err
+			}
+			if len(authors) > 0 {
+				if err := tx.Model(existing).Association("Authors").Replace(authors); err != nil {
+					return err
+				}
+			}
+			if len(categories) > 0 {
+				if err := tx.Model(existing).Association("Categories").Replace(categories); err != nil {
+					return err
+				}
+			}
+			if len(tags) > 0 {
+				if err := tx.Model(existing).Association("Tags").Replace(tags); err != nil {
+					return err
+				}
+			}
+			var updated models.Book
+			if err := tx.Preload("Authors").Preload("Categories").Preload("Tags").First(&updated, "id = ?", existing.ID).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, existing.ID, &before, &updated); err != nil {
+				return err
+			}
+			result.Status = ImportStatusUpdated
+			result.BookID = &existing.ID
+		}
+
+		if opts.DryRun {
+			return errDryRunRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRunRollback) {
+		zap.L().Error("ImportRecords: Failed to import row", zap.Int("row", record.Row), zap.String("title", record.Title), zap.Error(err))
+		return fail(err.Error())
+	}
+	if opts.DryRun && result.Status == ImportStatusCreated {
+		// The ID only existed inside the rolled back transaction
+		result.BookID = nil
+	}
+	return result
+}
+
+// validate checks the record and normalizes its ISBNs in place
+func (r *ImportRecord) validate() error {
+	if strings.TrimSpace(r.Title) == "" {
+		return errors.New("title is required")
+	}
+	isbn10, isbn13, err := NormalizeISBNs(r.ISBN10, r.ISBN13)
+	if err != nil {
+		return err
+	}
+	r.ISBN10, r.ISBN13 = isbn10, isbn13
+	if r.Format == "" {
+		r.Format = models.FormatPaperback
+	}
+	if r.Format != models.FormatPaperback && r.Format != models.FormatHardcover {
+		return fmt.Errorf("unknown format %q", r.Format)
+	}
+	if r.Language != "" && len(r.Language) != 2 {
+		return fmt.Errorf("language must be a two-letter code, got %q", r.Language)
+	}
+	return nil
+}
+
+func (r *ImportRecord) toBook() *models.Book {
+	book := &models.Book{
+		Title:         strings.TrimSpace(r.Title),
+		Subtitle:      r.Subtitle,
+		Description:   strings.ReplaceAll(r.Description, "\r\n", "\n"),
+		PublishedYear: r.PublishedYear,
+		PageCount:     r.PageCount,
+		Publisher:     r.Publisher,
+		MainImage:     r.MainImage,
+		Language:      r.Language,
+		Format:        r.Format,
+	}
+	if r.ISBN10 != "" {
+		isbn10 := r.ISBN10
+		book.ISBN10 = &isbn10
+	}
+	if r.ISBN13 != "" {
+		isbn13 := r.ISBN13
+		book.ISBN13 = &isbn13
+	}
+	return book
+}
+
+// updates returns the non-empty fields of the record as column updates
+func (r *ImportRecord) updates() map[string]interface{} {
+	book := r.toBook()
+	updates := map[string]interface{}{
+		"title":  book.Title,
+		"format": book.Format,
+	}
+	for column, value := range map[string]string{
+		"subtitle":    book.Subtitle,
+		"description": book.Description,
+		"publisher":   book.Publisher,
+		"main_image":  book.MainImage,
+		"language":    book.Language,
+	} {
+		if value != "" {
+			updates[column] = value
+		}
+	}
+	if book.ISBN10 != nil {
+		updates["isbn10"] = book.ISBN10
+	}
+	if book.ISBN13 != nil {
+		updates["isbn13"] = book.ISBN13
+	}
+	if book.PublishedYear != 0 {
+		updates["published_year"] = book.PublishedYear
+	}
+	if book.PageCount != 0 {
+		updates["page_count"] = book.PageCount
+	}
+	return updates
+}
+
+// findExistingBook returns the book matching either ISBN, or nil
+func findExistingBook(tx *gorm.DB, isbn10, isbn13 string) (*models.Book, error) {
+	for _, isbn := range []string{isbn13, isbn10} {
+		if isbn == "" {
+			continue
+		}
+		book, err := findBookByISBN(tx, isbn)
+		if err != nil || book != nil {
+			return book, err
+		}
+	}
+	return nil, nil
+}
+
+// findOrCreateTags resolves tags by key or case-insensitive name, creating the
+// missing ones with a key derived from the name. A trashed tag is restored. Both are
+// recorded in the audit log.
+func findOrCreateTags(tx *gorm.DB, names []string, actor models.Actor) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range uniqueNames(names) {
+		key := tagKey(name)
+		var tag models.Tag
+		err := tx.Unscoped().Where("key = ? OR LOWER(name) = LOWER(?)", key, name).First(&tag).Error
+		if err == nil && tag.DeletedAt.Valid {
+			if err = restoreTrashed(tx, &tag, &tag.DeletedAt); err == nil {
+				err = recordAudit(tx, actor, models.AuditActionRestore, models.AuditEntityTags, tag.ID, nil, nil)
+			}
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag = models.Tag{Key: key, Name: name}
+			if err = tx.Create(&tag).Error; err == nil {
+				err = recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityTags, tag.ID, nil, &tag)
+			}
+		}
+		if err != nil {
+			zap.L().Error("findOrCreateTags: Failed to resolve tag", zap.String("name", name), zap.Error(err))
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagKey derives a tag key such as "must-read" from a display name
+func tagKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+)
+
+// Supported bulk import file formats
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatMARC = "marc"
+)
+
+// ParseImportFile reads the records of a CSV or binary MARC21 file
+func ParseImportFile(format string, r io.Reader) ([]ImportRecord, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseCSVRecords(r)
+	case ImportFormatMARC:
+		return parseMARCRecords(r)
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+// parseCSVRecords reads a CSV file with a header row. Recognised columns are
+// title, subtitle, description, isbn, isbn10, isbn13, published_year,
+// page_count, publisher, main_image, language, format, authors, categories
+// and tags; multiple authors, categories or tags are separated by ";".
+// Unknown columns are ignored.
+func parseCSVRecords(r io.Reader) ([]ImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("CSV file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[strings.ReplaceAll(name, " ", "_")] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("CSV header must contain a title column")
+	}
+
+	var records []ImportRecord
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		// Rows are numbered by their line in the file, which blank lines and
+		// quoted line breaks make differ from the record count
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, ImportRecord{Row: parseErr.StartLine, ParseError: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		row, _ := reader.FieldPos(0)
+		if isBlankRow(values) {
+			continue
+		}
+
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(values) {
+				return strings.TrimSpace(values[i])
+			}
+			return ""
+		}
+
+		record := ImportRecord{
+			Row:         row,
+			Title:       get("title"),
+			Subtitle:    get("subtitle"),
+			Description: get("description"),
+			ISBN10:      get("isbn10"),
+			ISBN13:      get("isbn13"),
+			Publisher:   get("publisher"),
+			MainImage:   get("main_image"),
+			Language:    strings.ToLower(get("language")),
+			Format:      models.BookFormat(strings.ToLower(get("format"))),
+			Authors:     splitList(get("authors")),
+			Categories:  splitList(get("categories")),
+			Tags:        splitList(get("tags")),
+		}
+		if isbn := get("isbn"); isbn != "" {
+			record.assignISBN(isbn)
+		}
+		if record.PublishedYear, err = parseOptionalInt(get("published_year")); err != nil {
+			record.ParseError = "invalid published_year: " + err.Error()
+		}
+		if record.PageCount, err = parseOptionalInt(get("page_count")); err != nil {
+			record.ParseError = "invalid page_count: " + err.Error()
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+var (
+	marcISBNPattern  = regexp.MustCompile(`^[0-9Xx-]+`)
+	marcPagesPattern = regexp.MustCompile(`(\d+)\s*(p|pages|tr)\b`)
+)
+
+// parseMARCRecords maps binary MARC21 bibliographic records onto import records:
+// 020 ISBN, 100/700 authors, 245 title and subtitle, 260/264 publisher and
+// year, 300 pages, 520 description, 650 subjects as categories, 653 index
+// terms as tags and 008/35-37 language.
+func parseMARCRecords(r io.Reader) ([]ImportRecord, error) {
+	reader := newMARCReader(r)
+	var records []ImportRecord
+	for row := 1; ; row++ {
+		marc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			records = append(records, ImportRecord{Row: row, ParseError: err.Error()})
+			// A broken directory leaves the stream unusable from here on
+			break
+		}
+
+		record := ImportRecord{
+			Row:         row,
+			Title:       trimMARCPunctuation(marc.subfield("245", 'a')),
+			Subtitle:    trimMARCPunctuation(marc.subfield("245", 'b')),
+			Description: marc.subfield("520", 'a'),
+			Publisher:   trimMARCPunctuation(firstNonEmpty(marc.subfield("264", 'b'), marc.subfield("260", 'b'))),
+			Language:    normalizeLanguage(marcLanguage(marc.controlField("008"))),
+			Format:      models.FormatPaperback,
+		}
+		for _, value := range marc.subfields("020", 'a') {
+			// Drop qualifiers such as "(pbk.)" after the number
+			if isbn := marcISBNPattern.FindString(strings.TrimSpace(value)); isbn != "" {
+				record.assignISBN(isbn)
+			} else if strings.TrimSpace(value) != "" {
+				record.ParseError = fmt.Sprintf("invalid ISBN %q", value)
+			}
+		}
+		record.PublishedYear = parseYear(firstNonEmpty(marc.subfield("264", 'c'), marc.subfield("260", 'c')))
+		if match := marcPagesPattern.FindStringSubmatch(marc.subfield("300", 'a')); match != nil {
+			record.PageCount, _ = strconv.Atoi(match[1])
+		}
+		for _, tag := range []string{"100", "700"} {
+			for _, name := range marc.subfields(tag, 'a') {
+				record.Authors = append(record.Authors, marcPersonalName(name))
+			}
+		}
+		for _, subject := range marc.subfields("650", 'a') {
+			record.Categories = append(record.Categories, trimMARCPunctuation(subject))
+		}
+		for _, term := range marc.subfields("653", 'a') {
+			record.Tags = append(record.Tags, trimMARCPunctuation(term))
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// assignISBN fills the ISBN-10 or ISBN-13 slot matching the length of isbn.
+// An ISBN of any other length fails the row, as importing it without its
+// ISBN would skip the de-duplication on ISBN.
+func (r *ImportRecord) assignISBN(isbn string) {
+	cleaned := cleanISBN(isbn)
+	switch len(cleaned) {
+	case 10:
+		if r.ISBN10 == "" {
+			r.ISBN10 = cleaned
+		}
+	case 13:
+		if r.ISBN13 == "" {
+			r.ISBN13 = cleaned
+		}
+	default:
+		r.ParseError = fmt.Sprintf("invalid ISBN %q: must have 10 or 13 digits", isbn)
+	}
+}
+
+// trimMARCPunctuation removes the ISBD punctuation cataloguers append to subfields
+func trimMARCPunctuation(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,."))
+}
+
+// marcPersonalName turns an inverted heading such as "Martin, Robert C.," into "Robert C. Martin"
+func marcPersonalName(value string) string {
+	name := strings.TrimRight(strings.TrimSpace(value), " ,")
+	// Keep the period of a trailing initial
+	if words := strings.Fields(name); len(words) > 0 && len([]rune(words[len(words)-1])) > 2 {
+		name = strings.TrimSuffix(name, ".")
+	}
+	if last, first, ok := strings.Cut(name, ", "); ok && !strings.Contains(first, ",") {
+		name = first + " " + last
+	}
+	return name
+}
+
+func marcLanguage(field008 string) string {
+	if len(field008) < 38 {
+		return ""
+	}
+	return strings.TrimSpace(field008[35:38])
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ";")
+}
+
+func parseOptionalInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func isBlankRow(values []string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+)
+
+func TestParseCSVRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []ImportRecord
+		wantErr string
+	}{
+		{
+			name: "header mapping",
+			input: "\ufeffTitle, Published Year,ISBN,Authors,categories,TAGS,Language,Format,Page Count,Unknown\n" +
+				"Clean Code,2008,978-0-13-235088-4,Robert C. Martin,Software;Craft,agile, EN,Hardcover,464,ignored\n",
+			want: []ImportRecord{{
+				Row:           2,
+				Title:         "Clean Code",
+				ISBN13:        "9780132350884",
+				PublishedYear: 2008,
+				PageCount:     464,
+				Language:      "en",
+				Format:        models.FormatHardcover,
+				Authors:       []string{"Robert C. Martin"},
+				Categories:    []string{"Software", "Craft"},
+				Tags:          []string{"agile"},
+			}},
+		},
+		{
+			name:  "isbn column fills the matching slot",
+			input: "title,isbn,isbn13\nSICP,0-262-51087-1,\nCLRS,9780262033848,9780262033848\n",
+			want: []ImportRecord{
+				{Row: 2, Title: "SICP", ISBN10: "0262510871"},
+				{Row: 3, Title: "CLRS", ISBN13: "9780262033848"},
+			},
+		},
+		{
+			name:  "isbn of the wrong length fails the row",
+			input: "title,isbn\nDune,978-0-441-01359\n",
+			want:  []ImportRecord{{Row: 2, Title: "Dune", ParseError: `invalid ISBN "978-0-441-01359": must have 10 or 13 digits`}},
+		},
+		{
+			name:  "blank rows are skipped and short rows padded",
+			input: "title,subtitle,publisher\n\n,,\nDune\n",
+			want:  []ImportRecord{{Row: 4, Title: "Dune"}},
+		},
+		{
+			name:  "invalid numbers are reported on the row",
+			input: "title,published_year,page_count\nDune,1965,many\n",
+			want:  []ImportRecord{{Row: 2, Title: "Dune", PublishedYear: 1965, ParseError: `invalid page_count: strconv.Atoi: parsing "many": invalid syntax`}},
+		},
+		{
+			name:    "missing title column",
+			input:   "name,isbn\nDune,9780441013593\n",
+			wantErr: "CSV header must contain a title column",
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: "CSV file is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImportFile(ImportFormatCSV, strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMARCRecords(t *testing.T) {
+	field008 := "080801s2008    njua          001 0 eng d"
+	input := encodeMARC(t, &marcRecord{
+		Fields: []marcField{
+			{Tag: "008", Value: field008},
+			{Tag: "020", Subfields: []marcSubfield{{Code: 'a', Value: "9780132350884 (pbk. : alk. paper)"}}},
+			{Tag: "020", Subfields: []marcSubfield{{Code: 'a', Value: "0132350882"}}},
+			{Tag: "100", Indicators: "1 ", Subfields: []marcSubfield{{Code: 'a', Value: "Martin, Robert C.,"}}},
+			{Tag: "245", Indicators: "10", Subfields: []marcSubfield{
+				{Code: 'a', Value: "Clean code :"},
+				{Code: 'b', Value: "a handbook of agile software craftsmanship /"},
+			}},
+			{Tag: "264", Indicators: " 1", Subfields: []marcSubfield{
+				{Code: 'b', Value: "Prentice Hall,"},
+				{Code: 'c', Value: "c2009."},
+			}},
+			{Tag: "300", Subfields: []marcSubfield{{Code: 'a', Value: "xxix, 431 p. :"}}},
+			{Tag: "650", Indicators: " 0", Subfields: []marcSubfield{{Code: 'a', Value: "Agile software development."}}},
+			{Tag: "653", Subfields: []marcSubfield{{Code: 'a', Value: "refactoring"}}},
+		},
+	})
+
+	got, err := ParseImportFile(ImportFormatMARC, bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ImportRecord{{
+		Row:           1,
+		Title:         "Clean code",
+		Subtitle:      "a handbook of agile software craftsmanship",
+		ISBN10:        "0132350882",
+		ISBN13:        "9780132350884",
+		PublishedYear: 2009,
+		PageCount:     431,
+		Publisher:     "Prentice Hall",
+		Language:      "en",
+		Format:        models.FormatPaperback,
+		Authors:       []string{"Robert C. Martin"},
+		Categories:    []string{"Agile software development"},
+		Tags:          []string{"refactoring"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseMARCRecordsRejectsMalformedISBNs(t *testing.T) {
+	for value, wantErr := range map[string]string{
+		"013235088 (pbk.)": `invalid ISBN "013235088": must have 10 or 13 digits`,
+		"(pbk.)":           `invalid ISBN "(pbk.)"`,
+	} {
+		record := sampleMARCRecord()
+		record.Fields = append(record.Fields, marcField{Tag: "020", Subfields: []marcSubfield{{Code: 'a', Value: value}}})
+		got, err := ParseImportFile(ImportFormatMARC, bytes.NewReader(encodeMARC(t, record)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 1 || got[0].ParseError != wantErr {
+			t.Errorf("020 %q: got %+v, want parse error %q", value, got, wantErr)
+		}
+	}
+}
+
+func TestParseMARCRecordsStopsAtBrokenRecord(t *testing.T) {
+	valid := encodeMARC(t, sampleMARCRecord())
+	broken := append([]byte(nil), valid...)
+	copy(broken[marcLeaderLength:], "001-00100000")
+
+	got, err := ParseImportFile(ImportFormatMARC, bytes.NewReader(append(append(valid, broken...), valid...)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].ParseError != "" || got[1].Row != 2 || got[1].ParseError == "" {
+		t.Errorf("got %+v, want the first record and an error for row 2", got)
+	}
+}
+
+func TestParseImportFileUnknownFormat(t *testing.T) {
+	if _, err := ParseImportFile("xlsx", strings.NewReader("")); err == nil {
+		t.Error("got no error for an unsupported format")
+	}
+}
+
+func TestParseCSVRecordsReportsMalformedRows(t *testing.T) {
+	input := "title\nDune\n\"Bad \"quote\"\nEmma\n"
+	got, err := ParseImportFile(ImportFormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[1].Row != 3 || got[1].ParseError == "" || got[2].Row != 4 || got[2].Title != "Emma" {
+		t.Errorf("got %+v, want an error on row 3 between Dune and Emma", got)
+	}
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrBookListNotFound is returned for lists that do not exist or belong to someone else
+var ErrBookListNotFound = errors.New("list not found")
+
+// MoveToCartResult reports what MoveToCart did with each book on a list
+type MoveToCartResult struct {
+	Added   []MovedBook   `json:"added"`
+	Skipped []SkippedBook `json:"skipped"`
+}
+
+type MovedBook struct {
+	BookID     uuid.UUID `json:"book_id"`
+	BookCopyID uuid.UUID `json:"book_copy_id"`
+}
+
+type SkippedBook struct {
+	BookID uuid.UUID `json:"book_id"`
+	Reason string    `json:"reason"`
+}
+
+type BookListService struct {
+	db          *gorm.DB
+	cartService *CartService
+}
+
+func NewBookListService(db *gorm.DB, cartService *CartService) *BookListService {
+	return &BookListService{
+		db:          db,
+		cartService: cartService,
+	}
+}
+
+// GetBookLists retrieves the user's lists with the number of books on each
+func (s *BookListService) GetBookLists(userID string) ([]models.BookList, error) {
+	var lists []models.BookList
+	if err := s.db.Where("user_id = ?", userID).Order("name").Find(&lists).Error; err != nil {
+		zap.L().Error("GetBookLists: Failed to get lists", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return lists, nil
+	}
+
+	ids := make([]uuid.UUID, len(lists))
+	for i, list := range lists {
+		ids[i] = list.ID
+	}
+	var rows []struct {
+		BookListID uuid.UUID
+		Count      int64
+	}
+	if err := s.db.Model(&models.BookListItem{}).
+		Select("book_list_id, COUNT(*) AS count").
+		Where("book_list_id IN ?", ids).
+		Group("book_list_id").
+		Scan(&rows).Error; err != nil {
+		zap.L().Error("GetBookLists: Failed to count books", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.BookListID] = row.Count
+	}
+	for i := range lists {
+		lists[i].BookCount = counts[lists[i].ID]
+	}
+	return lists, nil
+}
+
+// GetBookList retrieves one of the user's lists with its books
+func (s *BookListService) GetBookList(id, userID string) (*models.BookList, error) {
+	list, err := s.loadBookList(s.db.Where("id = ? AND user_id = ?", id, userID))
+	if err != nil {
+		zap.L().Error("GetBookList: Failed to get list", zap.String("id", id), zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetSharedBookList retrieves a public list by its share token
+func (s *BookListService) GetSharedBookList(token string) (*models.BookList, error) {
+	list, err := s.loadBookList(s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name")
+	}).Where("share_token = ? AND is_public", token))
+	if err != nil {
+		zap.L().Error("GetSharedBookList: Failed to get shared list", zap.Error(err))
+		return nil, err
+	}
+	return list, nil
+}
+
+// CreateBookList creates a list for the user
+func (s *BookListService) CreateBookList(userID string, req models.BookListRequest) (*models.BookList, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	if err := s.checkListName(userUUID, uuid.Nil, req.Name); err != nil {
+		return nil, err
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	list := models.BookList{
+		UserID:      userUUID,
+		Name:        req.Name,
+		Description: req.Description,
+		IsPublic:    req.IsPublic,
+		ShareToken:  token,
+	}
+	if err := s.db.Create(&list).Error; err != nil {
+		zap.L().Error("CreateBookList: Failed to create list", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("CreateBookList: List created successfully", zap.String("id", list.ID.String()), zap.String("userID", userID))
+	return &list, nil
+}
+
+// UpdateBookList renames a list, changes its description or shares it
+func (s *BookListService) UpdateBookList(id, userID string, req models.BookListRequest) (*models.BookList, error) {
+	var list models.BookList
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookListNotFound
+		}
+		zap.L().Error("UpdateBookList: Failed to get list", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if err := s.checkListName(list.UserID, list.ID, req.Name); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&list).Updates(map[string]interface{}{
+		"name":        req.Name,
+		"description": req.Description,
+		"is_public":   req.IsPublic,
+	}).Error; err != nil {
+		zap.L().Error("UpdateBookList: Failed to update list", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	list.Name = req.Name
+	list.Description = req.Description
+	list.IsPublic = req.IsPublic
+	zap.L().Info("UpdateBookList: List updated successfully", zap.String("id", id), zap.String("userID", userID))
+	return &list, nil
+}
+
+// DeleteBookList deletes one of the user's lists
+func (s *BookListService) DeleteBookList(id, userID string) error {
+	// The list's items go with it through the foreign key
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.BookList{})
+	if result.Error != nil {
+		zap.L().Error("DeleteBookList: Failed to delete list", zap.String("id", id), zap.String("userID", userID), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookListNotFound
+	}
+	zap.L().Info("DeleteBookList: List deleted successfully", zap.String("id", id), zap.String("userID", userID))
+	return nil
+}
+
+// RegenerateShareToken replaces the share token of a list, invalidating
+// links shared before
+func (s *BookListService) RegenerateShareToken(id, userID string) (*models.BookList, error) {
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	result := s.db.Model(&models.BookList{}).Where("id = ? AND user_id = ?", id, userID).Update("share_token", token)
+	if result.Error != nil {
+		zap.L().Error("RegenerateShareToken: Failed to update token", zap.String("id", id), zap.Error(result.Error))
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBookListNotFound
+	}
+	return s.GetBookList(id, userID)
+}
+
+// AddBookToList adds a book to one of the user's lists; adding a book twice is a no-op
+func (s *BookListService) AddBookToList(id, userID, bookID string) error {
+	list, err := s.ownedList(id, userID)
+	if err != nil {
+		return err
+	}
+	bookUUID, err := uuid.Parse(bookID)
+	if err != nil {
+		return FieldErrors{"book_id": "invalid book ID"}
+	}
+	var count int64
+	if err := s.db.Model(&models.Book{}).Where("id = ?", bookUUID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return FieldErrors{"book_id": "book not found"}
+	}
+
+	item := models.BookListItem{BookListID: list.ID, BookID: bookUUID}
+	if err := s.db.Where("book_list_id = ? AND book_id = ?", list.ID, bookUUID).
+		FirstOrCreate(&item).Error; err != nil {
+		zap.L().Error("AddBookToList: Failed to add book", zap.String("id", id), zap.String("bookID", bookID), zap.Error(err))
+		return err
+	}
+	zap.L().Info("AddBookToList: Book added to list", zap.String("id", id), zap.String("bookID", bookID))
+	return nil
+}
+
+// RemoveBookFromList removes a book from one of the user's lists
+func (s *BookListService) RemoveBookFromList(id, userID, bookID string) error {
+	list, err := s.ownedList(id, userID)
+	if err != nil {
+		return err
+	}
+	result := s.db.Where("book_list_id = ? AND book_id = ?", list.ID, bookID).Delete(&models.BookListItem{})
+	if result.Error != nil {
+		zap.L().Error("RemoveBookFromList: Failed to remove book", zap.String("id", id), zap.String("bookID", bookID), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("book is not on this list")
+	}
+	zap.L().Info("RemoveBookFromList: Book removed from list", zap.String("id", id), zap.String("bookID", bookID))
+	return nil
+}
+
+// MoveToCart puts an available copy of each book on the list into the user's
+// cart, in the order the books were added, until the cart is full. Books stay
+// on the list.
+func (s *BookListService) MoveToCart(id, userID string) (*MoveToCartResult, error) {
+	list, err := s.ownedList(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	var items []models.BookListItem
+	if err := s.db.Where("book_list_id = ?", list.ID).Order("created_at").Find(&items).Error; err != nil {
+		zap.L().Error("MoveToCart: Failed to get list items", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	// Books with a copy in the cart already are left alone
+	var inCart []uuid.UUID
+	if err := s.db.Model(&models.CartItem{}).
+		Joins("JOIN book_copies ON book_copies.id = cart_items.book_copy_id").
+		Where("cart_items.user_id = ?", userID).
+		Pluck("book_copies.book_id", &inCart).Error; err != nil {
+		zap.L().Error("MoveToCart: Failed to get cart", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	carted := make(map[uuid.UUID]bool, len(inCart))
+	for _, bookID := range inCart {
+		carted[bookID] = true
+	}
+
+	result := &MoveToCartResult{Added: []MovedBook{}, Skipped: []SkippedBook{}}
+	cartFull := false
+	for _, item := range items {
+		switch {
+		case cartFull:
+			result.Skipped = append(result.Skipped, SkippedBook{BookID: item.BookID, Reason: ErrCartLimitReached.Error()})
+			continue
+		case carted[item.BookID]:
+			result.Skipped = append(result.Skipped, SkippedBook{BookID: item.BookID, Reason: "already in cart"})
+			continue
+		}
+
+		var bookCopy models.BookCopy
+		err := s.db.Where("book_id = ? AND status = ?", item.BookID, models.BookCopyStatusAvailable).
+			Order("created_at").
+			First(&bookCopy).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result.Skipped = append(result.Skipped, SkippedBook{BookID: item.BookID, Reason: "no copy available"})
+			continue
+		}
+		if err != nil {
+			zap.L().Error("MoveToCart: Failed to find available copy", zap.String("bookID", item.BookID.String()), zap.Error(err))
+			return nil, err
+		}
+
+		if err := s.cartService.AddToCart(userID, bookCopy.ID.String()); err != nil {
+			if errors.Is(err, ErrCartLimitReached) {
+				cartFull = true
+			}
+			result.Skipped = append(result.Skipped, SkippedBook{BookID: item.BookID, Reason: err.Error()})
+			continue
+		}
+		carted[item.BookID] = true
+		result.Added = append(result.Added, MovedBook{BookID: item.BookID, BookCopyID: bookCopy.ID})
+	}
+
+	zap.L().Info("MoveToCart: List moved to cart", zap.String("id", id), zap.String("userID", userID),
+		zap.Int("added", len(result.Added)), zap.Int("skipped", len(result.Skipped)))
+	return result, nil
+}
+
+func (s *BookListService) loadBookList(query *gorm.DB) (*models.BookList, error) {
+	var list models.BookList
+	if err := query.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("book_list_items.created_at")
+		}).
+		Preload("Items.Book").
+		Preload("Items.Book.Authors").
+		First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookListNotFound
+		}
+		return nil, err
+	}
+	list.BookCount = int64(len(list.Items))
+	return &list, nil
+}
+
+func (s *BookListService) ownedList(id, userID string) (*models.BookList, error) {
+	var list models.BookList
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookListNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// checkListName rejects a name already used by another of the user's lists
+func (s *BookListService) checkListName(userID, exclude uuid.UUID, name string) error {
+	var count int64
+	if err := s.db.Model(&models.BookList{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, exclude).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return FieldErrors{"name": "you already have a list with this name"}
+	}
+	return nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Similar books limits
+const (
+	DefaultSimilarBooksLimit = 10
+	MaxSimilarBooksLimit     = 50
+)
+
+// similarBooksQuery scores every book sharing an author, category or tag with
+// the source book. A shared author weighs most, a shared tag least. Other
+// editions of the same work are left out since the book page lists them
+// already. Ties go to books in the same language, then to newer books.
+const similarBooksQuery = `
+WITH source AS (
+	SELECT id, language, work_id FROM books WHERE id = @id
+), shared AS (
+	SELECT ba.book_id, 3 AS weight
+	FROM book_authors ba
+	JOIN book_authors src ON src.author_id = ba.author_id AND src.book_id = @id
+	UNION ALL
+	SELECT bc.book_id, 2 AS weight
+	FROM book_categories bc
+	JOIN book_categories src ON src.category_id = bc.category_id AND src.book_id = @id
+	UNION ALL
+	SELECT bt.book_id, 1 AS weight
+	FROM book_tags bt
+	JOIN book_tags src ON src.tag_id = bt.tag_id AND src.book_id = @id
+)
+SELECT b.id, SUM(shared.weight) AS score
+FROM shared
+JOIN books b ON b.id = shared.book_id AND b.deleted_at IS NULL
+CROSS JOIN source
+WHERE b.id <> source.id
+	AND (source.work_id IS NULL OR b.work_id IS DISTINCT FROM source.work_id)
+	AND (NOT @available_only OR EXISTS (
+		SELECT 1 FROM book_copies bc
+		WHERE bc.book_id = b.id AND bc.status = 'available' AND bc.deleted_at IS NULL
+	))
+GROUP BY b.id, b.language, b.created_at, source.language
+ORDER BY score DESC, b.language = source.language DESC, b.created_at DESC, b.id
+LIMIT @limit`
+
+// GetSimilarBooks returns up to limit books that share the most authors,
+// categories and tags with the book, best match first. With availableOnly
+// set, only books with a copy available to borrow are considered.
+func (s *BookService) GetSimilarBooks(id string, limit int, availableOnly bool) ([]models.SimilarBook, error) {
+	bookID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("book not found")
+	}
+	var count int64
+	if err := s.db.Model(&models.Book{}).Where("id = ?", bookID).Count(&count).Error; err != nil {
+		zap.L().Error("GetSimilarBooks: Failed to look up book", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if count == 0 {
+		zap.L().Warn("GetSimilarBooks: Book not found", zap.String("id", id))
+		return nil, errors.New("book not found")
+	}
+
+	var scores []struct {
+		ID    uuid.UUID
+		Score int
+	}
+	if err := s.db.Raw(similarBooksQuery, map[string]interface{}{
+		"id":             bookID,
+		"available_only": availableOnly,
+		"limit":          limit,
+	}).Scan(&scores).Error; err != nil {
+		zap.L().Error("GetSimilarBooks: Failed to score similar books", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return []models.SimilarBook{}, nil
+	}
+
+	ids := make([]uuid.UUID, len(scores))
+	scoreByID := make(map[uuid.UUID]int, len(scores))
+	for i, score := range scores {
+		ids[i] = score.ID
+		scoreByID[score.ID] = score.Score
+	}
+	books, err := loadBooksInOrder(s.db, ids)
+	if err != nil {
+		zap.L().Error("GetSimilarBooks: Failed to load similar books", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	similar := make([]models.SimilarBook, len(books))
+	for i, book := range books {
+		similar[i] = models.SimilarBook{Book: book, Score: scoreByID[book.ID]}
+	}
+	zap.L().Info("GetSimilarBooks: Successfully retrieved similar books", zap.String("id", id), zap.Int("count", len(similar)))
+	return similar, nil
+}
+
+// loadBooksInOrder loads the books with their relations in the order of ids,
+// skipping any that no longer exist
+func loadBooksInOrder(db *gorm.DB, ids []uuid.UUID) ([]models.Book, error) {
+	var found []models.Book
+	if err := db.Preload("Authors").Preload("Categories").Preload("Tags").
+		Where("id IN ?", ids).
+		Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Book, len(found))
+	for _, book := range found {
+		byID[book.ID] = book
+	}
+
+	books := make([]models.Book, 0, len(ids))
+	for _, id := range ids {
+		if book, ok := byID[id]; ok {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
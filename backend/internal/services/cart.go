@@ -9,6 +9,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrCartLimitReached is returned when adding to a cart that is already full
+var ErrCartLimitReached = errors.New("cart limit reached: maximum 5 items allowed")
+
 type CartService struct {
 	db *gorm.DB
 }
@@ -52,7 +55,7 @@ func (s *CartService) AddToCart(userID, bookCopyID string) error {
 
 	if count >= 5 {
 		zap.L().Warn("AddToCart: Cart limit reached", zap.String("userID", userID), zap.Int64("currentCount", count))
-		return errors.New("cart limit reached: maximum 5 items allowed")
+		return ErrCartLimitReached
 	}
 
 	// Check if book copy is already in cart
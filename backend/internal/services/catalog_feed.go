@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrFeedNotFound = errors.New("feed not found")
+
+// CatalogFeedFilter narrows a catalog feed to the books of one category,
+// author or tag, or in one language. The zero value is the whole catalog.
+type CatalogFeedFilter struct {
+	CategoryID string
+	AuthorID   string
+	TagID      string
+	Language   string
+}
+
+// CatalogFeed is a page of the newest books matching a filter
+type CatalogFeed struct {
+	// Name is the name of the category, author or tag the feed is about
+	Name  string
+	Books []ExportedBook
+	Total int64
+	// Updated is when the newest book matching the filter was added
+	Updated time.Time
+}
+
+// CatalogFeedVersion summarizes the books matching a filter. It is cheap to
+// compute, so conditional requests can be answered before loading any book.
+type CatalogFeedVersion struct {
+	Name  string
+	Total int64
+	// Updated is when the newest book matching the filter was added
+	Updated time.Time
+	// Modified is when a book matching the filter was last added or edited
+	Modified time.Time
+}
+
+// LastModified is the time to report in the Last-Modified header of the feed
+func (v *CatalogFeedVersion) LastModified() time.Time {
+	return feedTimestamp(v.Modified).UTC().Truncate(time.Second)
+}
+
+// CatalogFeedGroup is a category, author or tag listed in a navigation feed
+type CatalogFeedGroup struct {
+	ID        uuid.UUID
+	Name      string
+	BookCount int64
+	Updated   time.Time
+}
+
+// CatalogFeedService loads the data behind the syndication and e-reader
+// (OPDS) feeds of the catalog
+type CatalogFeedService struct {
+	db *gorm.DB
+}
+
+func NewCatalogFeedService(db *gorm.DB) *CatalogFeedService {
+	return &CatalogFeedService{db: db}
+}
+
+// GetFeedVersion returns the name, size and update times of the feed of the
+// filter without loading its books
+func (s *CatalogFeedService) GetFeedVersion(filter CatalogFeedFilter) (*CatalogFeedVersion, error) {
+	query, name, err := s.feedQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	return feedVersion(query, name, filter)
+}
+
+// GetFeedBooks returns the books matching the filter, newest first, with
+// their copy counts
+func (s *CatalogFeedService) GetFeedBooks(filter CatalogFeedFilter, page, limit int) (*CatalogFeed, error) {
+	query, name, err := s.feedQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	version, err := feedVersion(query, name, filter)
+	if err != nil {
+		return nil, err
+	}
+	feed := &CatalogFeed{Name: version.Name, Books: []ExportedBook{}, Total: version.Total, Updated: version.Updated}
+
+	var books []models.Book
+	if err := query.Preload("Authors").Preload("Categories").Preload("Tags").
+		Order("books.created_at DESC, books.id").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&books).Error; err != nil {
+		zap.L().Error("GetFeedBooks: Failed to get books", zap.Any("filter", filter), zap.Error(err))
+		return nil, err
+	}
+	if len(books) == 0 {
+		return feed, nil
+	}
+	copies, err := countCopies(s.db, books)
+	if err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		feed.Books = append(feed.Books, ExportedBook{Book: book, Copies: copies[book.ID]})
+	}
+	return feed, nil
+}
+
+// feedQuery selects the books matching the filter and returns the name of
+// the category, author, tag or language it is about
+func (s *CatalogFeedService) feedQuery(filter CatalogFeedFilter) (*gorm.DB, string, error) {
+	query := s.db.Model(&models.Book{})
+	name := ""
+
+	var group struct {
+		table     string
+		joinTable string
+		column    string
+		id        string
+	}
+	switch {
+	case filter.CategoryID != "":
+		group.table, group.joinTable, group.column, group.id = "categories", "book_categories", "category_id", filter.CategoryID
+	case filter.AuthorID != "":
+		group.table, group.joinTable, group.column, group.id = "authors", "book_authors", "author_id", filter.AuthorID
+	case filter.TagID != "":
+		group.table, group.joinTable, group.column, group.id = "tags", "book_tags", "tag_id", filter.TagID
+	}
+	if group.table != "" {
+		if _, err := uuid.Parse(group.id); err != nil {
+			return nil, "", ErrFeedNotFound
+		}
+		var names []string
+		if err := s.db.Table(group.table).Where("id = ? AND deleted_at IS NULL", group.id).Pluck("name", &names).Error; err != nil {
+			zap.L().Error("feedQuery: Failed to get feed name", zap.String("table", group.table), zap.String("id", group.id), zap.Error(err))
+			return nil, "", err
+		}
+		if len(names) == 0 {
+			return nil, "", ErrFeedNotFound
+		}
+		name = names[0]
+		query = query.Where("books.id IN (?)", s.db.Table(group.joinTable).Select("book_id").Where(group.column+" = ?", group.id))
+	}
+	if filter.Language != "" {
+		// Books store two-letter language codes
+		if len(filter.Language) != 2 {
+			return nil, "", ErrFeedNotFound
+		}
+		name = filter.Language
+		query = query.Where("books.language = ?", filter.Language)
+	}
+	return query, name, nil
+}
+
+// feedVersion counts the books of a feed query and finds when they last changed
+func feedVersion(query *gorm.DB, name string, filter CatalogFeedFilter) (*CatalogFeedVersion, error) {
+	var row struct {
+		Total    int64
+		Updated  *time.Time
+		Modified *time.Time
+	}
+	if err := query.Session(&gorm.Session{}).
+		Select("COUNT(*) AS total, MAX(books.created_at) AS updated, MAX(books.updated_at) AS modified").
+		Scan(&row).Error; err != nil {
+		zap.L().Error("feedVersion: Failed to summarize feed", zap.Any("filter", filter), zap.Error(err))
+		return nil, err
+	}
+	version := &CatalogFeedVersion{Name: name, Total: row.Total}
+	if row.Updated != nil {
+		version.Updated = *row.Updated
+	}
+	if row.Modified != nil {
+		version.Modified = *row.Modified
+	}
+	return version, nil
+}
+
+// GetFeedCategories lists the categories that have books, by name
+func (s *CatalogFeedService) GetFeedCategories() ([]CatalogFeedGroup, error) {
+	return s.feedGroups("categories", "book_categories", "category_id")
+}
+
+// GetFeedAuthors lists the authors that have books, by name
+func (s *CatalogFeedService) GetFeedAuthors() ([]CatalogFeedGroup, error) {
+	return s.feedGroups("authors", "book_authors", "author_id")
+}
+
+// GetFeedTags lists the tags that have books, by name
+func (s *CatalogFeedService) GetFeedTags() ([]CatalogFeedGroup, error) {
+	return s.feedGroups("tags", "book_tags", "tag_id")
+}
+
+func (s *CatalogFeedService) feedGroups(table, joinTable, column string) ([]CatalogFeedGroup, error) {
+	groups := []CatalogFeedGroup{}
+	if err := s.db.Table(table + " g").
+		Select("g.id, g.name, COUNT(b.id) AS book_count, MAX(b.created_at) AS updated").
+		Joins("JOIN " + joinTable + " j ON j." + column + " = g.id").
+		Joins("JOIN books b ON b.id = j.book_id AND b.deleted_at IS NULL").
+		Where("g.deleted_at IS NULL").
+		Group("g.id, g.name").
+		Order("g.name").
+		Scan(&groups).Error; err != nil {
+		zap.L().Error("feedGroups: Failed to get feed groups", zap.String("table", table), zap.Error(err))
+		return nil, err
+	}
+	return groups, nil
+}
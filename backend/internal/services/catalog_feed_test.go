@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetFeedVersionSummarizesWithoutLoadingBooks(t *testing.T) {
+	db, stub := newStubDB(t)
+	service := NewCatalogFeedService(db)
+
+	version, err := service.GetFeedVersion(CatalogFeedFilter{Language: "en"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version.Name != "en" || version.Total != 0 || !version.Modified.IsZero() {
+		t.Errorf("got %+v, want an empty feed named en", version)
+	}
+
+	statements := stub.Statements()
+	if len(statements) != 1 || !strings.Contains(statements[0], "COUNT(*)") {
+		t.Fatalf("got statements %q, want a single aggregate", statements)
+	}
+}
+
+func TestGetFeedVersionRejectsUnknownFeeds(t *testing.T) {
+	db, stub := newStubDB(t)
+	service := NewCatalogFeedService(db)
+
+	for name, filter := range map[string]CatalogFeedFilter{
+		"malformed category ID": {CategoryID: "not-a-uuid"},
+		"missing tag":           {TagID: "6f1c2b7e-8a4d-4c3e-9b2a-1d5e7f9a0b3c"},
+		"language name":         {Language: "english"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := service.GetFeedVersion(filter); !errors.Is(err, ErrFeedNotFound) {
+				t.Errorf("got error %v, want ErrFeedNotFound", err)
+			}
+		})
+	}
+	for _, statement := range stub.Statements() {
+		if strings.Contains(statement, "COUNT(*)") {
+			t.Errorf("unknown feeds should not be summarized, got %q", statement)
+		}
+	}
+}
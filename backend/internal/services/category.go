@@ -2,13 +2,21 @@ package services
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/hungcq/pscit/backend/internal/models"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	// ErrCategoryCycle is returned when a category would become its own ancestor
+	ErrCategoryCycle = errors.New("a category cannot be placed under itself or one of its subcategories")
+)
+
 type CategoryService struct {
 	db *gorm.DB
 }
@@ -41,8 +49,45 @@ func (s *CategoryService) GetCategory(id string) (*models.Category, error) {
 	return &category, nil
 }
 
-func (s *CategoryService) CreateCategory(category *models.Category) error {
-	if err := s.db.Create(category).Error; err != nil {
+// GetCategoryTree returns the root categories with their subcategories
+// nested. Subcategories of a trashed category are shown as roots until it is
+// restored.
+func (s *CategoryService) GetCategoryTree() ([]models.Category, error) {
+	var categories []models.Category
+	if err := s.db.Order("name").Find(&categories).Error; err != nil {
+		zap.L().Error("GetCategoryTree: Failed to retrieve categories", zap.Error(err))
+		return nil, err
+	}
+	return buildCategoryTree(categories), nil
+}
+
+// GetCategorySubtree returns a category with its subcategories nested
+func (s *CategoryService) GetCategorySubtree(id string) (*models.Category, error) {
+	var categories []models.Category
+	if err := s.db.Where("id IN ("+categorySubtreeSQL("id = ?", false)+")", id).
+		Order("name").Find(&categories).Error; err != nil {
+		zap.L().Error("GetCategorySubtree: Failed to retrieve categories", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	for _, root := range buildCategoryTree(categories) {
+		if root.ID.String() == id {
+			return &root, nil
+		}
+	}
+	zap.L().Warn("GetCategorySubtree: Category not found", zap.String("id", id))
+	return nil, errors.New("category not found")
+}
+
+func (s *CategoryService) CreateCategory(category *models.Category, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, category.ID, category.ParentID); err != nil {
+			return err
+		}
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityCategories, category.ID, nil, category)
+	}); err != nil {
 		zap.L().Error("CreateCategory: Failed to create category", zap.String("name", category.Name), zap.Error(err))
 		return err
 	}
@@ -50,33 +95,143 @@ func (s *CategoryService) CreateCategory(category *models.Category) error {
 	return nil
 }
 
-func (s *CategoryService) UpdateCategory(id string, category *models.Category) error {
-	result := s.db.Model(&models.Category{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"name":        category.Name,
-		"description": category.Description,
-	})
-	if result.Error != nil {
-		zap.L().Error("UpdateCategory: Failed to update category", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("UpdateCategory: Category not found for update", zap.String("id", id))
-		return errors.New("category not found")
+func (s *CategoryService) UpdateCategory(id string, category *models.Category, updateParent bool, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing, updated models.Category
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("UpdateCategory: Category not found for update", zap.String("id", id))
+				return errors.New("category not found")
+			}
+			return err
+		}
+		updates := map[string]interface{}{
+			"name":        category.Name,
+			"description": category.Description,
+		}
+		if updateParent {
+			if err := checkCategoryParent(tx, existing.ID, category.ParentID); err != nil {
+				return err
+			}
+			updates["parent_id"] = category.ParentID
+		}
+		if err := tx.Model(&models.Category{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&updated, "id = ?", id).Error; err != nil {
+			return err
+		}
+		*category = updated
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityCategories, updated.ID, &existing, &updated)
+	}); err != nil {
+		zap.L().Error("UpdateCategory: Failed to update category", zap.String("id", id), zap.Error(err))
+		return err
 	}
 	zap.L().Info("UpdateCategory: Category updated successfully", zap.String("id", id), zap.String("name", category.Name))
 	return nil
 }
 
-func (s *CategoryService) DeleteCategory(id string) error {
-	result := s.db.Unscoped().Delete(&models.Category{}, "id = ?", id)
-	if result.Error != nil {
-		zap.L().Error("DeleteCategory: Failed to delete category", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
+func (s *CategoryService) DeleteCategory(id string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Category
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("DeleteCategory: Category not found for deletion", zap.String("id", id))
+				return errors.New("category not found")
+			}
+			return err
+		}
+		// Soft delete keeps the book links so that restoring from the trash brings them back
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionDelete, models.AuditEntityCategories, existing.ID, &existing, nil)
+	}); err != nil {
+		zap.L().Error("DeleteCategory: Failed to delete category", zap.String("id", id), zap.Error(err))
+		return err
 	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("DeleteCategory: Category not found for deletion", zap.String("id", id))
-		return errors.New("category not found")
+	zap.L().Info("DeleteCategory: Category moved to trash", zap.String("id", id))
+	return nil
+}
+
+// buildCategoryTree nests categories under their parents. Categories whose
+// parent is not in the list become roots.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	present := make(map[uuid.UUID]bool, len(categories))
+	for _, category := range categories {
+		present[category.ID] = true
+	}
+
+	children := map[uuid.UUID][]models.Category{}
+	roots := []models.Category{}
+	for _, category := range categories {
+		if category.ParentID != nil && present[*category.ParentID] {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	var attach func(category *models.Category)
+	attach = func(category *models.Category) {
+		category.Children = children[category.ID]
+		for i := range category.Children {
+			attach(&category.Children[i])
+		}
+	}
+	for i := range roots {
+		attach(&roots[i])
+	}
+	return roots
+}
+
+// checkCategoryParent verifies that the category with the given ID, or a new
+// one when id is uuid.Nil, can be placed under parentID
+func checkCategoryParent(tx *gorm.DB, id uuid.UUID, parentID *uuid.UUID) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == id {
+		return ErrCategoryCycle
+	}
+	var count int64
+	if err := tx.Model(&models.Category{}).Where("id = ?", *parentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrParentCategoryNotFound
+	}
+	if id == uuid.Nil {
+		return nil
+	}
+
+	// Moves are serialized so that two concurrent ones cannot close a loop
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('category_tree'))").Error; err != nil {
+		return err
+	}
+	// Trashed subcategories count too, they come back when restored
+	if err := tx.Raw("SELECT COUNT(*) FROM ("+categorySubtreeSQL("id = ?", true)+") subtree WHERE id = ?", id, *parentID).
+		Scan(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryCycle
 	}
-	zap.L().Info("DeleteCategory: Category deleted successfully", zap.String("id", id))
 	return nil
 }
+
+// categorySubtreeSQL selects the IDs of the categories matching where together
+// with all their descendants. UNION rather than UNION ALL ends the recursion
+// even if the data ever contained a loop.
+func categorySubtreeSQL(where string, includeTrashed bool) string {
+	live, liveChild := " AND deleted_at IS NULL", " AND c.deleted_at IS NULL"
+	if includeTrashed {
+		live, liveChild = "", ""
+	}
+	return fmt.Sprintf(`WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE (%s)%s
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id%s
+		)
+		SELECT id FROM subtree`, where, live, liveChild)
+}
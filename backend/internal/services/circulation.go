@@ -0,0 +1,254 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoApprovedReservation  = errors.New("book copy is not on an approved reservation")
+	ErrReservationNotApproved = errors.New("reservation for this book copy has not been approved yet")
+	ErrCopyAlreadyCheckedOut  = errors.New("book copy is already checked out")
+	ErrCopyNotLendable        = errors.New("book copy is out of circulation")
+)
+
+// CirculationService handles copies handed out and brought back at the desk
+type CirculationService struct {
+	db           *gorm.DB
+	emailService *EmailService
+}
+
+func NewCirculationService(db *gorm.DB, emailService *EmailService) *CirculationService {
+	return &CirculationService{
+		db:           db,
+		emailService: emailService,
+	}
+}
+
+// reservationCopy is a copy's row on a reservation
+type reservationCopy struct {
+	ReservationID uuid.UUID
+	CheckedOutAt  *time.Time
+	CheckedInAt   *time.Time
+}
+
+// CheckOut hands out a scanned copy on the approved reservation it was
+// reserved for, marking it borrowed
+func (s *CirculationService) CheckOut(code string, actor models.Actor) (*models.CheckOutResult, error) {
+	var result models.CheckOutResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		copy, err := findDeskCopy(tx, code)
+		if err != nil {
+			return err
+		}
+		if !copy.Status.Lendable() {
+			return fmt.Errorf("%w: it is %s", ErrCopyNotLendable, copy.Status)
+		}
+
+		row, err := openReservationCopy(tx, copy.ID, models.ReservationStatusApproved)
+		if err != nil {
+			return err
+		}
+		if row.ReservationID == uuid.Nil {
+			pending, err := openReservationCopy(tx, copy.ID, models.ReservationStatusPending)
+			if err != nil {
+				return err
+			}
+			if pending.ReservationID != uuid.Nil {
+				return ErrReservationNotApproved
+			}
+			return ErrNoApprovedReservation
+		}
+		if row.CheckedOutAt != nil {
+			return ErrCopyAlreadyCheckedOut
+		}
+
+		if err := tx.Table("reservation_book_copies").
+			Where("reservation_id = ? AND book_copy_id = ?", row.ReservationID, copy.ID).
+			Update("checked_out_at", time.Now()).Error; err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("checked out on reservation %s", row.ReservationID)
+		if err := setDeskCopyStatus(tx, copy, models.BookCopyStatusBorrowed, reason, actor); err != nil {
+			return err
+		}
+
+		result.BookCopy = *copy
+		return tx.Preload("User").First(&result.Reservation, "id = ?", row.ReservationID).Error
+	})
+	if err != nil {
+		zap.L().Error("CheckOut: Failed to check out book copy", zap.String("code", code), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("CheckOut: Book copy checked out", zap.String("bookCopyID", result.BookCopy.ID.String()), zap.String("reservationID", result.Reservation.ID.String()))
+	return &result, nil
+}
+
+// CheckIn takes back a scanned copy, marking it available, and closes its
+// reservation once all the reservation's copies are back. A copy that was
+// not out on an approved reservation is flagged rather than rejected, since
+// it is physically at the desk either way.
+func (s *CirculationService) CheckIn(code string, actor models.Actor) (*models.CheckInResult, error) {
+	var result models.CheckInResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		copy, err := findDeskCopy(tx, code)
+		if err != nil {
+			return err
+		}
+		result.BookCopy = *copy
+
+		row, err := openReservationCopy(tx, copy.ID, models.ReservationStatusApproved)
+		if err != nil {
+			return err
+		}
+		switch {
+		case copy.Status == models.BookCopyStatusWithdrawn:
+			result.Unexpected = true
+			result.Message = "book copy has been withdrawn"
+			return nil
+		case row.ReservationID == uuid.Nil:
+			result.Unexpected = true
+			result.Message = ErrNoApprovedReservation.Error()
+			if copy.Status == models.BookCopyStatusAvailable {
+				result.Message = "book copy was not checked out"
+			}
+			return nil
+		}
+
+		var reservation models.Reservation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, "id = ?", row.ReservationID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Table("reservation_book_copies").
+			Where("reservation_id = ? AND book_copy_id = ?", reservation.ID, copy.ID).
+			Update("checked_in_at", now).Error; err != nil {
+			return err
+		}
+		// A copy reported lost is found again by coming back, while damaged
+		// copies stay out of circulation until repaired
+		if copy.Status.Lendable() || copy.Status == models.BookCopyStatusLost {
+			reason := fmt.Sprintf("checked in from reservation %s", reservation.ID)
+			if err := setDeskCopyStatus(tx, copy, models.BookCopyStatusAvailable, reason, actor); err != nil {
+				return err
+			}
+		}
+		result.BookCopy = *copy
+
+		// Copies deleted, lost or otherwise out of circulation while on loan
+		// are not waited for
+		var outstanding int64
+		if err := tx.Table("reservation_book_copies rbc").
+			Joins("JOIN book_copies bc ON bc.id = rbc.book_copy_id AND bc.deleted_at IS NULL").
+			Where("rbc.reservation_id = ? AND rbc.checked_in_at IS NULL AND bc.status IN ?", reservation.ID,
+				[]models.BookCopyStatus{models.BookCopyStatusReserved, models.BookCopyStatusBorrowed}).
+			Count(&outstanding).Error; err != nil {
+			return err
+		}
+		if outstanding == 0 {
+			before := reservation
+			if err := tx.Model(&models.Reservation{}).Where("id = ?", reservation.ID).
+				Update("status", models.ReservationStatusReturned).Error; err != nil {
+				return err
+			}
+			if err := tx.First(&reservation, "id = ?", reservation.ID).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityReservations, reservation.ID, &before, &reservation); err != nil {
+				return err
+			}
+			result.ReservationClosed = true
+		}
+
+		// The returned email lists the books of the reservation
+		if err := tx.Preload("User").
+			Unscoped().Preload("BookCopies").
+			Preload("BookCopies.Book").
+			Preload("BookCopies.Book.Authors").
+			First(&reservation, "id = ?", reservation.ID).Error; err != nil {
+			return err
+		}
+		result.Reservation = &reservation
+		return nil
+	})
+	if err != nil {
+		zap.L().Error("CheckIn: Failed to check in book copy", zap.String("code", code), zap.Error(err))
+		return nil, err
+	}
+
+	if result.Unexpected {
+		zap.L().Warn("CheckIn: Unexpected book copy checked in", zap.String("bookCopyID", result.BookCopy.ID.String()), zap.String("reason", result.Message))
+		return &result, nil
+	}
+	zap.L().Info("CheckIn: Book copy checked in", zap.String("bookCopyID", result.BookCopy.ID.String()), zap.String("reservationID", result.Reservation.ID.String()), zap.Bool("reservationClosed", result.ReservationClosed))
+	if result.ReservationClosed {
+		reservation := *result.Reservation
+		go func() {
+			if err := s.emailService.SendReservationStatusUpdate(&reservation); err != nil {
+				zap.L().Error("CheckIn: Failed to send reservation returned email", zap.String("reservationID", reservation.ID.String()), zap.Error(err))
+			}
+		}()
+	}
+	return &result, nil
+}
+
+// findDeskCopy locks the copy with the scanned accession number, or with
+// the given ID when the code is a UUID
+func findDeskCopy(tx *gorm.DB, code string) (*models.BookCopy, error) {
+	code = strings.TrimSpace(code)
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if id, err := uuid.Parse(code); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("accession_number = ?", strings.ToUpper(code))
+	}
+
+	var copy models.BookCopy
+	if err := query.First(&copy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book copy not found")
+		}
+		return nil, err
+	}
+	return &copy, nil
+}
+
+// openReservationCopy finds the copy's row on a reservation with the given
+// status that it has not come back from yet. The zero value means none.
+func openReservationCopy(tx *gorm.DB, copyID uuid.UUID, status models.ReservationStatus) (reservationCopy, error) {
+	var row reservationCopy
+	err := tx.Table("reservation_book_copies rbc").
+		Select("rbc.reservation_id, rbc.checked_out_at, rbc.checked_in_at").
+		Joins("JOIN reservations r ON r.id = rbc.reservation_id AND r.deleted_at IS NULL").
+		Where("rbc.book_copy_id = ? AND r.status = ? AND rbc.checked_in_at IS NULL", copyID, status).
+		Order("r.pickup_time NULLS LAST, r.created_at").
+		Limit(1).
+		Scan(&row).Error
+	return row, err
+}
+
+func setDeskCopyStatus(tx *gorm.DB, copy *models.BookCopy, status models.BookCopyStatus, reason string, actor models.Actor) error {
+	before := *copy
+	if before.Status == status {
+		return nil
+	}
+	if err := tx.Model(&models.BookCopy{}).Where("id = ?", copy.ID).Update("status", status).Error; err != nil {
+		return err
+	}
+	if err := tx.First(copy, "id = ?", copy.ID).Error; err != nil {
+		return err
+	}
+	if err := recordCopyStatusChange(tx, copy.ID, before.Status, status, reason, actor); err != nil {
+		return err
+	}
+	return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBookCopies, copy.ID, &before, copy)
+}
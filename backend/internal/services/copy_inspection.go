@@ -0,0 +1,318 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Inspection photo limits
+const (
+	MaxInspectionPhotos        = 5
+	MaxInspectionPhotoBytes    = 10 << 20
+	inspectionPhotoWidth       = 1600
+	inspectionPhotoJPEGQuality = 85
+)
+
+// inspectionAttributionWindow is how long after a copy comes back an
+// inspection is attributed to its borrower without naming the reservation
+const inspectionAttributionWindow = 7 * 24 * time.Hour
+
+// ErrInvalidInspectionPhoto is returned for uploads that are not a usable photo
+var ErrInvalidInspectionPhoto = errors.New("invalid inspection photo")
+
+// CopyInspectionService records the condition copies are found in and traces
+// damage back to the patrons who borrowed them
+type CopyInspectionService struct {
+	db    *gorm.DB
+	store BlobStore
+}
+
+func NewCopyInspectionService(db *gorm.DB, store BlobStore) *CopyInspectionService {
+	return &CopyInspectionService{
+		db:    db,
+		store: store,
+	}
+}
+
+// InspectCopy records the condition of a copy and updates the copy to it.
+// The inspection is attributed to the given reservation, or by default to
+// the reservation the copy came back from within the last week, so that
+// damage found on return shows up in the borrower's history. Later
+// inspections are only attributed when the reservation is given.
+func (s *CopyInspectionService) InspectCopy(ctx context.Context, copyID string, req models.CopyInspectionRequest, photos []io.Reader, actor models.Actor) (*models.CopyInspection, error) {
+	if !req.Condition.Valid() {
+		return nil, FieldErrors{"condition": fmt.Sprintf("unknown condition %q", req.Condition)}
+	}
+	if len(photos) > MaxInspectionPhotos {
+		return nil, FieldErrors{"photos": fmt.Sprintf("at most %d photos can be attached", MaxInspectionPhotos)}
+	}
+	var reservationID *uuid.UUID
+	if req.ReservationID != "" {
+		id, err := uuid.Parse(req.ReservationID)
+		if err != nil {
+			return nil, FieldErrors{"reservation_id": "invalid reservation ID"}
+		}
+		reservationID = &id
+	}
+
+	// Photos are re-encoded before anything is stored, which also strips
+	// their metadata such as the location they were taken at
+	encoded := make([][]byte, 0, len(photos))
+	for i, photo := range photos {
+		data, err := encodeInspectionPhoto(photo)
+		if err != nil {
+			zap.L().Warn("InspectCopy: Rejected photo", zap.String("id", copyID), zap.Int("index", i), zap.Error(err))
+			return nil, err
+		}
+		encoded = append(encoded, data)
+	}
+
+	inspection := models.CopyInspection{
+		ID:             uuid.New(),
+		Condition:      req.Condition,
+		Notes:          strings.TrimSpace(req.Notes),
+		Photos:         []string{},
+		InspectorID:    actor.UserID,
+		InspectorEmail: actor.Email,
+	}
+
+	// Photos are stored before the transaction so that the copy is not kept
+	// locked while they upload
+	var found models.BookCopy
+	if err := s.db.Select("id").First(&found, "id = ?", copyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book copy not found")
+		}
+		zap.L().Error("InspectCopy: Failed to get book copy", zap.String("id", copyID), zap.Error(err))
+		return nil, err
+	}
+	var stored []string
+	for i, data := range encoded {
+		key := fmt.Sprintf("copy-inspections/%s/%s/%d.jpg", found.ID, inspection.ID, i+1)
+		if err := s.store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+			zap.L().Error("InspectCopy: Failed to store photo", zap.String("id", copyID), zap.String("key", key), zap.Error(err))
+			s.deleteBlobs(stored)
+			return nil, err
+		}
+		stored = append(stored, key)
+		inspection.Photos = append(inspection.Photos, s.store.URL(key))
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var copy models.BookCopy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&copy, "id = ?", copyID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("book copy not found")
+			}
+			return err
+		}
+
+		reservation, err := inspectedReservation(tx, &copy, reservationID)
+		if err != nil {
+			return err
+		}
+		if reservation != nil {
+			inspection.ReservationID = &reservation.ID
+			inspection.UserID = &reservation.UserID
+		}
+		inspection.BookCopyID = copy.ID
+		inspection.PreviousCondition = copy.Condition
+		inspection.Damaged = req.Damaged || req.Condition.WorseThan(copy.Condition)
+		if err := tx.Create(&inspection).Error; err != nil {
+			return err
+		}
+
+		if copy.Condition == req.Condition {
+			return nil
+		}
+		before := copy
+		if err := tx.Model(&models.BookCopy{}).Where("id = ?", copy.ID).Update("condition", req.Condition).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&copy, "id = ?", copy.ID).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBookCopies, copy.ID, &before, &copy)
+	})
+	if err != nil {
+		zap.L().Error("InspectCopy: Failed to record inspection", zap.String("id", copyID), zap.Error(err))
+		s.deleteBlobs(stored)
+		return nil, err
+	}
+	zap.L().Info("InspectCopy: Inspection recorded", zap.String("id", copyID), zap.String("condition", string(inspection.Condition)), zap.Bool("damaged", inspection.Damaged))
+	return &inspection, nil
+}
+
+// GetCopyInspections retrieves the condition timeline of a copy, oldest first
+func (s *CopyInspectionService) GetCopyInspections(copyID string) ([]models.CopyInspection, error) {
+	var copy models.BookCopy
+	if err := s.db.First(&copy, "id = ?", copyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book copy not found")
+		}
+		zap.L().Error("GetCopyInspections: Failed to get book copy", zap.String("id", copyID), zap.Error(err))
+		return nil, err
+	}
+	inspections := []models.CopyInspection{}
+	if err := s.db.Where("book_copy_id = ?", copy.ID).Order("created_at").Find(&inspections).Error; err != nil {
+		zap.L().Error("GetCopyInspections: Failed to get inspections", zap.String("id", copyID), zap.Error(err))
+		return nil, err
+	}
+	return inspections, nil
+}
+
+// GetUserDamageReports retrieves the inspections that found copies damaged
+// after the user borrowed them, newest first
+func (s *CopyInspectionService) GetUserDamageReports(userID string, page, limit int) ([]models.CopyInspection, int64, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, 0, errors.New("user not found")
+	}
+	var user models.User
+	if err := s.db.First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, errors.New("user not found")
+		}
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.CopyInspection{}).Where("user_id = ? AND damaged", id)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		zap.L().Error("GetUserDamageReports: Failed to count damage reports", zap.String("userID", userID), zap.Error(err))
+		return nil, 0, err
+	}
+	reports := []models.CopyInspection{}
+	if err := query.Preload("BookCopy", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("BookCopy.Book", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&reports).Error; err != nil {
+		zap.L().Error("GetUserDamageReports: Failed to get damage reports", zap.String("userID", userID), zap.Error(err))
+		return nil, 0, err
+	}
+	return reports, total, nil
+}
+
+// countDamageReports sets the number of damage reports of the users who made
+// the reservations
+func countDamageReports(db *gorm.DB, reservations []models.Reservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	userIDs := make([]uuid.UUID, len(reservations))
+	for i, reservation := range reservations {
+		userIDs[i] = reservation.UserID
+	}
+
+	var rows []struct {
+		UserID uuid.UUID
+		Count  int64
+	}
+	if err := db.Model(&models.CopyInspection{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("user_id IN ? AND damaged", userIDs).
+		Group("user_id").
+		Scan(&rows).Error; err != nil {
+		zap.L().Error("countDamageReports: Failed to count damage reports", zap.Error(err))
+		return err
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	for i := range reservations {
+		reservations[i].User.DamageReportCount = counts[reservations[i].UserID]
+	}
+	return nil
+}
+
+// inspectedReservation returns the reservation an inspection is about: the
+// given one, which must include the copy, or else the one the copy came back
+// from, if that was recent and the copy has stayed in since. Otherwise it is
+// nil, as the damage cannot be pinned on the last borrower.
+func inspectedReservation(tx *gorm.DB, copy *models.BookCopy, reservationID *uuid.UUID) (*models.Reservation, error) {
+	query := tx.Model(&models.Reservation{}).
+		Select("reservations.*").
+		Joins("JOIN reservation_book_copies rbc ON rbc.reservation_id = reservations.id").
+		Where("rbc.book_copy_id = ?", copy.ID)
+	if reservationID != nil {
+		query = query.Where("reservations.id = ?", *reservationID)
+	} else {
+		if copy.Status == models.BookCopyStatusReserved || copy.Status == models.BookCopyStatusBorrowed {
+			return nil, nil
+		}
+		query = query.Where("rbc.checked_in_at >= ?", time.Now().Add(-inspectionAttributionWindow)).
+			Order("rbc.checked_in_at DESC")
+	}
+
+	var reservations []models.Reservation
+	if err := query.Limit(1).Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	if len(reservations) == 0 {
+		if reservationID != nil {
+			return nil, FieldErrors{"reservation_id": "book copy is not on this reservation"}
+		}
+		return nil, nil
+	}
+	return &reservations[0], nil
+}
+
+// encodeInspectionPhoto checks an uploaded photo and re-encodes it as a JPEG
+// no wider than inspectionPhotoWidth
+func encodeInspectionPhoto(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxInspectionPhotoBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidInspectionPhoto)
+	}
+	if len(data) > MaxInspectionPhotoBytes {
+		return nil, fmt.Errorf("%w: file is larger than %d MB", ErrInvalidInspectionPhoto, MaxInspectionPhotoBytes>>20)
+	}
+	if contentType := http.DetectContentType(data); !coverContentTypes[contentType] {
+		return nil, fmt.Errorf("%w: unsupported file type %s", ErrInvalidInspectionPhoto, contentType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInspectionPhoto, err)
+	}
+	if cfg.Width*cfg.Height > maxCoverPixels {
+		return nil, fmt.Errorf("%w: image is too large (%dx%d)", ErrInvalidInspectionPhoto, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInspectionPhoto, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeToWidth(img, inspectionPhotoWidth), &jpeg.Options{Quality: inspectionPhotoJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deleteBlobs removes the photos of a failed inspection; a leftover file only costs storage
+func (s *CopyInspectionService) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(context.Background(), key); err != nil {
+			zap.L().Warn("deleteBlobs: Failed to delete blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestInspectedReservationOnlyAttributesRecentReturns(t *testing.T) {
+	tests := []struct {
+		status    models.BookCopyStatus
+		wantQuery bool
+	}{
+		{models.BookCopyStatusAvailable, true},
+		{models.BookCopyStatusDamaged, true},
+		{models.BookCopyStatusBorrowed, false},
+		{models.BookCopyStatusReserved, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			db, stub := newStubDB(t)
+			copy := &models.BookCopy{ID: uuid.New(), Status: tt.status}
+			reservation, err := inspectedReservation(db, copy, nil)
+			if err != nil || reservation != nil {
+				t.Fatalf("got %v, %v, want no reservation", reservation, err)
+			}
+
+			statements := stub.Statements()
+			if !tt.wantQuery {
+				if len(statements) != 0 {
+					t.Errorf("a copy that is out should not be attributed, got %q", statements)
+				}
+				return
+			}
+			if len(statements) != 1 || !strings.Contains(statements[0], "rbc.checked_in_at >= $") {
+				t.Errorf("got %q, want a lookup limited to recent check-ins", statements)
+			}
+		})
+	}
+}
+
+func TestInspectedReservationRequiresTheCopy(t *testing.T) {
+	db, _ := newStubDB(t)
+	reservationID := uuid.New()
+	_, err := inspectedReservation(db, &models.BookCopy{ID: uuid.New()}, &reservationID)
+	if fieldErrors, ok := err.(FieldErrors); !ok || fieldErrors["reservation_id"] == "" {
+		t.Errorf("got %v, want a reservation_id field error", err)
+	}
+}
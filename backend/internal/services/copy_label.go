@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"go.uber.org/zap"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// MaxLabelCopies caps the copies printed in one label sheet request
+const MaxLabelCopies = 240
+
+// Label sheet layout in millimetres: 24 labels of 70x37mm on an A4 page
+const (
+	labelColumns = 3
+	labelRows    = 8
+	labelWidth   = 70.0
+	labelHeight  = 37.0
+	labelTop     = (297.0 - labelRows*labelHeight) / 2
+	labelPadding = 3.0
+	labelQRSize  = 25.0
+)
+
+// CopyLabelService renders the barcodes and printable labels of book copies
+type CopyLabelService struct {
+	db *gorm.DB
+}
+
+func NewCopyLabelService(db *gorm.DB) *CopyLabelService {
+	return &CopyLabelService{db: db}
+}
+
+// RenderBarcode returns the Code128 barcode of an accession number as a PNG
+// image, scaled by the given factor from one pixel per module
+func RenderBarcode(value string, scale int) ([]byte, error) {
+	code, err := code128.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBarcodePNG(code, code.Bounds().Dx()*scale, 30*scale)
+}
+
+// GetBarcode renders the Code128 barcode of a copy's accession number
+func (s *CopyLabelService) GetBarcode(copyID string) ([]byte, error) {
+	var copy models.BookCopy
+	if err := s.db.First(&copy, "id = ?", copyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book copy not found")
+		}
+		zap.L().Error("GetBarcode: Failed to get book copy", zap.String("id", copyID), zap.Error(err))
+		return nil, err
+	}
+	image, err := RenderBarcode(copy.AccessionNumber, 2)
+	if err != nil {
+		zap.L().Error("GetBarcode: Failed to render barcode", zap.String("id", copyID), zap.Error(err))
+		return nil, err
+	}
+	return image, nil
+}
+
+// WriteLabelSheet writes a printable PDF of spine and inside cover labels
+// for the given copies, in the order given. Each label carries the book
+// title, the copy's accession number and barcode, and a QR code linking to
+// the book's page.
+func (s *CopyLabelService) WriteLabelSheet(w io.Writer, copyIDs []string) error {
+	if len(copyIDs) == 0 {
+		return FieldErrors{"copy_ids": "select at least one book copy"}
+	}
+	if len(copyIDs) > MaxLabelCopies {
+		return FieldErrors{"copy_ids": fmt.Sprintf("at most %d copies can be printed at once", MaxLabelCopies)}
+	}
+	ids := make([]uuid.UUID, 0, len(copyIDs))
+	for _, id := range copyIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return FieldErrors{"copy_ids": fmt.Sprintf("invalid copy ID %q", id)}
+		}
+		ids = append(ids, parsed)
+	}
+
+	var copies []models.BookCopy
+	if err := s.db.Preload("Book").Where("id IN ?", ids).Find(&copies).Error; err != nil {
+		zap.L().Error("WriteLabelSheet: Failed to get book copies", zap.Error(err))
+		return err
+	}
+	byID := make(map[uuid.UUID]*models.BookCopy, len(copies))
+	for i := range copies {
+		byID[copies[i].ID] = &copies[i]
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Book copy labels", true)
+	pdf.SetCreator(catalogName, true)
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, id := range ids {
+		copy, ok := byID[id]
+		if !ok {
+			return FieldErrors{"copy_ids": fmt.Sprintf("book copy %s not found", id)}
+		}
+		slot := i % (labelColumns * labelRows)
+		if slot == 0 {
+			pdf.AddPage()
+		}
+		x := float64(slot%labelColumns) * labelWidth
+		y := labelTop + float64(slot/labelColumns)*labelHeight
+		if err := drawCopyLabel(pdf, tr, copy, x, y); err != nil {
+			zap.L().Error("WriteLabelSheet: Failed to draw label", zap.String("id", id.String()), zap.Error(err))
+			return err
+		}
+	}
+
+	if err := pdf.Output(w); err != nil {
+		zap.L().Error("WriteLabelSheet: Failed to write PDF", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func drawCopyLabel(pdf *gofpdf.Fpdf, tr func(string) string, copy *models.BookCopy, x, y float64) error {
+	code, err := qr.Encode(BookPageURL(&copy.Book), qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+	qrImage, err := encodeBarcodePNG(code, 200, 200)
+	if err != nil {
+		return err
+	}
+	barcodeImage, err := RenderBarcode(copy.AccessionNumber, 3)
+	if err != nil {
+		return err
+	}
+
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	qrName, barcodeName := "qr-"+copy.ID.String(), "barcode-"+copy.ID.String()
+	pdf.RegisterImageOptionsReader(qrName, options, bytes.NewReader(qrImage))
+	pdf.RegisterImageOptionsReader(barcodeName, options, bytes.NewReader(barcodeImage))
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	pdf.ImageOptions(qrName, x+labelPadding, y+(labelHeight-labelQRSize)/2, labelQRSize, labelQRSize, false, options, 0, "")
+
+	// Title, accession number and barcode share the right of the label
+	textX := x + labelPadding*2 + labelQRSize
+	textWidth := labelWidth - labelQRSize - labelPadding*3
+	pdf.SetFont("Helvetica", "B", 8)
+	lines := pdf.SplitLines([]byte(tr(labelText(copy.Book.Title))), textWidth)
+	if len(lines) > 2 {
+		lines = append(lines[:1], append(bytes.TrimSpace(lines[1]), "..."...))
+	}
+	for i, line := range lines {
+		pdf.SetXY(textX, y+labelPadding+float64(i)*3.5)
+		pdf.CellFormat(textWidth, 3.5, string(line), "", 0, "L", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetXY(textX, y+labelPadding+8)
+	pdf.CellFormat(textWidth, 5, copy.AccessionNumber, "", 0, "L", false, 0, "")
+	pdf.ImageOptions(barcodeName, textX, y+labelPadding+14, textWidth, 12, false, options, 0, "")
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetXY(textX, y+labelPadding+27)
+	pdf.CellFormat(textWidth, 3, tr(catalogName), "", 0, "L", false, 0, "")
+	return pdf.Error()
+}
+
+func encodeBarcodePNG(code barcode.Barcode, width, height int) ([]byte, error) {
+	scaled, err := barcode.Scale(code, width, height)
+	if err != nil {
+		return nil, err
+	}
+	// Barcodes are 16-bit grayscale, which PDF documents cannot embed
+	gray := image.NewGray(scaled.Bounds())
+	draw.Draw(gray, gray.Bounds(), scaled, scaled.Bounds().Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// labelText strips the diacritics the built-in PDF fonts cannot show, e.g.
+// Vietnamese tone marks, keeping the Latin-1 letters they can
+func labelText(value string) string {
+	var b strings.Builder
+	for _, r := range norm.NFC.String(value) {
+		if r <= unicode.MaxLatin1 {
+			b.WriteRune(r)
+			continue
+		}
+		switch r {
+		case 'đ':
+			b.WriteRune('d')
+			continue
+		case 'Đ':
+			b.WriteRune('D')
+			continue
+		}
+		for _, base := range norm.NFD.String(string(r)) {
+			if !unicode.Is(unicode.Mn, base) {
+				b.WriteRune(base)
+			}
+		}
+	}
+	return b.String()
+}
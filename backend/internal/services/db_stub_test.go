@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stubDB is a database that finds no rows and accepts every write. It lets
+// service code run offline, and records the statements it was sent.
+type stubDB struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (s *stubDB) record(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements = append(s.statements, query)
+}
+
+// Statements returns the statements sent so far
+func (s *stubDB) Statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.statements...)
+}
+
+func newStubDB(t *testing.T) (*gorm.DB, *stubDB) {
+	t.Helper()
+	stub := &stubDB{}
+	sqlDB := sql.OpenDB(stubConnector{stub: stub})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open stub database: %v", err)
+	}
+	return db, stub
+}
+
+type stubConnector struct {
+	stub *stubDB
+}
+
+func (c stubConnector) Connect(context.Context) (driver.Conn, error) {
+	return stubConn{stub: c.stub}, nil
+}
+
+func (c stubConnector) Driver() driver.Driver {
+	return stubDriver{stub: c.stub}
+}
+
+type stubDriver struct {
+	stub *stubDB
+}
+
+func (d stubDriver) Open(string) (driver.Conn, error) {
+	return stubConn{stub: d.stub}, nil
+}
+
+type stubConn struct {
+	stub *stubDB
+}
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) {
+	return stubStmt{conn: c, query: query}, nil
+}
+
+func (c stubConn) Close() error {
+	return nil
+}
+
+func (c stubConn) Begin() (driver.Tx, error) {
+	return stubTx{}, nil
+}
+
+func (c stubConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c stubConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.stub.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c stubConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.stub.record(query)
+	return stubRows{}, nil
+}
+
+type stubStmt struct {
+	conn  stubConn
+	query string
+}
+
+func (s stubStmt) Close() error {
+	return nil
+}
+
+func (s stubStmt) NumInput() int {
+	return -1
+}
+
+func (s stubStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s stubStmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+type stubTx struct{}
+
+func (stubTx) Commit() error {
+	return nil
+}
+
+func (stubTx) Rollback() error {
+	return nil
+}
+
+type stubRows struct{}
+
+func (stubRows) Columns() []string {
+	return nil
+}
+
+func (stubRows) Close() error {
+	return nil
+}
+
+func (stubRows) Next([]driver.Value) error {
+	return io.EOF
+}
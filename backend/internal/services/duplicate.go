@@ -0,0 +1,460 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/hungcq/pscit/backend/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidMerge is returned when the records of a merge request are unusable
+var ErrInvalidMerge = errors.New("invalid merge request")
+
+// DefaultDuplicateThreshold is the trigram similarity above which two names
+// are reported as likely duplicates
+const DefaultDuplicateThreshold = 0.6
+
+// DuplicateCandidate is one record of a duplicate group
+type DuplicateCandidate struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	BookCount int64     `json:"book_count"`
+}
+
+// DuplicateGroup is a set of records whose names are likely the same entity.
+// SuggestedSurvivorID is the record with the most books, oldest first on ties.
+type DuplicateGroup struct {
+	// Exact is set when every name in the group folds to the same string
+	Exact               bool                 `json:"exact"`
+	Similarity          float64              `json:"similarity"`
+	SuggestedSurvivorID uuid.UUID            `json:"suggested_survivor_id"`
+	Candidates          []DuplicateCandidate `json:"candidates"`
+}
+
+// MergedRecord is a duplicate folded into the survivor of a merge
+type MergedRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	BookCount int64     `json:"book_count"`
+}
+
+// MergeReport describes the outcome of a merge
+type MergeReport struct {
+	Survivor DuplicateCandidate `json:"survivor"`
+	Merged   []MergedRecord     `json:"merged"`
+	// BooksRelinked counts books moved over to the survivor
+	BooksRelinked int64 `json:"books_relinked"`
+	// LinksDropped counts links removed because the book already had the survivor
+	LinksDropped int64 `json:"links_dropped"`
+	// FieldsFilled lists survivor fields copied from a duplicate because they were empty
+	FieldsFilled []string `json:"fields_filled"`
+}
+
+// duplicateEntity describes the tables behind a mergeable record type
+type duplicateEntity struct {
+	name      string
+	table     string
+	joinTable string
+	column    string
+	// textField is filled from a duplicate when empty on the survivor
+	textField string
+	auditType string
+	// load reads records by ID, trashed ones included, for audit snapshots
+	load func(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]interface{}, error)
+}
+
+var (
+	authorEntity = duplicateEntity{name: "author", table: "authors", joinTable: "book_authors", column: "author_id",
+		textField: "biography", auditType: models.AuditEntityAuthors, load: loadAuthorSnapshots}
+	categoryEntity = duplicateEntity{name: "category", table: "categories", joinTable: "book_categories", column: "category_id",
+		textField: "description", auditType: models.AuditEntityCategories, load: loadCategorySnapshots}
+)
+
+type DuplicateService struct {
+	db *gorm.DB
+}
+
+func NewDuplicateService(db *gorm.DB) *DuplicateService {
+	return &DuplicateService{db: db}
+}
+
+// FindDuplicateAuthors groups authors whose names match once case, accents and
+// whitespace are folded, or whose folded names are at least threshold similar
+func (s *DuplicateService) FindDuplicateAuthors(threshold float64) ([]DuplicateGroup, error) {
+	return s.findDuplicates(authorEntity, threshold)
+}
+
+// FindDuplicateCategories groups categories the same way as FindDuplicateAuthors
+func (s *DuplicateService) FindDuplicateCategories(threshold float64) ([]DuplicateGroup, error) {
+	return s.findDuplicates(categoryEntity, threshold)
+}
+
+// MergeAuthors moves the books of the duplicate authors to the survivor and
+// deletes the duplicates, all in one transaction
+func (s *DuplicateService) MergeAuthors(survivorID string, duplicateIDs []string, actor models.Actor) (*MergeReport, error) {
+	return s.merge(authorEntity, survivorID, duplicateIDs, actor)
+}
+
+// MergeCategories moves the books of the duplicate categories to the survivor
+// and deletes the duplicates, all in one transaction
+func (s *DuplicateService) MergeCategories(survivorID string, duplicateIDs []string, actor models.Actor) (*MergeReport, error) {
+	return s.merge(categoryEntity, survivorID, duplicateIDs, actor)
+}
+
+type duplicatePair struct {
+	IDA        uuid.UUID `gorm:"column:id_a"`
+	IDB        uuid.UUID `gorm:"column:id_b"`
+	Similarity float64
+	Exact      bool
+}
+
+func (s *DuplicateService) findDuplicates(entity duplicateEntity, threshold float64) ([]DuplicateGroup, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.New("threshold must be between 0 and 1")
+	}
+
+	var pairs []duplicatePair
+	var candidates []DuplicateCandidate
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lets the % operator use the trigram index at the requested threshold
+		if err := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', ?, true)", fmt.Sprint(threshold)).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(fmt.Sprintf(`
+			SELECT a.id AS id_a, b.id AS id_b,
+			       similarity(normalize_name(a.name), normalize_name(b.name)) AS similarity,
+			       normalize_name(a.name) = normalize_name(b.name) AS exact
+			FROM %[1]s a
+			JOIN %[1]s b ON a.id < b.id
+			 AND (normalize_name(a.name) = normalize_name(b.name) OR normalize_name(a.name) %% normalize_name(b.name))
+			WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL`, entity.table)).
+			Scan(&pairs).Error; err != nil {
+			return err
+		}
+		if len(pairs) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(pairs)*2)
+		for _, pair := range pairs {
+			ids = append(ids, pair.IDA, pair.IDB)
+		}
+		return tx.Raw(fmt.Sprintf(`
+			SELECT t.id, t.name, COUNT(j.book_id) AS book_count
+			FROM %s t
+			LEFT JOIN %s j ON j.%s = t.id
+			WHERE t.id IN ?
+			GROUP BY t.id, t.name, t.created_at
+			ORDER BY book_count DESC, t.created_at`, entity.table, entity.joinTable, entity.column), ids).
+			Scan(&candidates).Error
+	})
+	if err != nil {
+		zap.L().Error("findDuplicates: Failed to find duplicates", zap.String("entity", entity.name), zap.Error(err))
+		return nil, err
+	}
+
+	groups := groupDuplicates(pairs, candidates)
+	zap.L().Info("findDuplicates: Successfully found duplicates", zap.String("entity", entity.name), zap.Int("groups", len(groups)))
+	return groups, nil
+}
+
+// groupDuplicates joins overlapping pairs into groups. candidates must be
+// ordered by survivor preference.
+func groupDuplicates(pairs []duplicatePair, candidates []DuplicateCandidate) []DuplicateGroup {
+	parent := make(map[uuid.UUID]uuid.UUID)
+	var find func(id uuid.UUID) uuid.UUID
+	find = func(id uuid.UUID) uuid.UUID {
+		if p, ok := parent[id]; ok && p != id {
+			root := find(p)
+			parent[id] = root
+			return root
+		}
+		parent[id] = id
+		return id
+	}
+	for _, pair := range pairs {
+		parent[find(pair.IDA)] = find(pair.IDB)
+	}
+
+	byRoot := make(map[uuid.UUID]*DuplicateGroup)
+	var roots []uuid.UUID
+	for _, candidate := range candidates {
+		root := find(candidate.ID)
+		group, ok := byRoot[root]
+		if !ok {
+			// The first candidate of a group is the preferred survivor
+			group = &DuplicateGroup{Exact: true, SuggestedSurvivorID: candidate.ID}
+			byRoot[root] = group
+			roots = append(roots, root)
+		}
+		group.Candidates = append(group.Candidates, candidate)
+	}
+	for _, pair := range pairs {
+		group := byRoot[find(pair.IDA)]
+		if pair.Similarity > group.Similarity {
+			group.Similarity = pair.Similarity
+		}
+		group.Exact = group.Exact && pair.Exact
+	}
+
+	groups := make([]DuplicateGroup, 0, len(roots))
+	for _, root := range roots {
+		groups = append(groups, *byRoot[root])
+	}
+	// Exact matches first, then the most similar
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Exact != groups[j].Exact {
+			return groups[i].Exact
+		}
+		return groups[i].Similarity > groups[j].Similarity
+	})
+	return groups
+}
+
+type mergeRow struct {
+	ID        uuid.UUID
+	Name      string
+	Text      string
+	BookCount int64
+}
+
+func (s *DuplicateService) merge(entity duplicateEntity, survivorID string, duplicateIDs []string, actor models.Actor) (*MergeReport, error) {
+	survivorUUID, err := uuid.Parse(survivorID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid survivor ID %s", ErrInvalidMerge, survivorID)
+	}
+	var ids []uuid.UUID
+	seen := map[uuid.UUID]bool{survivorUUID: true}
+	for _, raw := range duplicateIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s ID %s", ErrInvalidMerge, entity.name, raw)
+		}
+		if id == survivorUUID {
+			return nil, fmt.Errorf("%w: the surviving %s cannot also be merged", ErrInvalidMerge, entity.name)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: at least one %s to merge is required", ErrInvalidMerge, entity.name)
+	}
+
+	report := &MergeReport{Merged: []MergedRecord{}, FieldsFilled: []string{}}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var rows []mergeRow
+		if err := tx.Raw(fmt.Sprintf(`
+			SELECT t.id, t.name, t.%[4]s AS text,
+			       (SELECT COUNT(*) FROM %[2]s j WHERE j.%[3]s = t.id) AS book_count
+			FROM %[1]s t
+			WHERE t.id IN ? AND t.deleted_at IS NULL
+			ORDER BY t.created_at
+			FOR UPDATE`, entity.table, entity.joinTable, entity.column, entity.textField),
+			append([]uuid.UUID{survivorUUID}, ids...)).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		var survivor *mergeRow
+		for i := range rows {
+			if rows[i].ID == survivorUUID {
+				survivor = &rows[i]
+			}
+		}
+		if survivor == nil {
+			return fmt.Errorf("%w: %s not found: %s", ErrInvalidMerge, entity.name, survivorID)
+		}
+		if len(rows) != len(ids)+1 {
+			return fmt.Errorf("%w: one or more %s IDs to merge were not found", ErrInvalidMerge, entity.name)
+		}
+
+		// Snapshot everything the merge touches so each change gets an audit entry
+		var bookIDs []uuid.UUID
+		if err := tx.Table(entity.joinTable).Distinct("book_id").Where(entity.column+" IN ?", ids).
+			Pluck("book_id", &bookIDs).Error; err != nil {
+			return err
+		}
+		booksBefore, err := loadBookSnapshots(tx, bookIDs)
+		if err != nil {
+			return err
+		}
+		updatedIDs := []uuid.UUID{survivorUUID}
+		if entity.table == categoryEntity.table {
+			var childIDs []uuid.UUID
+			if err := tx.Table(entity.table).Where("parent_id IN ? AND id NOT IN ?", ids, ids).
+				Pluck("id", &childIDs).Error; err != nil {
+				return err
+			}
+			updatedIDs = append(updatedIDs, childIDs...)
+		}
+		recordsBefore, err := entity.load(tx, append(updatedIDs, ids...))
+		if err != nil {
+			return err
+		}
+
+		// Link every book of the duplicates to the survivor, then drop the old links
+		relinked := tx.Exec(fmt.Sprintf(`
+			INSERT INTO %[1]s (book_id, %[2]s)
+			SELECT DISTINCT j.book_id, ?::uuid
+			FROM %[1]s j
+			WHERE j.%[2]s IN ?
+			  AND NOT EXISTS (SELECT 1 FROM %[1]s s WHERE s.book_id = j.book_id AND s.%[2]s = ?)`,
+			entity.joinTable, entity.column), survivorUUID, ids, survivorUUID)
+		if relinked.Error != nil {
+			return relinked.Error
+		}
+		removed := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s IN ?", entity.joinTable, entity.column), ids)
+		if removed.Error != nil {
+			return removed.Error
+		}
+		report.BooksRelinked = relinked.RowsAffected
+		report.LinksDropped = removed.RowsAffected - relinked.RowsAffected
+
+		if survivor.Text == "" {
+			for _, row := range rows {
+				if row.ID != survivorUUID && row.Text != "" {
+					if err := tx.Table(entity.table).Where("id = ?", survivorUUID).
+						Update(entity.textField, row.Text).Error; err != nil {
+						return err
+					}
+					report.FieldsFilled = append(report.FieldsFilled, entity.textField)
+					break
+				}
+			}
+		}
+
+		if entity.table == categoryEntity.table {
+			if err := reparentMergedCategories(tx, survivorUUID, ids); err != nil {
+				return err
+			}
+		}
+
+		// Merged records are gone for good, so their names can be reused
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN ?", entity.table), ids).Error; err != nil {
+			return err
+		}
+
+		booksAfter, err := loadBookSnapshots(tx, bookIDs)
+		if err != nil {
+			return err
+		}
+		for _, id := range bookIDs {
+			if err := recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, id, booksBefore[id], booksAfter[id]); err != nil {
+				return err
+			}
+		}
+		recordsAfter, err := entity.load(tx, updatedIDs)
+		if err != nil {
+			return err
+		}
+		for _, id := range updatedIDs {
+			if err := recordAudit(tx, actor, models.AuditActionUpdate, entity.auditType, id, recordsBefore[id], recordsAfter[id]); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			if err := recordAudit(tx, actor, models.AuditActionDelete, entity.auditType, id, recordsBefore[id], nil); err != nil {
+				return err
+			}
+		}
+
+		for _, row := range rows {
+			if row.ID == survivorUUID {
+				continue
+			}
+			report.Merged = append(report.Merged, MergedRecord{ID: row.ID, Name: row.Name, BookCount: row.BookCount})
+		}
+		report.Survivor = DuplicateCandidate{
+			ID:        survivor.ID,
+			Name:      survivor.Name,
+			BookCount: survivor.BookCount + report.BooksRelinked,
+		}
+		return nil
+	})
+	if err != nil {
+		zap.L().Error("merge: Failed to merge records", zap.String("entity", entity.name), zap.String("survivorID", survivorID), zap.Error(err))
+		return nil, err
+	}
+
+	zap.L().Info("merge: Successfully merged records", zap.String("entity", entity.name), zap.String("survivorID", survivorID),
+		zap.Int("merged", len(report.Merged)), zap.Int64("booksRelinked", report.BooksRelinked))
+	return report, nil
+}
+
+// reparentMergedCategories moves the subcategories of merged categories under
+// the survivor. Those that are ancestors of the survivor cannot go under it;
+// they and the survivor itself are lifted to the nearest ancestor that is not
+// being merged.
+func reparentMergedCategories(tx *gorm.DB, survivorID uuid.UUID, mergedIDs []uuid.UUID) error {
+	if err := tx.Exec(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		UPDATE categories SET parent_id = ?
+		WHERE parent_id IN ? AND id NOT IN (SELECT id FROM ancestors)`,
+		survivorID, survivorID, mergedIDs).Error; err != nil {
+		return err
+	}
+	// Each pass lifts the remaining children one level up
+	for {
+		result := tx.Exec(`
+			UPDATE categories c SET parent_id = merged.parent_id
+			FROM categories merged
+			WHERE c.parent_id = merged.id AND merged.id IN ?`, mergedIDs)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+	}
+}
+
+// loadBookSnapshots reads books with their associations, keyed by ID
+func loadBookSnapshots(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]*models.Book, error) {
+	snapshots := make(map[uuid.UUID]*models.Book, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+	var books []models.Book
+	if err := tx.Unscoped().Preload("Authors").Preload("Categories").Preload("Tags").
+		Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	for i := range books {
+		snapshots[books[i].ID] = &books[i]
+	}
+	return snapshots, nil
+}
+
+func loadAuthorSnapshots(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]interface{}, error) {
+	var authors []models.Author
+	if err := tx.Unscoped().Where("id IN ?", ids).Find(&authors).Error; err != nil {
+		return nil, err
+	}
+	snapshots := make(map[uuid.UUID]interface{}, len(authors))
+	for i := range authors {
+		snapshots[authors[i].ID] = &authors[i]
+	}
+	return snapshots, nil
+}
+
+func loadCategorySnapshots(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]interface{}, error) {
+	var categories []models.Category
+	if err := tx.Unscoped().Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	snapshots := make(map[uuid.UUID]interface{}, len(categories))
+	for i := range categories {
+		snapshots[categories[i].ID] = &categories[i]
+	}
+	return snapshots, nil
+}
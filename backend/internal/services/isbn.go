@@ -0,0 +1,372 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// FieldErrors maps request fields to the reason they were rejected
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + ": " + e[field]
+	}
+	return strings.Join(messages, "; ")
+}
+
+// cleanISBN strips the separators people commonly type into ISBNs
+func cleanISBN(isbn string) string {
+	isbn = strings.ToUpper(strings.TrimSpace(isbn))
+	return strings.NewReplacer("-", "", " ", "").Replace(isbn)
+}
+
+// NormalizeISBNs strips separators from both ISBNs, verifies their check
+// digits and derives whichever one is missing. An ISBN typed into the other
+// field is moved to the right one. The returned error is a FieldErrors keyed
+// by "isbn10" and "isbn13".
+func NormalizeISBNs(isbn10, isbn13 string) (string, string, error) {
+	isbn10, isbn13 = cleanISBN(isbn10), cleanISBN(isbn13)
+	if len(isbn10) == 13 && isbn13 == "" {
+		isbn10, isbn13 = "", isbn10
+	}
+	if len(isbn13) == 10 && isbn10 == "" {
+		isbn10, isbn13 = isbn13, ""
+	}
+
+	errs := FieldErrors{}
+	if isbn10 != "" {
+		if err := checkISBN10(isbn10); err != nil {
+			errs["isbn10"] = err.Error()
+		}
+	}
+	if isbn13 != "" {
+		if err := checkISBN13(isbn13); err != nil {
+			errs["isbn13"] = err.Error()
+		}
+	}
+	if len(errs) > 0 {
+		return "", "", errs
+	}
+
+	switch {
+	case isbn10 != "" && isbn13 == "":
+		isbn13 = isbn10To13(isbn10)
+	case isbn13 != "" && isbn10 == "":
+		// 979 ISBNs have no ISBN-10 form
+		isbn10, _ = isbn13To10(isbn13)
+	case isbn10 != "" && isbn13 != "":
+		if isbn10To13(isbn10) != isbn13 {
+			return "", "", FieldErrors{"isbn10": "does not match isbn13 " + isbn13}
+		}
+	}
+	return isbn10, isbn13, nil
+}
+
+// normalizeSingleISBN validates an ISBN of either length and returns the
+// ISBN-10 and ISBN-13 pair it belongs to
+func normalizeSingleISBN(isbn string) (string, string, error) {
+	isbn = cleanISBN(isbn)
+	switch len(isbn) {
+	case 10:
+		if err := checkISBN10(isbn); err != nil {
+			return "", "", err
+		}
+	case 13:
+		if err := checkISBN13(isbn); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", fmt.Errorf("must have 10 or 13 characters, got %d", len(isbn))
+	}
+	isbn10, isbn13, _ := NormalizeISBNs(isbn, "")
+	return isbn10, isbn13, nil
+}
+
+// normalizeBookISBNs normalizes the ISBNs of book in place, storing empty ones as NULL
+func normalizeBookISBNs(book *models.Book) error {
+	isbn10, isbn13, err := NormalizeISBNs(stringValue(book.ISBN10), stringValue(book.ISBN13))
+	if err != nil {
+		return err
+	}
+	book.ISBN10, book.ISBN13 = optionalString(isbn10), optionalString(isbn13)
+	return nil
+}
+
+// checkISBNConflicts reports the ISBNs of book that a book other than id
+// already uses. Soft-deleted books are included since they still hold the
+// unique index.
+func checkISBNConflicts(db *gorm.DB, id uuid.UUID, book *models.Book) error {
+	errs := FieldErrors{}
+	for field, isbn := range map[string]*string{"isbn10": book.ISBN10, "isbn13": book.ISBN13} {
+		if isbn == nil {
+			continue
+		}
+		var other models.Book
+		err := db.Unscoped().Select("id", "title", "deleted_at").
+			Where(field+" = ? AND id <> ?", *isbn, id).
+			First(&other).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			zap.L().Error("checkISBNConflicts: Failed to check ISBN", zap.String("isbn", *isbn), zap.Error(err))
+			return err
+		}
+		errs[field] = fmt.Sprintf("already used by %q (%s)", other.Title, other.ID)
+		if other.DeletedAt.Valid {
+			errs[field] += ", which is in the trash"
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isISBNConflict reports whether err is a violation of the unique ISBN
+// indexes, which a concurrent write can still cause after checkISBNConflicts
+func isISBNConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return false
+	}
+	return pgErr.ConstraintName == "idx_books_isbn10" || pgErr.ConstraintName == "idx_books_isbn13"
+}
+
+// explainISBNConflict turns a violation of the unique ISBN indexes into the
+// FieldErrors checkISBNConflicts would have returned, once the conflicting
+// write is committed
+func explainISBNConflict(db *gorm.DB, id uuid.UUID, book *models.Book, err error) error {
+	if !isISBNConflict(err) {
+		return err
+	}
+	if conflictErr := checkISBNConflicts(db, id, book); conflictErr != nil {
+		return conflictErr
+	}
+	return err
+}
+
+func checkISBN10(isbn string) error {
+	if len(isbn) != 10 {
+		return fmt.Errorf("must have 10 characters, got %d", len(isbn))
+	}
+	sum := 0
+	for i, r := range isbn {
+		digit := int(r - '0')
+		switch {
+		case r == 'X' && i == 9:
+			digit = 10
+		case r < '0' || r > '9':
+			return errors.New("must contain only digits, with an optional final X")
+		}
+		sum += (10 - i) * digit
+	}
+	if sum%11 != 0 {
+		return errors.New("invalid check digit")
+	}
+	return nil
+}
+
+func checkISBN13(isbn string) error {
+	if len(isbn) != 13 {
+		return fmt.Errorf("must have 13 digits, got %d", len(isbn))
+	}
+	if !strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979") {
+		return errors.New("must start with 978 or 979")
+	}
+	sum := 0
+	for i, r := range isbn {
+		if r < '0' || r > '9' {
+			return errors.New("must contain only digits")
+		}
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+	if sum%10 != 0 {
+		return errors.New("invalid check digit")
+	}
+	return nil
+}
+
+// isbn10To13 converts a valid ISBN-10 to its 978-prefixed ISBN-13
+func isbn10To13(isbn string) string {
+	body := "978" + isbn[:9]
+	sum := 0
+	for i, r := range body {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+	return fmt.Sprintf("%s%d", body, (10-sum%10)%10)
+}
+
+// isbn13To10 converts a valid 978-prefixed ISBN-13 to its ISBN-10
+func isbn13To10(isbn string) (string, bool) {
+	if !strings.HasPrefix(isbn, "978") {
+		return "", false
+	}
+	body := isbn[3:12]
+	sum := 0
+	for i, r := range body {
+		sum += (10 - i) * int(r-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", true
+	}
+	return fmt.Sprintf("%s%d", body, check), true
+}
+
+func sameISBN(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// ISBNNormalizationIssue is a book the normalization job could not update
+type ISBNNormalizationIssue struct {
+	BookID uuid.UUID `json:"book_id"`
+	Title  string    `json:"title"`
+	ISBN10 *string   `json:"isbn10"`
+	ISBN13 *string   `json:"isbn13"`
+	// ConflictingBookID is the book already holding the normalized ISBN
+	ConflictingBookID *uuid.UUID `json:"conflicting_book_id,omitempty"`
+	Message           string     `json:"message"`
+}
+
+// ISBNNormalizationReport summarises a run of NormalizeStoredISBNs
+type ISBNNormalizationReport struct {
+	DryRun    bool                     `json:"dry_run"`
+	Scanned   int                      `json:"scanned"`
+	Updated   int                      `json:"updated"`
+	Unchanged int                      `json:"unchanged"`
+	Invalid   []ISBNNormalizationIssue `json:"invalid"`
+	Conflicts []ISBNNormalizationIssue `json:"conflicts"`
+}
+
+// NormalizeStoredISBNs rewrites the ISBNs of every book, soft-deleted ones
+// included, into their normalized form. Books with an invalid ISBN, or whose
+// normalized ISBN would collide with another book, are left untouched and
+// reported so they can be fixed by hand. Each rewrite is recorded in the
+// audit log.
+func (s *BookService) NormalizeStoredISBNs(dryRun bool, actor models.Actor) (*ISBNNormalizationReport, error) {
+	var books []models.Book
+	if err := s.db.Unscoped().Select("id", "title", "isbn10", "isbn13").Order("created_at").Find(&books).Error; err != nil {
+		zap.L().Error("NormalizeStoredISBNs: Failed to load books", zap.Error(err))
+		return nil, err
+	}
+
+	report := &ISBNNormalizationReport{
+		DryRun:    dryRun,
+		Scanned:   len(books),
+		Invalid:   []ISBNNormalizationIssue{},
+		Conflicts: []ISBNNormalizationIssue{},
+	}
+	issue := func(book models.Book, message string) ISBNNormalizationIssue {
+		return ISBNNormalizationIssue{BookID: book.ID, Title: book.Title, ISBN10: book.ISBN10, ISBN13: book.ISBN13, Message: message}
+	}
+
+	// A normalized ISBN may only be taken if no other book holds it yet, so
+	// every current value is claimed up front. Values freed by this run become
+	// available on the next one.
+	owners := make(map[string]uuid.UUID)
+	claim := func(column string, isbn *string, id uuid.UUID) {
+		if isbn != nil {
+			owners[column+":"+*isbn] = id
+		}
+	}
+	for _, book := range books {
+		claim("isbn10", book.ISBN10, book.ID)
+		claim("isbn13", book.ISBN13, book.ID)
+	}
+
+	type rewrite struct {
+		before, after models.Book
+	}
+	var changed []rewrite
+	for _, book := range books {
+		candidate := book
+		if err := normalizeBookISBNs(&candidate); err != nil {
+			report.Invalid = append(report.Invalid, issue(book, err.Error()))
+			continue
+		}
+		if sameISBN(candidate.ISBN10, book.ISBN10) && sameISBN(candidate.ISBN13, book.ISBN13) {
+			report.Unchanged++
+			continue
+		}
+
+		var owner *uuid.UUID
+		for column, isbn := range map[string]*string{"isbn10": candidate.ISBN10, "isbn13": candidate.ISBN13} {
+			if isbn == nil {
+				continue
+			}
+			if id, ok := owners[column+":"+*isbn]; ok && id != book.ID {
+				owner = &id
+			}
+		}
+		if owner != nil {
+			entry := issue(book, "normalized ISBN is already used by another book")
+			entry.ConflictingBookID = owner
+			report.Conflicts = append(report.Conflicts, entry)
+			continue
+		}
+		claim("isbn10", candidate.ISBN10, book.ID)
+		claim("isbn13", candidate.ISBN13, book.ID)
+		changed = append(changed, rewrite{before: book, after: candidate})
+	}
+	report.Updated = len(changed)
+
+	if !dryRun && len(changed) > 0 {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			for _, change := range changed {
+				book := change.after
+				if err := tx.Unscoped().Model(&models.Book{}).Where("id = ?", book.ID).
+					UpdateColumns(map[string]interface{}{"isbn10": book.ISBN10, "isbn13": book.ISBN13}).Error; err != nil {
+					return err
+				}
+				if err := recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, book.ID, &change.before, &book); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			zap.L().Error("NormalizeStoredISBNs: Failed to update books", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	zap.L().Info("NormalizeStoredISBNs: Finished normalizing ISBNs", zap.Bool("dryRun", dryRun),
+		zap.Int("scanned", report.Scanned), zap.Int("updated", report.Updated),
+		zap.Int("invalid", len(report.Invalid)), zap.Int("conflicts", len(report.Conflicts)))
+	return report, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// orphanGracePeriod keeps freshly created authors and categories, which are
+// usually about to be linked to a book, out of the orphan cleanup
+const orphanGracePeriod = "1 day"
+
+type MaintenanceService struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceService(db *gorm.DB) *MaintenanceService {
+	return &MaintenanceService{db: db}
+}
+
+// CleanOrphanedRecords deletes join rows pointing at missing books, authors or
+// categories, then authors and categories no book refers to. Categories with
+// subcategories are kept, and trashed ones are left to TrashService.PurgeExpired.
+// Each deleted author and category gets a delete entry in the audit log.
+func (s *MaintenanceService) CleanOrphanedRecords(ctx context.Context) (int64, error) {
+	statements := []struct {
+		name string
+		sql  string
+		// auditType is set for the entities whose deletions are audited
+		auditType string
+	}{
+		{"book_authors", `DELETE FROM book_authors ba
+			WHERE NOT EXISTS (SELECT 1 FROM books b WHERE b.id = ba.book_id)
+			   OR NOT EXISTS (SELECT 1 FROM authors a WHERE a.id = ba.author_id)`, ""},
+		{"book_categories", `DELETE FROM book_categories bc
+			WHERE NOT EXISTS (SELECT 1 FROM books b WHERE b.id = bc.book_id)
+			   OR NOT EXISTS (SELECT 1 FROM categories c WHERE c.id = bc.category_id)`, ""},
+		{"authors", `DELETE FROM authors a
+			WHERE a.deleted_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM book_authors ba WHERE ba.author_id = a.id)
+			  AND a.created_at < NOW() - INTERVAL '` + orphanGracePeriod + `'`, models.AuditEntityAuthors},
+		{"categories", `DELETE FROM categories c
+			WHERE c.deleted_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM book_categories bc WHERE bc.category_id = c.id)
+			  AND NOT EXISTS (SELECT 1 FROM categories child WHERE child.parent_id = c.id)
+			  AND c.created_at < NOW() - INTERVAL '` + orphanGracePeriod + `'`, models.AuditEntityCategories},
+	}
+
+	var total int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if statement.auditType == "" {
+				result := tx.Exec(statement.sql)
+				if result.Error != nil {
+					zap.L().Error("CleanOrphanedRecords: Failed to delete orphans", zap.String("table", statement.name), zap.Error(result.Error))
+					return result.Error
+				}
+				zap.L().Info("CleanOrphanedRecords: Deleted orphans", zap.String("table", statement.name), zap.Int64("rows", result.RowsAffected))
+				total += result.RowsAffected
+				continue
+			}
+
+			var ids []uuid.UUID
+			if err := tx.Raw(statement.sql + " RETURNING id").Scan(&ids).Error; err != nil {
+				zap.L().Error("CleanOrphanedRecords: Failed to delete orphans", zap.String("table", statement.name), zap.Error(err))
+				return err
+			}
+			for _, id := range ids {
+				if err := recordAudit(tx, models.Actor{}, models.AuditActionDelete, statement.auditType, id, nil, nil); err != nil {
+					return err
+				}
+			}
+			zap.L().Info("CleanOrphanedRecords: Deleted orphans", zap.String("table", statement.name), zap.Int("rows", len(ids)))
+			total += int64(len(ids))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
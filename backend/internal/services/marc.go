@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Binary MARC21 (ISO 2709) delimiters
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+	marcLeaderLength      = 24
+	marcDirectoryEntryLen = 12
+)
+
+// marcField is a single variable field. Control fields (tags 001-009) only
+// carry Value; data fields carry indicators and subfields.
+type marcField struct {
+	Tag        string
+	Value      string
+	Indicators string
+	Subfields  []marcSubfield
+}
+
+type marcSubfield struct {
+	Code  byte
+	Value string
+}
+
+type marcRecord struct {
+	Leader string
+	Fields []marcField
+}
+
+// subfield returns the first value of code in the first field with tag
+func (r *marcRecord) subfield(tag string, code byte) string {
+	for _, field := range r.Fields {
+		if field.Tag != tag {
+			continue
+		}
+		for _, sf := range field.Subfields {
+			if sf.Code == code {
+				return sf.Value
+			}
+		}
+	}
+	return ""
+}
+
+// subfields returns every value of code across all fields with tag
+func (r *marcRecord) subfields(tag string, code byte) []string {
+	var values []string
+	for _, field := range r.Fields {
+		if field.Tag != tag {
+			continue
+		}
+		for _, sf := range field.Subfields {
+			if sf.Code == code {
+				values = append(values, sf.Value)
+			}
+		}
+	}
+	return values
+}
+
+func (r *marcRecord) controlField(tag string) string {
+	for _, field := range r.Fields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// marcReader reads consecutive binary MARC21 records from a stream
+type marcReader struct {
+	r *bufio.Reader
+}
+
+func newMARCReader(r io.Reader) *marcReader {
+	return &marcReader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF when the stream is exhausted
+func (m *marcReader) Read() (*marcRecord, error) {
+	raw, err := m.r.ReadBytes(marcRecordTerminator)
+	if err == io.EOF {
+		// Tolerate trailing whitespace/newlines after the last record
+		if len(bytes.TrimSpace(raw)) == 0 {
+			return nil, io.EOF
+		}
+		return nil, errors.New("truncated MARC record")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseMARCRecord(bytes.TrimLeft(raw, "\r\n "))
+}
+
+func parseMARCRecord(raw []byte) (*marcRecord, error) {
+	if len(raw) < marcLeaderLength+1 {
+		return nil, errors.New("MARC record shorter than its leader")
+	}
+	leader := string(raw[:marcLeaderLength])
+	baseAddress, err := strconv.Atoi(leader[12:17])
+	if err != nil || baseAddress <= marcLeaderLength || baseAddress > len(raw) {
+		return nil, fmt.Errorf("invalid MARC base address %q", leader[12:17])
+	}
+
+	record := &marcRecord{Leader: leader}
+	directory := raw[marcLeaderLength : baseAddress-1]
+	for i := 0; i+marcDirectoryEntryLen <= len(directory); i += marcDirectoryEntryLen {
+		entry := string(directory[i : i+marcDirectoryEntryLen])
+		length, errLen := strconv.Atoi(entry[3:7])
+		start, errStart := strconv.Atoi(entry[7:12])
+		// Atoi also accepts signed numbers such as "-001"
+		if errLen != nil || errStart != nil || length < 0 || start < 0 {
+			return nil, fmt.Errorf("invalid MARC directory entry %q", entry)
+		}
+		begin := baseAddress + start
+		end := begin + length
+		if begin > end || end > len(raw) {
+			return nil, fmt.Errorf("MARC field %s overruns the record", entry[:3])
+		}
+		data := bytes.TrimRight(raw[begin:end], string([]byte{marcFieldTerminator}))
+		record.Fields = append(record.Fields, parseMARCField(entry[:3], data))
+	}
+	return record, nil
+}
+
+func parseMARCField(tag string, data []byte) marcField {
+	field := marcField{Tag: tag}
+	if tag < "010" {
+		field.Value = string(data)
+		return field
+	}
+
+	parts := bytes.Split(data, []byte{marcSubfieldDelimiter})
+	field.Indicators = string(parts[0])
+	for _, part := range parts[1:] {
+		if len(part) == 0 {
+			continue
+		}
+		field.Subfields = append(field.Subfields, marcSubfield{
+			Code:  part[0],
+			Value: strings.TrimSpace(string(part[1:])),
+		})
+	}
+	return field
+}
+
+// marcWriter encodes records as binary MARC21
+type marcWriter struct {
+	w io.Writer
+}
+
+func newMARCWriter(w io.Writer) *marcWriter {
+	return &marcWriter{w: w}
+}
+
+func (m *marcWriter) Write(record *marcRecord) error {
+	var directory, data bytes.Buffer
+	for _, field := range record.Fields {
+		start := data.Len()
+		if field.Tag < "010" {
+			data.WriteString(field.Value)
+		} else {
+			indicators := field.Indicators
+			if len(indicators) != 2 {
+				indicators = "  "
+			}
+			data.WriteString(indicators)
+			for _, sf := range field.Subfields {
+				data.WriteByte(marcSubfieldDelimiter)
+				data.WriteByte(sf.Code)
+				data.WriteString(sf.Value)
+			}
+		}
+		data.WriteByte(marcFieldTerminator)
+		fmt.Fprintf(&directory, "%s%04d%05d", field.Tag, data.Len()-start, start)
+	}
+	directory.WriteByte(marcFieldTerminator)
+	data.WriteByte(marcRecordTerminator)
+
+	baseAddress := marcLeaderLength + directory.Len()
+	recordLength := baseAddress + data.Len()
+	if recordLength > 99999 {
+		return errors.New("MARC record exceeds 99999 bytes")
+	}
+
+	leader := []byte(record.Leader)
+	if len(leader) != marcLeaderLength {
+		leader = []byte("00000nam a2200000 i 4500")
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", recordLength))
+	copy(leader[12:17], fmt.Sprintf("%05d", baseAddress))
+
+	for _, chunk := range [][]byte{leader, directory.Bytes(), data.Bytes()} {
+		if _, err := m.w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func encodeMARC(t *testing.T, records ...*marcRecord) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := newMARCWriter(&buf)
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("write MARC record: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func sampleMARCRecord() *marcRecord {
+	return &marcRecord{
+		Fields: []marcField{
+			{Tag: "001", Value: "ocm12345"},
+			{Tag: "020", Indicators: "  ", Subfields: []marcSubfield{{Code: 'a', Value: "9780132350884"}}},
+			{Tag: "245", Indicators: "10", Subfields: []marcSubfield{
+				{Code: 'a', Value: "Clean code :"},
+				{Code: 'b', Value: "a handbook of agile software craftsmanship /"},
+			}},
+		},
+	}
+}
+
+func TestMARCRoundTrip(t *testing.T) {
+	record := sampleMARCRecord()
+	reader := newMARCReader(bytes.NewReader(encodeMARC(t, record, record)))
+
+	for i := 0; i < 2; i++ {
+		got, err := reader.Read()
+		if err != nil {
+			t.Fatalf("record %d: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(got.Fields, record.Fields) {
+			t.Errorf("record %d: got fields %+v, want %+v", i+1, got.Fields, record.Fields)
+		}
+		if got.controlField("001") != "ocm12345" || got.subfield("245", 'b') != "a handbook of agile software craftsmanship /" {
+			t.Errorf("record %d: lookups do not match the written fields", i+1)
+		}
+	}
+	if _, err := reader.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v after the last record, want io.EOF", err)
+	}
+}
+
+func TestMARCReaderMalformed(t *testing.T) {
+	valid := encodeMARC(t, sampleMARCRecord())
+	// The first directory entry starts right after the leader
+	entry := func(field string) []byte {
+		raw := append([]byte(nil), valid...)
+		copy(raw[marcLeaderLength:marcLeaderLength+marcDirectoryEntryLen], field)
+		return raw
+	}
+	leader := func(base string) []byte {
+		raw := append([]byte(nil), valid...)
+		copy(raw[12:17], base)
+		return raw
+	}
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr string
+	}{
+		{"shorter than leader", []byte("00010nam\x1d"), "MARC record shorter than its leader"},
+		{"truncated", valid[:len(valid)-1], "truncated MARC record"},
+		{"non-numeric base address", leader("abcde"), "invalid MARC base address"},
+		{"base address inside leader", leader("00010"), "invalid MARC base address"},
+		{"base address past the end", leader("99999"), "invalid MARC base address"},
+		{"negative base address", leader("-0030"), "invalid MARC base address"},
+		{"non-numeric entry", entry("001abcd00000"), "invalid MARC directory entry"},
+		{"signed length", entry("001-00100000"), "invalid MARC directory entry"},
+		{"signed start", entry("0010009-0001"), "invalid MARC directory entry"},
+		{"field overruns record", entry("001999900000"), "MARC field 001 overruns the record"},
+		{"start overruns record", entry("001000199999"), "MARC field 001 overruns the record"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newMARCReader(bytes.NewReader(tt.input)).Read()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMARCReaderSkipsTrailingWhitespace(t *testing.T) {
+	input := append(encodeMARC(t, sampleMARCRecord()), "\r\n"...)
+	reader := newMARCReader(bytes.NewReader(input))
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := reader.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
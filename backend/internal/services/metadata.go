@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/config"
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// ErrMetadataNotFound is returned by a MetadataProvider that has no record for an ISBN
+var ErrMetadataNotFound = errors.New("no metadata found for ISBN")
+
+// BookMetadata is the bibliographic record a MetadataProvider returns for an ISBN
+type BookMetadata struct {
+	Source         string            `json:"source"`
+	Title          string            `json:"title"`
+	Subtitle       string            `json:"subtitle"`
+	Description    string            `json:"description"`
+	ISBN10         string            `json:"isbn10"`
+	ISBN13         string            `json:"isbn13"`
+	PublishedYear  int               `json:"published_year"`
+	PageCount      int               `json:"page_count"`
+	Publisher      string            `json:"publisher"`
+	GoogleVolumeID string            `json:"google_volume_id"`
+	MainImage      string            `json:"main_image"`
+	Language       string            `json:"language"`
+	Format         models.BookFormat `json:"format"`
+	Authors        []string          `json:"authors"`
+	Categories     []string          `json:"categories"`
+}
+
+// MetadataProvider looks up book metadata by ISBN from an external catalog
+type MetadataProvider interface {
+	Name() string
+	LookupISBN(ctx context.Context, isbn string) (*BookMetadata, error)
+}
+
+// metadataParser turns a provider's raw response body into BookMetadata
+type metadataParser func(isbn string, body []byte) (*BookMetadata, error)
+
+// NewMetadataProviders returns the configured providers in lookup order. When
+// METADATA_FIXTURE_DIR is set, recorded responses are read from that directory
+// instead of calling the real APIs.
+func NewMetadataProviders() []MetadataProvider {
+	if dir := config.AppConfig.MetadataFixtureDir; dir != "" {
+		return []MetadataProvider{
+			NewFixtureMetadataProvider(googleBooksProviderName, filepath.Join(dir, googleBooksProviderName), parseGoogleBooksResponse),
+			NewFixtureMetadataProvider(openLibraryProviderName, filepath.Join(dir, openLibraryProviderName), parseOpenLibraryResponse),
+		}
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	return []MetadataProvider{
+		NewGoogleBooksProvider(client, config.AppConfig.GoogleBooksAPIKey),
+		NewOpenLibraryProvider(client),
+	}
+}
+
+const (
+	googleBooksProviderName = "google_books"
+	openLibraryProviderName = "open_library"
+)
+
+// GoogleBooksProvider looks up ISBNs with the Google Books volumes API
+type GoogleBooksProvider struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+func NewGoogleBooksProvider(client *http.Client, apiKey string) *GoogleBooksProvider {
+	return &GoogleBooksProvider{
+		client:  client,
+		apiKey:  apiKey,
+		baseURL: "https://www.googleapis.com/books/v1/volumes",
+	}
+}
+
+func (p *GoogleBooksProvider) Name() string {
+	return googleBooksProviderName
+}
+
+func (p *GoogleBooksProvider) LookupISBN(ctx context.Context, isbn string) (*BookMetadata, error) {
+	params := url.Values{}
+	params.Set("q", "isbn:"+isbn)
+	if p.apiKey != "" {
+		params.Set("key", p.apiKey)
+	}
+	body, err := fetchMetadata(ctx, p.client, p.baseURL+"?"+params.Encode())
+	if err != nil {
+		zap.L().Error("GoogleBooksProvider: Failed to fetch volume", zap.String("isbn", isbn), zap.Error(err))
+		return nil, err
+	}
+	return parseGoogleBooksResponse(isbn, body)
+}
+
+type googleBooksResponse struct {
+	Items []struct {
+		ID         string `json:"id"`
+		VolumeInfo struct {
+			Title               string   `json:"title"`
+			Subtitle            string   `json:"subtitle"`
+			Authors             []string `json:"authors"`
+			Publisher           string   `json:"publisher"`
+			PublishedDate       string   `json:"publishedDate"`
+			Description         string   `json:"description"`
+			PageCount           int      `json:"pageCount"`
+			Categories          []string `json:"categories"`
+			Language            string   `json:"language"`
+			IndustryIdentifiers []struct {
+				Type       string `json:"type"`
+				Identifier string `json:"identifier"`
+			} `json:"industryIdentifiers"`
+			ImageLinks struct {
+				Thumbnail string `json:"thumbnail"`
+			} `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+func parseGoogleBooksResponse(isbn string, body []byte) (*BookMetadata, error) {
+	var resp googleBooksResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid Google Books response: %w", err)
+	}
+	if len(resp.Items) == 0 {
+		return nil, ErrMetadataNotFound
+	}
+
+	item := resp.Items[0]
+	info := item.VolumeInfo
+	metadata := &BookMetadata{
+		Source:         googleBooksProviderName,
+		Title:          info.Title,
+		Subtitle:       info.Subtitle,
+		Description:    stripHTMLTags(info.Description),
+		PublishedYear:  parseYear(info.PublishedDate),
+		PageCount:      info.PageCount,
+		Publisher:      info.Publisher,
+		GoogleVolumeID: item.ID,
+		MainImage:      info.ImageLinks.Thumbnail,
+		Language:       normalizeLanguage(info.Language),
+		Format:         models.FormatPaperback,
+		Authors:        info.Authors,
+		Categories:     info.Categories,
+	}
+	for _, id := range info.IndustryIdentifiers {
+		switch id.Type {
+		case "ISBN_10":
+			metadata.ISBN10 = id.Identifier
+		case "ISBN_13":
+			metadata.ISBN13 = id.Identifier
+		}
+	}
+	return metadata, nil
+}
+
+// OpenLibraryProvider looks up ISBNs with the Open Library books API
+type OpenLibraryProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewOpenLibraryProvider(client *http.Client) *OpenLibraryProvider {
+	return &OpenLibraryProvider{
+		client:  client,
+		baseURL: "https://openlibrary.org/api/books",
+	}
+}
+
+func (p *OpenLibraryProvider) Name() string {
+	return openLibraryProviderName
+}
+
+func (p *OpenLibraryProvider) LookupISBN(ctx context.Context, isbn string) (*BookMetadata, error) {
+	params := url.Values{}
+	params.Set("bibkeys", "ISBN:"+isbn)
+	params.Set("format", "json")
+	params.Set("jscmd", "data")
+	body, err := fetchMetadata(ctx, p.client, p.baseURL+"?"+params.Encode())
+	if err != nil {
+		zap.L().Error("OpenLibraryProvider: Failed to fetch book", zap.String("isbn", isbn), zap.Error(err))
+		return nil, err
+	}
+	return parseOpenLibraryResponse(isbn, body)
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+type openLibraryBook struct {
+	Title         string            `json:"title"`
+	Subtitle      string            `json:"subtitle"`
+	Authors       []openLibraryName `json:"authors"`
+	Publishers    []openLibraryName `json:"publishers"`
+	PublishDate   string            `json:"publish_date"`
+	NumberOfPages int               `json:"number_of_pages"`
+	Subjects      []openLibraryName `json:"subjects"`
+	Identifiers   struct {
+		ISBN10 []string `json:"isbn_10"`
+		ISBN13 []string `json:"isbn_13"`
+	} `json:"identifiers"`
+	Cover struct {
+		Medium string `json:"medium"`
+		Large  string `json:"large"`
+	} `json:"cover"`
+}
+
+// openLibrarySubjectLimit keeps only the broadest subjects; Open Library
+// often lists dozens of very specific ones
+const openLibrarySubjectLimit = 3
+
+func parseOpenLibraryResponse(isbn string, body []byte) (*BookMetadata, error) {
+	var resp map[string]openLibraryBook
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid Open Library response: %w", err)
+	}
+	book, ok := resp["ISBN:"+isbn]
+	if !ok {
+		return nil, ErrMetadataNotFound
+	}
+
+	metadata := &BookMetadata{
+		Source:        openLibraryProviderName,
+		Title:         book.Title,
+		Subtitle:      book.Subtitle,
+		PublishedYear: parseYear(book.PublishDate),
+		PageCount:     book.NumberOfPages,
+		MainImage:     book.Cover.Large,
+		Format:        models.FormatPaperback,
+	}
+	if metadata.MainImage == "" {
+		metadata.MainImage = book.Cover.Medium
+	}
+	if len(book.Publishers) > 0 {
+		metadata.Publisher = book.Publishers[0].Name
+	}
+	if len(book.Identifiers.ISBN10) > 0 {
+		metadata.ISBN10 = book.Identifiers.ISBN10[0]
+	}
+	if len(book.Identifiers.ISBN13) > 0 {
+		metadata.ISBN13 = book.Identifiers.ISBN13[0]
+	}
+	for _, author := range book.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	for i, subject := range book.Subjects {
+		if i == openLibrarySubjectLimit {
+			break
+		}
+		metadata.Categories = append(metadata.Categories, subject.Name)
+	}
+	return metadata, nil
+}
+
+// FixtureMetadataProvider serves recorded provider responses from disk, one
+// <isbn>.json file per ISBN, so imports can run without network access
+type FixtureMetadataProvider struct {
+	name  string
+	dir   string
+	parse metadataParser
+}
+
+func NewFixtureMetadataProvider(name, dir string, parse metadataParser) *FixtureMetadataProvider {
+	return &FixtureMetadataProvider{
+		name:  name,
+		dir:   dir,
+		parse: parse,
+	}
+}
+
+func (p *FixtureMetadataProvider) Name() string {
+	return p.name
+}
+
+func (p *FixtureMetadataProvider) LookupISBN(ctx context.Context, isbn string) (*BookMetadata, error) {
+	body, err := os.ReadFile(filepath.Join(p.dir, isbn+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrMetadataNotFound
+		}
+		return nil, err
+	}
+	return p.parse(isbn, body)
+}
+
+func fetchMetadata(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+func stripHTMLTags(input string) string {
+	return strings.Join(strings.Fields(htmlTagPattern.ReplaceAllString(input, " ")), " ")
+}
+
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// parseYear extracts the year from dates such as "2008", "2008-08-01" or "August 1, 2008"
+func parseYear(date string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(date))
+	return year
+}
+
+// normalizeLanguage maps provider language codes to the two-letter codes stored on books
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimPrefix(language, "/languages/"))
+	switch language {
+	case "eng":
+		return "en"
+	case "vie":
+		return "vi"
+	}
+	if len(language) == 2 {
+		return language
+	}
+	return ""
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+)
+
+const metadataFixtureDir = "testdata/metadata"
+
+func readMetadataFixture(t *testing.T, provider, isbn string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join(metadataFixtureDir, provider, isbn+".json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return body
+}
+
+func fixtureMetadataProviders() []MetadataProvider {
+	return []MetadataProvider{
+		NewFixtureMetadataProvider(googleBooksProviderName, filepath.Join(metadataFixtureDir, googleBooksProviderName), parseGoogleBooksResponse),
+		NewFixtureMetadataProvider(openLibraryProviderName, filepath.Join(metadataFixtureDir, openLibraryProviderName), parseOpenLibraryResponse),
+	}
+}
+
+func TestParseGoogleBooksResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		isbn    string
+		body    []byte
+		want    *BookMetadata
+		wantErr error
+	}{
+		{
+			name: "volume",
+			isbn: "9780132350884",
+			body: readMetadataFixture(t, googleBooksProviderName, "9780132350884"),
+			want: &BookMetadata{
+				Source:         googleBooksProviderName,
+				Title:          "Clean Code",
+				Subtitle:       "A Handbook of Agile Software Craftsmanship",
+				Description:    "Even bad code can function. But if code isn't clean, it can bring a development organization to its knees. Noted software expert Robert C. Martin presents a revolutionary paradigm with Clean Code: A Handbook of Agile Software Craftsmanship .",
+				ISBN10:         "0132350882",
+				ISBN13:         "9780132350884",
+				PublishedYear:  2008,
+				PageCount:      464,
+				Publisher:      "Pearson Education",
+				GoogleVolumeID: "hjEFCAAAQBAJ",
+				MainImage:      "http://books.google.com/books/content?id=hjEFCAAAQBAJ&printsec=frontcover&img=1&zoom=1&source=gbs_api",
+				Language:       "en",
+				Format:         models.FormatPaperback,
+				Authors:        []string{"Robert C. Martin"},
+				Categories:     []string{"Computers"},
+			},
+		},
+		{
+			name:    "no items",
+			isbn:    "9780000000002",
+			body:    []byte(`{"kind": "books#volumes", "totalItems": 0}`),
+			wantErr: ErrMetadataNotFound,
+		},
+		{
+			name:    "invalid JSON",
+			isbn:    "9780132350884",
+			body:    []byte(`<html>`),
+			wantErr: errors.New("invalid Google Books response"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGoogleBooksResponse(tt.isbn, tt.body)
+			checkMetadata(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseOpenLibraryResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		isbn    string
+		body    []byte
+		want    *BookMetadata
+		wantErr error
+	}{
+		{
+			name: "book",
+			isbn: "9780262033848",
+			body: readMetadataFixture(t, openLibraryProviderName, "9780262033848"),
+			want: &BookMetadata{
+				Source:        openLibraryProviderName,
+				Title:         "Introduction to algorithms",
+				ISBN10:        "0262033844",
+				ISBN13:        "9780262033848",
+				PublishedYear: 2009,
+				PageCount:     1292,
+				Publisher:     "MIT Press",
+				MainImage:     "https://covers.openlibrary.org/b/id/6979861-L.jpg",
+				Format:        models.FormatPaperback,
+				Authors:       []string{"Thomas H. Cormen", "Charles E. Leiserson", "Ronald L. Rivest", "Clifford Stein"},
+				Categories:    []string{"Computer programming", "Computer algorithms"},
+			},
+		},
+		{
+			name: "subjects are capped",
+			isbn: "9780132350884",
+			body: readMetadataFixture(t, openLibraryProviderName, "9780132350884"),
+			want: &BookMetadata{
+				Source:        openLibraryProviderName,
+				Title:         "Clean code",
+				Subtitle:      "a handbook of agile software craftsmanship",
+				ISBN10:        "0132350882",
+				ISBN13:        "9780132350884",
+				PublishedYear: 2009,
+				PageCount:     431,
+				Publisher:     "Prentice Hall",
+				MainImage:     "https://covers.openlibrary.org/b/id/9641987-L.jpg",
+				Format:        models.FormatPaperback,
+				Authors:       []string{"Robert C. Martin"},
+				Categories:    []string{"Agile software development", "Computer software", "Reliability"},
+			},
+		},
+		{
+			name: "medium cover without large",
+			isbn: "9780000000002",
+			body: []byte(`{"ISBN:9780000000002": {"title": "Untitled", "cover": {"medium": "https://example.com/m.jpg"}}}`),
+			want: &BookMetadata{
+				Source:    openLibraryProviderName,
+				Title:     "Untitled",
+				MainImage: "https://example.com/m.jpg",
+				Format:    models.FormatPaperback,
+			},
+		},
+		{
+			name:    "other ISBN",
+			isbn:    "9780132350884",
+			body:    readMetadataFixture(t, openLibraryProviderName, "9780262033848"),
+			wantErr: ErrMetadataNotFound,
+		},
+		{
+			name:    "empty response",
+			isbn:    "9780132350884",
+			body:    []byte(`{}`),
+			wantErr: ErrMetadataNotFound,
+		},
+		{
+			name:    "invalid JSON",
+			isbn:    "9780132350884",
+			body:    []byte(`[]`),
+			wantErr: errors.New("invalid Open Library response"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOpenLibraryResponse(tt.isbn, tt.body)
+			checkMetadata(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+// checkMetadata compares a parse result. A wantErr that is not a sentinel is
+// matched on its message prefix.
+func checkMetadata(t *testing.T, got *BookMetadata, err error, want *BookMetadata, wantErr error) {
+	t.Helper()
+	if wantErr != nil {
+		if err == nil {
+			t.Fatalf("got %+v, want error %v", got, wantErr)
+		}
+		if !errors.Is(err, wantErr) && !strings.HasPrefix(err.Error(), wantErr.Error()) {
+			t.Fatalf("got error %v, want %v", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestImportISBNs(t *testing.T) {
+	tests := []struct {
+		name       string
+		isbns      []string
+		provider   string
+		wantStatus []ImportStatus
+		wantSource []string
+		wantTitle  []string
+	}{
+		{
+			name:       "first provider with a record wins",
+			isbns:      []string{"978-0-13-235088-4"},
+			wantStatus: []ImportStatus{ImportStatusCreated},
+			wantSource: []string{googleBooksProviderName},
+			wantTitle:  []string{"Clean Code"},
+		},
+		{
+			name:       "falls back to the next provider",
+			isbns:      []string{"9780262033848"},
+			wantStatus: []ImportStatus{ImportStatusCreated},
+			wantSource: []string{openLibraryProviderName},
+			wantTitle:  []string{"Introduction to algorithms"},
+		},
+		{
+			name:       "only the requested provider",
+			isbns:      []string{"9780132350884"},
+			provider:   openLibraryProviderName,
+			wantStatus: []ImportStatus{ImportStatusCreated},
+			wantSource: []string{openLibraryProviderName},
+			wantTitle:  []string{"Clean code"},
+		},
+		{
+			name:       "missing and invalid ISBNs",
+			isbns:      []string{"9780306406157", "9780132350885"},
+			wantStatus: []ImportStatus{ImportStatusNotFound, ImportStatusError},
+			wantSource: []string{"", ""},
+			wantTitle:  []string{"", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := newStubDB(t)
+			service := NewBookImportService(db, fixtureMetadataProviders())
+
+			results, err := service.ImportISBNs(context.Background(), tt.isbns, tt.provider, models.Actor{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(tt.isbns) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.isbns))
+			}
+			for i, result := range results {
+				if result.Status != tt.wantStatus[i] {
+					t.Errorf("%s: got status %q (%s), want %q", tt.isbns[i], result.Status, result.Error, tt.wantStatus[i])
+				}
+				if result.Source != tt.wantSource[i] {
+					t.Errorf("%s: got source %q, want %q", tt.isbns[i], result.Source, tt.wantSource[i])
+				}
+				title := ""
+				if result.Book != nil {
+					title = result.Book.Title
+				}
+				if title != tt.wantTitle[i] {
+					t.Errorf("%s: got title %q, want %q", tt.isbns[i], title, tt.wantTitle[i])
+				}
+			}
+		})
+	}
+}
+
+func TestImportISBNsNormalizesISBNs(t *testing.T) {
+	db, _ := newStubDB(t)
+	service := NewBookImportService(db, fixtureMetadataProviders())
+
+	results, err := service.ImportISBNs(context.Background(), []string{"978-0-13-235088-4"}, "", models.Actor{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	book := results[0].Book
+	if book == nil {
+		t.Fatalf("got status %q (%s), want a book", results[0].Status, results[0].Error)
+	}
+	if book.ISBN10 == nil || *book.ISBN10 != "0132350882" || book.ISBN13 == nil || *book.ISBN13 != "9780132350884" {
+		t.Errorf("got ISBNs %v/%v, want 0132350882/9780132350884", book.ISBN10, book.ISBN13)
+	}
+	if len(book.Authors) != 1 || book.Authors[0].Name != "Robert C. Martin" {
+		t.Errorf("got authors %+v, want Robert C. Martin", book.Authors)
+	}
+}
+
+func TestImportISBNsUnknownProvider(t *testing.T) {
+	db, _ := newStubDB(t)
+	service := NewBookImportService(db, fixtureMetadataProviders())
+
+	if _, err := service.ImportISBNs(context.Background(), []string{"9780132350884"}, "worldcat", models.Actor{}); err == nil {
+		t.Fatal("got no error for an unknown provider")
+	}
+}
+
+func TestImportISBNsRecordsAudit(t *testing.T) {
+	db, stub := newStubDB(t)
+	service := NewBookImportService(db, fixtureMetadataProviders())
+
+	results, err := service.ImportISBNs(context.Background(), []string{"9780132350884"}, "", models.Actor{Email: "librarian@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	book := results[0].Book
+	if book == nil {
+		t.Fatalf("got status %q (%s), want a book", results[0].Status, results[0].Error)
+	}
+
+	// One entry for the book and one for each author, category and tag it created
+	want := 1 + len(book.Authors) + len(book.Categories) + len(book.Tags)
+	got := 0
+	for _, statement := range stub.Statements() {
+		if strings.HasPrefix(statement, `INSERT INTO "audit_logs"`) {
+			got++
+		}
+	}
+	if got != want {
+		t.Errorf("got %d audit entries, want %d", got, want)
+	}
+}
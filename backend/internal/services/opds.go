@@ -0,0 +1,427 @@
+package services
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/config"
+	"github.com/hungcq/pscit/backend/internal/models"
+)
+
+// OPDS media types
+const (
+	OPDSNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	OPDSAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OPDS2Type           = "application/opds+json"
+)
+
+// catalogName is the library's name as shown on the website
+const catalogName = "PSciT Library"
+
+// OPDS link relations
+const (
+	opdsRelBorrow    = "http://opds-spec.org/acquisition/borrow"
+	opdsRelImage     = "http://opds-spec.org/image"
+	opdsRelThumbnail = "http://opds-spec.org/image/thumbnail"
+	opdsRelNew       = "http://opds-spec.org/sort/new"
+)
+
+// OPDSFeed is a catalog feed ready to be written as OPDS 1.2 or OPDS 2.0.
+// A feed with Navigation entries is a navigation feed, any other one is an
+// acquisition feed listing Books.
+type OPDSFeed struct {
+	// ID is a stable, unique path of the feed, e.g. "/categories/<id>"
+	ID         string
+	Title      string
+	Updated    time.Time
+	Links      OPDSLinks
+	Navigation []OPDSNavigationEntry
+	Books      []ExportedBook
+	// Total is the number of books across all pages of an acquisition feed
+	Total int64
+}
+
+// OPDSLinks holds the hrefs of a feed and its neighbours; empty ones are left out
+type OPDSLinks struct {
+	Self     string
+	Start    string
+	Up       string
+	First    string
+	Previous string
+	Next     string
+}
+
+// OPDSNavigationEntry links to another feed of the catalog
+type OPDSNavigationEntry struct {
+	ID      string
+	Title   string
+	Summary string
+	Href    string
+	Updated time.Time
+	// Acquisition is set when the linked feed lists books rather than more feeds
+	Acquisition bool
+	// New marks the new arrivals feed
+	New bool
+}
+
+// BookPageURL is the address of a book's page on the website
+func BookPageURL(book *models.Book) string {
+	return strings.TrimRight(config.AppConfig.SiteURL, "/") + "/books/" + book.ID.String()
+}
+
+// bookCoverURLs returns the full size and thumbnail covers of a book,
+// preferring uploaded covers over the imported image
+func bookCoverURLs(book *models.Book) (string, string) {
+	image, thumbnail := book.MainImage, book.MainImage
+	if url := book.CoverImages["large"]; url != "" {
+		image = url
+	}
+	if url := book.CoverImages["small"]; url != "" {
+		thumbnail = url
+	}
+	return image, thumbnail
+}
+
+// bookIdentifiers lists the URNs a book is known by
+func bookIdentifiers(book *models.Book) []string {
+	identifiers := []string{"urn:uuid:" + book.ID.String()}
+	for _, isbn := range []*string{book.ISBN13, book.ISBN10} {
+		if isbn != nil && *isbn != "" {
+			identifiers = append(identifiers, "urn:isbn:"+*isbn)
+		}
+	}
+	return identifiers
+}
+
+// feedTimestamp keeps empty feeds from claiming they were updated in year 1
+func feedTimestamp(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// atomFeed is an Atom feed with the Dublin Core and OPDS extensions used by
+// OPDS 1.2 catalogs
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	XmlnsDC   string      `xml:"xmlns:dc,attr,omitempty"`
+	XmlnsOPDS string      `xml:"xmlns:opds,attr,omitempty"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID          string         `xml:"id"`
+	Title       string         `xml:"title"`
+	Updated     string         `xml:"updated"`
+	Published   string         `xml:"published,omitempty"`
+	Authors     []atomPerson   `xml:"author"`
+	Identifiers []string       `xml:"dc:identifier"`
+	Language    string         `xml:"dc:language,omitempty"`
+	Publisher   string         `xml:"dc:publisher,omitempty"`
+	Issued      string         `xml:"dc:issued,omitempty"`
+	Categories  []atomCategory `xml:"category"`
+	Summary     *atomText      `xml:"summary,omitempty"`
+	Content     *atomText      `xml:"content,omitempty"`
+	Links       []atomLink     `xml:"link"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomLink struct {
+	Rel          string            `xml:"rel,attr,omitempty"`
+	Href         string            `xml:"href,attr"`
+	Type         string            `xml:"type,attr,omitempty"`
+	Title        string            `xml:"title,attr,omitempty"`
+	Availability *opdsAvailability `xml:"opds:availability,omitempty"`
+	Copies       *opdsCopies       `xml:"opds:copies,omitempty"`
+}
+
+type opdsAvailability struct {
+	Status string `xml:"status,attr"`
+}
+
+type opdsCopies struct {
+	Total     int64 `xml:"total,attr"`
+	Available int64 `xml:"available,attr"`
+}
+
+// WriteOPDS1 writes the feed as an OPDS 1.2 Atom document
+func WriteOPDS1(w io.Writer, feed *OPDSFeed) error {
+	doc := atomFeed{
+		Xmlns:     "http://www.w3.org/2005/Atom",
+		XmlnsDC:   "http://purl.org/dc/terms/",
+		XmlnsOPDS: "http://opds-spec.org/2010/catalog",
+		ID:        "urn:pscit:opds" + feed.ID,
+		Title:     feed.Title,
+		Updated:   feedTimestamp(feed.Updated).UTC().Format(time.RFC3339),
+		Author:    &atomPerson{Name: catalogName, URI: config.AppConfig.SiteURL},
+		Entries:   []atomEntry{},
+	}
+
+	feedType := OPDSAcquisitionType
+	if feed.Navigation != nil {
+		feedType = OPDSNavigationType
+	}
+	for _, link := range []struct{ rel, href, linkType string }{
+		{"self", feed.Links.Self, feedType},
+		{"start", feed.Links.Start, OPDSNavigationType},
+		{"up", feed.Links.Up, OPDSNavigationType},
+		{"first", feed.Links.First, feedType},
+		{"previous", feed.Links.Previous, feedType},
+		{"next", feed.Links.Next, feedType},
+	} {
+		if link.href != "" {
+			doc.Links = append(doc.Links, atomLink{Rel: link.rel, Href: link.href, Type: link.linkType})
+		}
+	}
+
+	for _, entry := range feed.Navigation {
+		link := atomLink{Rel: "subsection", Href: entry.Href, Type: OPDSNavigationType}
+		if entry.Acquisition {
+			link.Type = OPDSAcquisitionType
+		}
+		if entry.New {
+			link.Rel = opdsRelNew
+		}
+		navEntry := atomEntry{
+			ID:      "urn:pscit:opds" + entry.ID,
+			Title:   entry.Title,
+			Updated: feedTimestamp(entry.Updated).UTC().Format(time.RFC3339),
+			Links:   []atomLink{link},
+		}
+		if entry.Summary != "" {
+			navEntry.Content = &atomText{Type: "text", Body: entry.Summary}
+		}
+		doc.Entries = append(doc.Entries, navEntry)
+	}
+
+	for i := range feed.Books {
+		doc.Entries = append(doc.Entries, opds1BookEntry(&feed.Books[i]))
+	}
+
+	return writeXML(w, doc)
+}
+
+func opds1BookEntry(book *ExportedBook) atomEntry {
+	entry := atomEntry{
+		ID:          "urn:uuid:" + book.ID.String(),
+		Title:       bookFeedTitle(book),
+		Updated:     book.UpdatedAt.UTC().Format(time.RFC3339),
+		Published:   book.CreatedAt.UTC().Format(time.RFC3339),
+		Identifiers: bookIdentifiers(&book.Book),
+		Language:    book.Language,
+		Publisher:   book.Publisher,
+	}
+	if book.PublishedYear > 0 {
+		entry.Issued = strconv.Itoa(book.PublishedYear)
+	}
+	for _, author := range book.Authors {
+		entry.Authors = append(entry.Authors, atomPerson{Name: author.Name})
+	}
+	for _, category := range book.Categories {
+		entry.Categories = append(entry.Categories, atomCategory{Term: category.Name, Label: category.Name})
+	}
+	for _, tag := range book.Tags {
+		entry.Categories = append(entry.Categories, atomCategory{Term: tag.Key, Label: tag.Name})
+	}
+	if book.Description != "" {
+		entry.Summary = &atomText{Type: "text", Body: book.Description}
+	}
+
+	image, thumbnail := bookCoverURLs(&book.Book)
+	if image != "" {
+		entry.Links = append(entry.Links,
+			atomLink{Rel: opdsRelImage, Href: image, Type: "image/jpeg"},
+			atomLink{Rel: opdsRelThumbnail, Href: thumbnail, Type: "image/jpeg"},
+		)
+	}
+	// Books are lent in person, so acquiring one means reserving it on the website
+	pageURL := BookPageURL(&book.Book)
+	status := "available"
+	if book.Copies.Available == 0 {
+		status = "unavailable"
+	}
+	entry.Links = append(entry.Links,
+		atomLink{Rel: "alternate", Href: pageURL, Type: "text/html", Title: book.Title},
+		atomLink{
+			Rel:          opdsRelBorrow,
+			Href:         pageURL,
+			Type:         "text/html",
+			Availability: &opdsAvailability{Status: status},
+			Copies:       &opdsCopies{Total: book.Copies.Total, Available: book.Copies.Available},
+		},
+	)
+	return entry
+}
+
+// opds2Feed is an OPDS 2.0 feed
+type opds2Feed struct {
+	Metadata     opds2FeedMetadata  `json:"metadata"`
+	Links        []opds2Link        `json:"links"`
+	Navigation   []opds2Link        `json:"navigation,omitempty"`
+	Publications []opds2Publication `json:"publications,omitempty"`
+}
+
+type opds2FeedMetadata struct {
+	Title         string `json:"title"`
+	Modified      string `json:"modified"`
+	NumberOfItems *int64 `json:"numberOfItems,omitempty"`
+}
+
+type opds2Link struct {
+	Rel        string           `json:"rel,omitempty"`
+	Href       string           `json:"href"`
+	Type       string           `json:"type,omitempty"`
+	Title      string           `json:"title,omitempty"`
+	Properties *opds2Properties `json:"properties,omitempty"`
+}
+
+type opds2Properties struct {
+	NumberOfItems *int64             `json:"numberOfItems,omitempty"`
+	Availability  *opds2Availability `json:"availability,omitempty"`
+}
+
+type opds2Availability struct {
+	State string `json:"state"`
+}
+
+type opds2Publication struct {
+	Metadata opds2PublicationMetadata `json:"metadata"`
+	Links    []opds2Link              `json:"links"`
+	Images   []opds2Link              `json:"images,omitempty"`
+}
+
+type opds2PublicationMetadata struct {
+	Type        string         `json:"@type"`
+	Identifier  string         `json:"identifier"`
+	Title       string         `json:"title"`
+	Subtitle    string         `json:"subtitle,omitempty"`
+	Author      []opds2Contrib `json:"author,omitempty"`
+	Publisher   string         `json:"publisher,omitempty"`
+	Language    string         `json:"language,omitempty"`
+	Published   string         `json:"published,omitempty"`
+	Modified    string         `json:"modified"`
+	Description string         `json:"description,omitempty"`
+	Subject     []opds2Contrib `json:"subject,omitempty"`
+}
+
+type opds2Contrib struct {
+	Name string `json:"name"`
+	Code string `json:"code,omitempty"`
+}
+
+// OPDS2Document returns the feed as an OPDS 2.0 document, ready to be encoded as JSON
+func OPDS2Document(feed *OPDSFeed) interface{} {
+	doc := opds2Feed{
+		Metadata: opds2FeedMetadata{
+			Title:    feed.Title,
+			Modified: feedTimestamp(feed.Updated).UTC().Format(time.RFC3339),
+		},
+	}
+	for _, link := range []struct{ rel, href string }{
+		{"self", feed.Links.Self},
+		{"start", feed.Links.Start},
+		{"up", feed.Links.Up},
+		{"first", feed.Links.First},
+		{"previous", feed.Links.Previous},
+		{"next", feed.Links.Next},
+	} {
+		if link.href != "" {
+			doc.Links = append(doc.Links, opds2Link{Rel: link.rel, Href: link.href, Type: OPDS2Type})
+		}
+	}
+
+	for _, entry := range feed.Navigation {
+		link := opds2Link{Href: entry.Href, Type: OPDS2Type, Title: entry.Title}
+		if entry.New {
+			link.Rel = opdsRelNew
+		}
+		doc.Navigation = append(doc.Navigation, link)
+	}
+	if feed.Navigation == nil {
+		doc.Metadata.NumberOfItems = &feed.Total
+	}
+	for i := range feed.Books {
+		doc.Publications = append(doc.Publications, opds2BookPublication(&feed.Books[i]))
+	}
+	return doc
+}
+
+func opds2BookPublication(book *ExportedBook) opds2Publication {
+	// Prefer an ISBN, which readers can match against other catalogs
+	identifiers := bookIdentifiers(&book.Book)
+	identifier := identifiers[0]
+	if len(identifiers) > 1 {
+		identifier = identifiers[1]
+	}
+	publication := opds2Publication{
+		Metadata: opds2PublicationMetadata{
+			Type:        "http://schema.org/Book",
+			Identifier:  identifier,
+			Title:       book.Title,
+			Subtitle:    book.Subtitle,
+			Publisher:   book.Publisher,
+			Language:    book.Language,
+			Modified:    book.UpdatedAt.UTC().Format(time.RFC3339),
+			Description: book.Description,
+		},
+	}
+	if book.PublishedYear > 0 {
+		publication.Metadata.Published = strconv.Itoa(book.PublishedYear)
+	}
+	for _, author := range book.Authors {
+		publication.Metadata.Author = append(publication.Metadata.Author, opds2Contrib{Name: author.Name})
+	}
+	for _, category := range book.Categories {
+		publication.Metadata.Subject = append(publication.Metadata.Subject, opds2Contrib{Name: category.Name})
+	}
+	for _, tag := range book.Tags {
+		publication.Metadata.Subject = append(publication.Metadata.Subject, opds2Contrib{Name: tag.Name, Code: tag.Key})
+	}
+
+	image, thumbnail := bookCoverURLs(&book.Book)
+	if image != "" {
+		publication.Images = []opds2Link{
+			{Href: image, Type: "image/jpeg"},
+			{Href: thumbnail, Type: "image/jpeg", Rel: "thumbnail"},
+		}
+	}
+	state := "available"
+	if book.Copies.Available == 0 {
+		state = "unavailable"
+	}
+	pageURL := BookPageURL(&book.Book)
+	publication.Links = []opds2Link{
+		{Rel: "alternate", Href: pageURL, Type: "text/html"},
+		{
+			Rel:        opdsRelBorrow,
+			Href:       pageURL,
+			Type:       "text/html",
+			Properties: &opds2Properties{Availability: &opds2Availability{State: state}},
+		},
+	}
+	return publication
+}
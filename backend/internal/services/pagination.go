@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded or that were
+// issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const defaultCursorLimit = 10
+
+// CursorPage holds the cursors of the pages around a keyset-paginated result.
+// A nil cursor means there is nothing further in that direction.
+type CursorPage struct {
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+}
+
+// pageCursor is the decoded form of an opaque cursor. It points at the row on
+// the edge of a page; the next page starts after it, or ends before it when
+// Before is set.
+type pageCursor struct {
+	Sort   string    `json:"s"`
+	Key    string    `json:"k"`
+	ID     uuid.UUID `json:"id"`
+	Before bool      `json:"b,omitempty"`
+}
+
+// keyset orders a listing by a sort expression with the row ID as tie-breaker
+// and pages through it by comparing against the cursor row instead of using
+// an offset
+type keyset struct {
+	// sort names the ordering so cursors cannot be replayed against another one
+	sort     string
+	expr     string
+	idColumn string
+	desc     bool
+	cursor   *pageCursor
+	key      interface{}
+}
+
+// newKeyset decodes raw, which is empty for the first page. name identifies
+// the sort field; timeKey is set when expr is a timestamp, whose cursor keys
+// are RFC 3339 strings.
+func newKeyset(raw, name, expr, idColumn string, desc, timeKey bool) (*keyset, error) {
+	sort := name + ":asc"
+	if desc {
+		sort = name + ":desc"
+	}
+	k := &keyset{
+		sort:     sort,
+		expr:     expr,
+		idColumn: idColumn,
+		desc:     desc,
+	}
+	if raw == "" {
+		return k, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != k.sort {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+	k.cursor = &cursor
+	k.key = cursor.Key
+	if timeKey {
+		if k.key, err = time.Parse(time.RFC3339Nano, cursor.Key); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return k, nil
+}
+
+// apply adds the cursor condition, ordering and limit to query. One row more
+// than limit is fetched to tell whether another page follows.
+func (k *keyset) apply(query *gorm.DB, limit int) *gorm.DB {
+	// Walking backwards flips the ordering; the rows are reversed afterwards
+	desc := k.desc
+	if k.cursor != nil && k.cursor.Before {
+		desc = !desc
+	}
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
+	}
+
+	if k.cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", k.expr, k.idColumn, op), k.key, k.cursor.ID)
+	}
+	return query.Order(fmt.Sprintf("%s %s, %s %s", k.expr, direction, k.idColumn, direction)).Limit(limit + 1)
+}
+
+func (k *keyset) encode(key string, id uuid.UUID, before bool) *string {
+	data, _ := json.Marshal(pageCursor{Sort: k.sort, Key: key, ID: id, Before: before})
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	return &cursor
+}
+
+// finishKeysetPage trims the extra row fetched by apply, restores display
+// order and builds the cursors of the neighbouring pages. keyOf returns the
+// sort key and ID of a row.
+func finishKeysetPage[T any](k *keyset, rows []T, limit int, keyOf func(T) (string, uuid.UUID)) ([]T, CursorPage) {
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	backward := k.cursor != nil && k.cursor.Before
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	var page CursorPage
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	firstKey, firstID := keyOf(rows[0])
+	lastKey, lastID := keyOf(rows[len(rows)-1])
+	if backward {
+		page.NextCursor = k.encode(lastKey, lastID, false)
+		if hasMore {
+			page.PrevCursor = k.encode(firstKey, firstID, true)
+		}
+	} else {
+		if hasMore {
+			page.NextCursor = k.encode(lastKey, lastID, false)
+		}
+		if k.cursor != nil {
+			page.PrevCursor = k.encode(firstKey, firstID, true)
+		}
+	}
+	return rows, page
+}
+
+func cursorLimit(limit int) int {
+	if limit < 1 {
+		return defaultCursorLimit
+	}
+	return limit
+}
+
+func timeCursorKey(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
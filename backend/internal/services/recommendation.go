@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Recommendation limits
+const (
+	DefaultRecommendationLimit = 10
+	MaxRecommendationLimit     = 50
+	// coBorrowsPerBook caps the related books kept for each book
+	coBorrowsPerBook = 50
+)
+
+// refreshCoBorrowsQuery pairs up the books each patron has borrowed, whether
+// in one reservation or across several, and scores a pair by the number of
+// patrons who borrowed both books. Copies deleted since are still counted:
+// the history is what matters.
+const refreshCoBorrowsQuery = `
+WITH borrowed AS (
+	SELECT DISTINCT r.user_id, bc.book_id
+	FROM reservations r
+	JOIN reservation_book_copies rbc ON rbc.reservation_id = r.id
+	JOIN book_copies bc ON bc.id = rbc.book_copy_id
+	WHERE r.status IN ('approved', 'returned') AND r.deleted_at IS NULL
+), pairs AS (
+	SELECT a.book_id, b.book_id AS related_book_id, COUNT(*) AS score
+	FROM borrowed a
+	JOIN borrowed b ON b.user_id = a.user_id AND b.book_id <> a.book_id
+	GROUP BY a.book_id, b.book_id
+), ranked AS (
+	SELECT pairs.*, ROW_NUMBER() OVER (PARTITION BY book_id ORDER BY score DESC, related_book_id) AS rank
+	FROM pairs
+)
+INSERT INTO book_co_borrows (book_id, related_book_id, score, updated_at)
+SELECT ranked.book_id, ranked.related_book_id, ranked.score, NOW()
+FROM ranked
+JOIN books b ON b.id = ranked.book_id
+JOIN books rb ON rb.id = ranked.related_book_id
+WHERE ranked.rank <= @per_book`
+
+// userBorrowedBooksQuery selects the books a user has borrowed or asked to borrow
+const userBorrowedBooksQuery = `
+SELECT DISTINCT bc.book_id
+FROM reservations r
+JOIN reservation_book_copies rbc ON rbc.reservation_id = r.id
+JOIN book_copies bc ON bc.id = rbc.book_copy_id
+WHERE r.user_id = @user_id AND r.status <> 'rejected' AND r.deleted_at IS NULL`
+
+type RecommendationService struct {
+	db *gorm.DB
+}
+
+func NewRecommendationService(db *gorm.DB) *RecommendationService {
+	return &RecommendationService{db: db}
+}
+
+// RefreshCoBorrows rebuilds the co-borrowing pairs from the reservation history
+func (s *RecommendationService) RefreshCoBorrows(ctx context.Context) (int64, error) {
+	var rows int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM book_co_borrows").Error; err != nil {
+			zap.L().Error("RefreshCoBorrows: Failed to clear co-borrows", zap.Error(err))
+			return err
+		}
+		result := tx.Exec(refreshCoBorrowsQuery, map[string]interface{}{"per_book": coBorrowsPerBook})
+		if result.Error != nil {
+			zap.L().Error("RefreshCoBorrows: Failed to compute co-borrows", zap.Error(result.Error))
+			return result.Error
+		}
+		rows = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	zap.L().Info("RefreshCoBorrows: Co-borrows refreshed", zap.Int64("pairs", rows))
+	return rows, nil
+}
+
+// GetCoBorrowedBooks returns the books most often borrowed by patrons who also
+// borrowed the given book
+func (s *RecommendationService) GetCoBorrowedBooks(bookID string, limit int) ([]models.RecommendedBook, error) {
+	id, err := uuid.Parse(bookID)
+	if err != nil {
+		return nil, errors.New("book not found")
+	}
+	var count int64
+	if err := s.db.Model(&models.Book{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		zap.L().Error("GetCoBorrowedBooks: Failed to look up book", zap.String("id", bookID), zap.Error(err))
+		return nil, err
+	}
+	if count == 0 {
+		zap.L().Warn("GetCoBorrowedBooks: Book not found", zap.String("id", bookID))
+		return nil, errors.New("book not found")
+	}
+
+	var pairs []models.BookCoBorrow
+	if err := s.db.Where("book_id = ?", id).
+		Order("score DESC, related_book_id").
+		Limit(limit).
+		Find(&pairs).Error; err != nil {
+		zap.L().Error("GetCoBorrowedBooks: Failed to get co-borrows", zap.String("id", bookID), zap.Error(err))
+		return nil, err
+	}
+
+	scores := make([]bookScore, len(pairs))
+	for i, pair := range pairs {
+		scores[i] = bookScore{ID: pair.RelatedBookID, Score: pair.Score}
+	}
+	books, err := s.loadScoredBooks(scores)
+	if err != nil {
+		zap.L().Error("GetCoBorrowedBooks: Failed to load books", zap.String("id", bookID), zap.Error(err))
+		return nil, err
+	}
+	return books, nil
+}
+
+// GetUserRecommendations suggests books borrowed by patrons with a similar
+// borrowing history, leaving out books the user has borrowed or reserved
+func (s *RecommendationService) GetUserRecommendations(userID string, limit int) ([]models.RecommendedBook, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	var scores []bookScore
+	if err := s.db.Raw(`
+		SELECT cb.related_book_id AS id, SUM(cb.score) AS score
+		FROM book_co_borrows cb
+		WHERE cb.book_id IN (`+userBorrowedBooksQuery+`)
+		  AND cb.related_book_id NOT IN (`+userBorrowedBooksQuery+`)
+		GROUP BY cb.related_book_id
+		ORDER BY score DESC, cb.related_book_id
+		LIMIT @limit`,
+		map[string]interface{}{"user_id": id, "limit": limit},
+	).Scan(&scores).Error; err != nil {
+		zap.L().Error("GetUserRecommendations: Failed to score recommendations", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+
+	books, err := s.loadScoredBooks(scores)
+	if err != nil {
+		zap.L().Error("GetUserRecommendations: Failed to load books", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("GetUserRecommendations: Successfully retrieved recommendations", zap.String("userID", userID), zap.Int("count", len(books)))
+	return books, nil
+}
+
+type bookScore struct {
+	ID    uuid.UUID
+	Score int
+}
+
+func (s *RecommendationService) loadScoredBooks(scores []bookScore) ([]models.RecommendedBook, error) {
+	if len(scores) == 0 {
+		return []models.RecommendedBook{}, nil
+	}
+	ids := make([]uuid.UUID, len(scores))
+	scoreByID := make(map[uuid.UUID]int, len(scores))
+	for i, score := range scores {
+		ids[i] = score.ID
+		scoreByID[score.ID] = score.Score
+	}
+	books, err := loadBooksInOrder(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	recommended := make([]models.RecommendedBook, len(books))
+	for i, book := range books {
+		recommended[i] = models.RecommendedBook{Book: book, Score: scoreByID[book.ID]}
+	}
+	return recommended, nil
+}
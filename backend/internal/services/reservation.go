@@ -39,7 +39,7 @@ func (s *ReservationService) countUserActiveBooks(tx *gorm.DB, userID uuid.UUID)
 // CreateReservation creates a new reservation
 func (s *ReservationService) CreateReservation(
 	userID string, startDate time.Time, endDate time.Time,
-	suggestedPickTimes []string, suggestedReturnTimes []string,
+	suggestedPickTimes []string, suggestedReturnTimes []string, actor models.Actor,
 ) (*models.Reservation, error) {
 	var reservation models.Reservation
 
@@ -140,12 +140,22 @@ func (s *ReservationService) CreateReservation(
 			Update("status", models.BookCopyStatusReserved).Error; err != nil {
 			return fmt.Errorf("failed to update book copy status: %w", err)
 		}
+		for _, bookCopy := range bookCopies {
+			if err := recordCopyStatusChange(tx, bookCopy.ID, bookCopy.Status, models.BookCopyStatusReserved,
+				fmt.Sprintf("reserved on reservation %s", reservation.ID), actor); err != nil {
+				return fmt.Errorf("failed to record book copy status: %w", err)
+			}
+		}
 
 		// Clear cart within the same transaction
 		if err := cartService.ClearCartTx(tx, userID); err != nil {
 			return fmt.Errorf("failed to clear cart: %w", err)
 		}
 
+		if err := recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityReservations, reservation.ID, nil, &reservation); err != nil {
+			return fmt.Errorf("failed to record audit log: %w", err)
+		}
+
 		return nil
 	})
 
@@ -192,6 +202,40 @@ func (s *ReservationService) GetReservations(page, limit int, filters models.Res
 	var reservations []models.Reservation
 	var total int64
 
+	query := s.reservationListQuery(filters)
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * limit
+	if err := query.Offset(offset).Limit(limit).Order("reservations.created_at DESC").Find(&reservations).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := countDamageReports(s.db, reservations); err != nil {
+		return nil, 0, err
+	}
+
+	return reservations, total, nil
+}
+
+// GetReservationsByCursor retrieves a page of reservations, newest first,
+// after or before cursor, which is empty for the first page
+func (s *ReservationService) GetReservationsByCursor(cursor string, limit int, filters models.ReservationFilters) ([]models.Reservation, CursorPage, error) {
+	reservations, page, err := s.reservationsByCursor(s.reservationListQuery(filters), cursor, limit)
+	if err != nil {
+		return nil, CursorPage{}, err
+	}
+	if err := countDamageReports(s.db, reservations); err != nil {
+		return nil, CursorPage{}, err
+	}
+	return reservations, page, nil
+}
+
+// reservationListQuery selects the reservations matching the filters with their relations preloaded
+func (s *ReservationService) reservationListQuery(filters models.ReservationFilters) *gorm.DB {
 	query := s.db.Model(&models.Reservation{}).
 		Preload("User").
 		Unscoped().Preload("BookCopies").
@@ -208,25 +252,17 @@ func (s *ReservationService) GetReservations(page, limit int, filters models.Res
 		query = query.Where("reservations.status = ?", filters.Status)
 	}
 
+	// A subquery rather than a join keeps reservations with several matching
+	// copies from being listed more than once
 	if filters.BookTitle != "" {
-		query = query.Joins("JOIN reservation_book_copies ON reservation_book_copies.reservation_id = reservations.id").
-			Joins("JOIN book_copies ON book_copies.id = reservation_book_copies.book_copy_id").
-			Joins("JOIN books ON books.id = book_copies.book_id").
-			Where("LOWER(books.title) LIKE ?", "%"+strings.ToLower(filters.BookTitle)+"%")
-	}
-
-	// Get total count
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+		query = query.Where(`reservations.id IN (
+			SELECT reservation_book_copies.reservation_id FROM reservation_book_copies
+			JOIN book_copies ON book_copies.id = reservation_book_copies.book_copy_id
+			JOIN books ON books.id = book_copies.book_id
+			WHERE LOWER(books.title) LIKE ?)`, "%"+strings.ToLower(filters.BookTitle)+"%")
 	}
 
-	// Get paginated results
-	offset := (page - 1) * limit
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&reservations).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return reservations, total, nil
+	return query
 }
 
 // GetUserReservations gets reservations for a specific user with pagination
@@ -248,12 +284,7 @@ func (s *ReservationService) GetUserReservations(userID string, page, limit int)
 	}
 
 	// Get paginated reservations with preloaded relations
-	if err := s.db.Unscoped().Preload("BookCopies").
-		Preload("BookCopies.Book").
-		Preload("BookCopies.Book.Authors").
-		Preload("BookCopies.Book.Categories").
-		Preload("User").
-		Where("user_id = ?", userUUID).
+	if err := s.userReservationQuery(userUUID).
 		Offset(offset).Limit(limit).
 		Order("created_at DESC").
 		Find(&reservations).Error; err != nil {
@@ -263,9 +294,46 @@ func (s *ReservationService) GetUserReservations(userID string, page, limit int)
 	return reservations, total, nil
 }
 
+// GetUserReservationsByCursor gets a page of a user's reservations, newest
+// first, after or before cursor, which is empty for the first page
+func (s *ReservationService) GetUserReservationsByCursor(userID, cursor string, limit int) ([]models.Reservation, CursorPage, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, CursorPage{}, errors.New("invalid user ID")
+	}
+	return s.reservationsByCursor(s.userReservationQuery(userUUID), cursor, limit)
+}
+
+func (s *ReservationService) userReservationQuery(userID uuid.UUID) *gorm.DB {
+	return s.db.Unscoped().Preload("BookCopies").
+		Preload("BookCopies.Book").
+		Preload("BookCopies.Book.Authors").
+		Preload("BookCopies.Book.Categories").
+		Preload("User").
+		Where("user_id = ?", userID)
+}
+
+// reservationsByCursor pages through query by creation time, newest first
+func (s *ReservationService) reservationsByCursor(query *gorm.DB, cursor string, limit int) ([]models.Reservation, CursorPage, error) {
+	keys, err := newKeyset(cursor, "created_at", "reservations.created_at", "reservations.id", true, true)
+	if err != nil {
+		return nil, CursorPage{}, err
+	}
+	limit = cursorLimit(limit)
+
+	var reservations []models.Reservation
+	if err := keys.apply(query, limit).Find(&reservations).Error; err != nil {
+		return nil, CursorPage{}, err
+	}
+	reservations, page := finishKeysetPage(keys, reservations, limit, func(reservation models.Reservation) (string, uuid.UUID) {
+		return timeCursorKey(reservation.CreatedAt), reservation.ID
+	})
+	return reservations, page, nil
+}
+
 // UpdateReservationStatus updates a reservation's status
 func (s *ReservationService) UpdateReservationStatus(
-	id string, status models.ReservationStatus, pickupTime time.Time, returnTime time.Time,
+	id string, status models.ReservationStatus, pickupTime time.Time, returnTime time.Time, actor models.Actor,
 ) (*models.Reservation, error) {
 	var reservation models.Reservation
 
@@ -289,9 +357,17 @@ func (s *ReservationService) UpdateReservationStatus(
 			updates["return_time"] = returnTime
 		}
 
+		before := reservation
 		if err := tx.Model(&reservation).Updates(updates).Error; err != nil {
 			return err
 		}
+		var updated models.Reservation
+		if err := tx.First(&updated, "id = ?", reservation.ID).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityReservations, reservation.ID, &before, &updated); err != nil {
+			return err
+		}
 
 		// Update all book copies status in a single query
 		bookCopyStatus := models.BookCopyStatusAvailable
@@ -304,17 +380,33 @@ func (s *ReservationService) UpdateReservationStatus(
 			bookCopyStatus = models.BookCopyStatusAvailable
 		}
 
-		// Use raw SQL to update with FROM clause
-		if err := tx.Exec(`
-			UPDATE book_copies
-			SET status = ?, updated_at = ?
-			FROM reservation_book_copies
-			WHERE reservation_book_copies.book_copy_id = book_copies.id
-			  AND reservation_book_copies.reservation_id = ?
-			  AND book_copies.deleted_at IS NULL
-		`, bookCopyStatus, time.Now(), reservation.ID).Error; err != nil {
+		// Copies already checked in at the desk may be on another reservation
+		// by now, and copies out of circulation stay so
+		var bookCopies []models.BookCopy
+		if err := tx.Joins("JOIN reservation_book_copies ON reservation_book_copies.book_copy_id = book_copies.id").
+			Where("reservation_book_copies.reservation_id = ? AND reservation_book_copies.checked_in_at IS NULL", reservation.ID).
+			Where("book_copies.status IN ? AND book_copies.status <> ?", models.LendableCopyStatuses, bookCopyStatus).
+			Find(&bookCopies).Error; err != nil {
 			return err
 		}
+		for _, bookCopy := range bookCopies {
+			if err := tx.Model(&models.BookCopy{}).Where("id = ?", bookCopy.ID).Update("status", bookCopyStatus).Error; err != nil {
+				return err
+			}
+			if err := recordCopyStatusChange(tx, bookCopy.ID, bookCopy.Status, bookCopyStatus,
+				fmt.Sprintf("reservation %s %s", reservation.ID, status), actor); err != nil {
+				return err
+			}
+		}
+
+		// Returning the whole reservation brings back the copies not yet checked in at the desk
+		if status == models.ReservationStatusReturned {
+			if err := tx.Table("reservation_book_copies").
+				Where("reservation_id = ? AND checked_in_at IS NULL", reservation.ID).
+				Update("checked_in_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
@@ -369,7 +461,7 @@ func (s *ReservationService) GetReservation(id string) (*models.Reservation, err
 
 // CheckoutCart creates a reservation from the user's cart
 func (s *ReservationService) CheckoutCart(
-	userID string, startDate time.Time, endDate time.Time, suggestedPickTimes []string, suggestedReturnTimes []string,
+	userID string, startDate time.Time, endDate time.Time, suggestedPickTimes []string, suggestedReturnTimes []string, actor models.Actor,
 ) (*models.Reservation, error) {
 	// Get cart items
 	cartService := NewCartService(s.db)
@@ -402,7 +494,7 @@ func (s *ReservationService) CheckoutCart(
 	}
 
 	// Create reservation
-	reservation, err := s.CreateReservation(userID, startDate, endDate, suggestedPickTimes, suggestedReturnTimes)
+	reservation, err := s.CreateReservation(userID, startDate, endDate, suggestedPickTimes, suggestedReturnTimes, actor)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrReviewNotAllowed is returned when the user has not borrowed and returned the book
+	ErrReviewNotAllowed = errors.New("you can only review books you have borrowed and returned")
+)
+
+type ReviewService struct {
+	db *gorm.DB
+}
+
+func NewReviewService(db *gorm.DB) *ReviewService {
+	return &ReviewService{db: db}
+}
+
+// GetBookReviews retrieves the approved reviews of a book, newest first
+func (s *ReviewService) GetBookReviews(bookID string, page, limit int) ([]models.Review, int64, error) {
+	var reviews []models.Review
+	var total int64
+
+	query := s.db.Model(&models.Review{}).Where("book_id = ? AND status = ?", bookID, models.ReviewStatusApproved)
+	if err := query.Count(&total).Error; err != nil {
+		zap.L().Error("GetBookReviews: Failed to count reviews", zap.String("bookID", bookID), zap.Error(err))
+		return nil, 0, err
+	}
+	// Only the reviewer's name is public
+	if err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name")
+	}).Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&reviews).Error; err != nil {
+		zap.L().Error("GetBookReviews: Failed to get reviews", zap.String("bookID", bookID), zap.Error(err))
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// GetReviews retrieves reviews for moderation, optionally only those with the given status
+func (s *ReviewService) GetReviews(status string, page, limit int) ([]models.Review, int64, error) {
+	var reviews []models.Review
+	var total int64
+
+	query := s.db.Model(&models.Review{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		zap.L().Error("GetReviews: Failed to count reviews", zap.Error(err))
+		return nil, 0, err
+	}
+	if err := query.Preload("User").Preload("Book").
+		Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&reviews).Error; err != nil {
+		zap.L().Error("GetReviews: Failed to get reviews", zap.Error(err))
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// SaveReview creates the user's review of a book or replaces their earlier
+// one. A new or edited review waits for moderation before it is shown.
+func (s *ReviewService) SaveReview(bookID, userID string, req models.ReviewRequest) (*models.Review, error) {
+	bookUUID, err := uuid.Parse(bookID)
+	if err != nil {
+		return nil, errors.New("book not found")
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	var review models.Review
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Book{}).Where("id = ?", bookUUID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("book not found")
+		}
+
+		returned, err := hasReturnedBook(tx, userUUID, bookUUID)
+		if err != nil {
+			return err
+		}
+		if !returned {
+			return ErrReviewNotAllowed
+		}
+
+		err = tx.Where("book_id = ? AND user_id = ?", bookUUID, userUUID).First(&review).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			review = models.Review{
+				BookID: bookUUID,
+				UserID: userUUID,
+				Rating: req.Rating,
+				Body:   req.Body,
+				Status: models.ReviewStatusPending,
+			}
+			if err := tx.Create(&review).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&review).Updates(map[string]interface{}{
+				"rating":       req.Rating,
+				"body":         req.Body,
+				"status":       models.ReviewStatusPending,
+				"moderated_by": nil,
+				"moderated_at": nil,
+			}).Error; err != nil {
+				return err
+			}
+			review.ModeratedBy = nil
+			review.ModeratedAt = nil
+		}
+		return updateBookRating(tx, bookUUID)
+	}); err != nil {
+		zap.L().Error("SaveReview: Failed to save review", zap.String("bookID", bookID), zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("SaveReview: Review saved successfully", zap.String("id", review.ID.String()), zap.String("bookID", bookID), zap.String("userID", userID))
+	return &review, nil
+}
+
+// DeleteReview deletes a review written by the user
+func (s *ReviewService) DeleteReview(id, userID string) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&review).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReviewNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return updateBookRating(tx, review.BookID)
+	}); err != nil {
+		zap.L().Error("DeleteReview: Failed to delete review", zap.String("id", id), zap.String("userID", userID), zap.Error(err))
+		return err
+	}
+	zap.L().Info("DeleteReview: Review deleted successfully", zap.String("id", id), zap.String("userID", userID))
+	return nil
+}
+
+// UpdateReviewStatus approves or hides a review
+func (s *ReviewService) UpdateReviewStatus(id string, status models.ReviewStatus, moderatorID *uuid.UUID) (*models.Review, error) {
+	var review models.Review
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&review, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReviewNotFound
+			}
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&review).Updates(map[string]interface{}{
+			"status":       status,
+			"moderated_by": moderatorID,
+			"moderated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		review.Status = status
+		review.ModeratedBy = moderatorID
+		review.ModeratedAt = &now
+		return updateBookRating(tx, review.BookID)
+	}); err != nil {
+		zap.L().Error("UpdateReviewStatus: Failed to update review status", zap.String("id", id), zap.String("status", string(status)), zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("UpdateReviewStatus: Review status updated successfully", zap.String("id", id), zap.String("status", string(status)))
+	return &review, nil
+}
+
+// hasReturnedBook reports whether the user has a returned reservation
+// including a copy of the book
+func hasReturnedBook(db *gorm.DB, userID, bookID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Table("reservations r").
+		Joins("JOIN reservation_book_copies rbc ON rbc.reservation_id = r.id").
+		Joins("JOIN book_copies bc ON bc.id = rbc.book_copy_id").
+		Where("r.user_id = ? AND bc.book_id = ? AND r.status = ? AND r.deleted_at IS NULL", userID, bookID, models.ReservationStatusReturned).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// updateBookRating recomputes the cached rating of a book from its approved reviews
+func updateBookRating(tx *gorm.DB, bookID uuid.UUID) error {
+	return tx.Exec(`UPDATE books SET
+		average_rating = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE book_id = @id AND status = @status), 0),
+		review_count = (SELECT COUNT(*) FROM reviews WHERE book_id = @id AND status = @status)
+		WHERE id = @id`,
+		map[string]interface{}{"id": bookID, "status": models.ReviewStatusApproved},
+	).Error
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// Job is a unit of background work run by the JobScheduler. Run returns the
+// number of rows it affected.
+type Job struct {
+	Name        string
+	Description string
+	// Schedule is a five-field cron expression ("0 3 * * *"); jobs without
+	// one only run when triggered
+	Schedule string
+	Run      func(ctx context.Context) (int64, error)
+}
+
+// JobInfo describes a registered job and its latest run
+type JobInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Schedule    string         `json:"schedule"`
+	NextRunAt   *time.Time     `json:"next_run_at"`
+	Running     bool           `json:"running"`
+	LastRun     *models.JobRun `json:"last_run"`
+}
+
+type registeredJob struct {
+	Job
+	entryID cron.EntryID
+	running bool
+}
+
+// JobScheduler runs registered jobs on their cron schedules inside the server
+// process and records every run in the job_runs table
+type JobScheduler struct {
+	db     *gorm.DB
+	cron   *cron.Cron
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	jobs  map[string]*registeredJob
+	order []string
+	// manual tracks triggered runs, which cron does not wait for on Stop
+	manual sync.WaitGroup
+}
+
+func NewJobScheduler(db *gorm.DB) *JobScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobScheduler{
+		db:     db,
+		cron:   cron.New(),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*registeredJob),
+	}
+}
+
+// Register adds a job to the registry. Names must be unique.
+func (s *JobScheduler) Register(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %q is already registered", job.Name)
+	}
+	registered := &registeredJob{Job: job}
+	if job.Schedule != "" {
+		entryID, err := s.cron.AddFunc(job.Schedule, func() {
+			s.runScheduled(registered)
+		})
+		if err != nil {
+			return fmt.Errorf("invalid schedule for job %q: %w", job.Name, err)
+		}
+		registered.entryID = entryID
+	}
+	s.jobs[job.Name] = registered
+	s.order = append(s.order, job.Name)
+	zap.L().Info("Register: Job registered", zap.String("job", job.Name), zap.String("schedule", job.Schedule))
+	return nil
+}
+
+// Start marks runs interrupted by a previous shutdown as failed and starts
+// firing schedules
+func (s *JobScheduler) Start() {
+	now := time.Now()
+	if err := s.db.Model(&models.JobRun{}).
+		Where("status = ?", models.JobRunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      models.JobRunStatusFailed,
+			"finished_at": now,
+			"error":       "interrupted by server shutdown",
+		}).Error; err != nil {
+		zap.L().Error("Start: Failed to close interrupted job runs", zap.Error(err))
+	}
+	s.cron.Start()
+	zap.L().Info("Start: Job scheduler started", zap.Int("jobs", len(s.order)))
+}
+
+// Stop stops firing schedules, cancels running jobs and waits for them to return
+func (s *JobScheduler) Stop() {
+	s.cancel()
+	<-s.cron.Stop().Done()
+	s.manual.Wait()
+	zap.L().Info("Stop: Job scheduler stopped")
+}
+
+// ListJobs returns the registered jobs in registration order
+func (s *JobScheduler) ListJobs() ([]JobInfo, error) {
+	s.mu.Lock()
+	infos := make([]JobInfo, 0, len(s.order))
+	for _, name := range s.order {
+		job := s.jobs[name]
+		info := JobInfo{
+			Name:        job.Name,
+			Description: job.Description,
+			Schedule:    job.Schedule,
+			Running:     job.running,
+		}
+		if job.entryID != 0 {
+			if next := s.cron.Entry(job.entryID).Next; !next.IsZero() {
+				info.NextRunAt = &next
+			}
+		}
+		infos = append(infos, info)
+	}
+	s.mu.Unlock()
+
+	for i := range infos {
+		var run models.JobRun
+		err := s.db.Where("job_name = ?", infos[i].Name).Order("started_at DESC").First(&run).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			zap.L().Error("ListJobs: Failed to get last run", zap.String("job", infos[i].Name), zap.Error(err))
+			return nil, err
+		}
+		infos[i].LastRun = &run
+	}
+	return infos, nil
+}
+
+// Trigger starts a job immediately in the background and returns its run
+// record. userID is the admin who asked for it.
+func (s *JobScheduler) Trigger(name string, userID *uuid.UUID) (*models.JobRun, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	run, err := s.begin(job, models.JobTriggerManual, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.manual.Add(1)
+	go func() {
+		defer s.manual.Done()
+		s.execute(job, run)
+	}()
+	return run, nil
+}
+
+// GetRuns returns the run history of a job, most recent first
+func (s *JobScheduler) GetRuns(name string, page, limit int) ([]models.JobRun, int64, error) {
+	s.mu.Lock()
+	_, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, 0, ErrJobNotFound
+	}
+
+	var runs []models.JobRun
+	var total int64
+	query := s.db.Model(&models.JobRun{}).Where("job_name = ?", name)
+	if err := query.Count(&total).Error; err != nil {
+		zap.L().Error("GetRuns: Failed to count job runs", zap.String("job", name), zap.Error(err))
+		return nil, 0, err
+	}
+	if err := query.Order("started_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&runs).Error; err != nil {
+		zap.L().Error("GetRuns: Failed to get job runs", zap.String("job", name), zap.Error(err))
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+func (s *JobScheduler) runScheduled(job *registeredJob) {
+	run, err := s.begin(job, models.JobTriggerSchedule, nil)
+	if err != nil {
+		zap.L().Warn("runScheduled: Skipping scheduled run", zap.String("job", job.Name), zap.Error(err))
+		return
+	}
+	s.execute(job, run)
+}
+
+// begin claims the job and records the start of a run. A job never runs twice
+// at the same time within a process.
+func (s *JobScheduler) begin(job *registeredJob, trigger models.JobTrigger, userID *uuid.UUID) (*models.JobRun, error) {
+	s.mu.Lock()
+	if job.running {
+		s.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	job.running = true
+	s.mu.Unlock()
+
+	run := &models.JobRun{
+		JobName:     job.Name,
+		Trigger:     trigger,
+		TriggeredBy: userID,
+		Status:      models.JobRunStatusRunning,
+		StartedAt:   time.Now(),
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		zap.L().Error("begin: Failed to record job run", zap.String("job", job.Name), zap.Error(err))
+		s.release(job)
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *JobScheduler) execute(job *registeredJob, run *models.JobRun) {
+	defer s.release(job)
+
+	rows, err := func() (rows int64, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		return job.Run(s.ctx)
+	}()
+
+	finishedAt := time.Now()
+	updates := map[string]interface{}{
+		"status":        models.JobRunStatusSucceeded,
+		"finished_at":   finishedAt,
+		"rows_affected": rows,
+	}
+	if err != nil {
+		updates["status"] = models.JobRunStatusFailed
+		updates["error"] = err.Error()
+		zap.L().Error("execute: Job failed", zap.String("job", job.Name), zap.String("runID", run.ID.String()), zap.Error(err))
+	} else {
+		zap.L().Info("execute: Job finished", zap.String("job", job.Name), zap.String("runID", run.ID.String()),
+			zap.Int64("rowsAffected", rows), zap.Duration("duration", finishedAt.Sub(run.StartedAt)))
+	}
+
+	if err := s.db.Model(run).Updates(updates).Error; err != nil {
+		zap.L().Error("execute: Failed to record job result", zap.String("job", job.Name), zap.String("runID", run.ID.String()), zap.Error(err))
+	}
+}
+
+func (s *JobScheduler) release(job *registeredJob) {
+	s.mu.Lock()
+	job.running = false
+	s.mu.Unlock()
+}
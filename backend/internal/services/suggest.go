@@ -0,0 +1,97 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultSuggestionLimit = 5
+	MaxSuggestionLimit     = 10
+	// minSuggestQueryLength avoids matching nearly everything on the first keystroke
+	minSuggestQueryLength = 2
+)
+
+// suggestQuery ranks the titles, author names, categories and tags close to
+// the query. Names are folded with normalize_name so that missing diacritics
+// do not matter, and matched with pg_trgm's word similarity so that typos and
+// partially typed words still match. Names starting with the query come
+// first. Each branch can use the trigram index on its folded name and stops
+// after @limit rows.
+const suggestQuery = `
+(SELECT 'book' AS type, b.id, b.title AS label, '' AS key,
+        word_similarity(normalize_name(@q), normalize_name(b.title)) AS score,
+        normalize_name(b.title) LIKE normalize_name(@prefix) AS prefix
+ FROM books b
+ WHERE b.deleted_at IS NULL
+   AND (normalize_name(@q) <% normalize_name(b.title) OR normalize_name(b.title) LIKE normalize_name(@prefix))
+ ORDER BY prefix DESC, score DESC, b.title
+ LIMIT @limit)
+UNION ALL
+(SELECT 'author', a.id, a.name, '',
+        word_similarity(normalize_name(@q), normalize_name(a.name)) AS score,
+        normalize_name(a.name) LIKE normalize_name(@prefix) AS prefix
+ FROM authors a
+ WHERE a.deleted_at IS NULL
+   AND (normalize_name(@q) <% normalize_name(a.name) OR normalize_name(a.name) LIKE normalize_name(@prefix))
+ ORDER BY prefix DESC, score DESC, a.name
+ LIMIT @limit)
+UNION ALL
+(SELECT 'category', c.id, c.name, '',
+        word_similarity(normalize_name(@q), normalize_name(c.name)) AS score,
+        normalize_name(c.name) LIKE normalize_name(@prefix) AS prefix
+ FROM categories c
+ WHERE c.deleted_at IS NULL
+   AND (normalize_name(@q) <% normalize_name(c.name) OR normalize_name(c.name) LIKE normalize_name(@prefix))
+ ORDER BY prefix DESC, score DESC, c.name
+ LIMIT @limit)
+UNION ALL
+(SELECT 'tag', t.id, t.name, t.key,
+        word_similarity(normalize_name(@q), normalize_name(t.name)) AS score,
+        normalize_name(t.name) LIKE normalize_name(@prefix) AS prefix
+ FROM tags t
+ WHERE t.deleted_at IS NULL
+   AND (normalize_name(@q) <% normalize_name(t.name) OR normalize_name(t.name) LIKE normalize_name(@prefix))
+ ORDER BY prefix DESC, score DESC, t.name
+ LIMIT @limit)
+ORDER BY prefix DESC, score DESC, label`
+
+type SuggestService struct {
+	db *gorm.DB
+}
+
+func NewSuggestService(db *gorm.DB) *SuggestService {
+	return &SuggestService{db: db}
+}
+
+// Suggest returns up to limit books, authors, categories and tags each whose
+// name is close to the query, best matches first
+func (s *SuggestService) Suggest(query string, limit int) ([]models.Suggestion, error) {
+	query = strings.Join(strings.Fields(query), " ")
+	if utf8.RuneCountInString(query) < minSuggestQueryLength {
+		return []models.Suggestion{}, nil
+	}
+
+	suggestions := []models.Suggestion{}
+	if err := s.db.Raw(suggestQuery, map[string]interface{}{
+		"q":      query,
+		"prefix": likePrefix(query),
+		"limit":  limit,
+	}).Scan(&suggestions).Error; err != nil {
+		zap.L().Error("Suggest: Failed to get suggestions", zap.String("query", query), zap.Error(err))
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// likePrefix builds a LIKE pattern matching folded names that start with the
+// query. normalize_name leaves the LIKE wildcards alone, so they are escaped
+// beforehand and the pattern is folded in SQL.
+func likePrefix(query string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query) + "%"
+}
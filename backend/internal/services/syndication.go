@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/config"
+)
+
+// Syndication media types
+const (
+	AtomType = "application/atom+xml; charset=utf-8"
+	RSSType  = "application/rss+xml; charset=utf-8"
+)
+
+// SyndicationFeed is a new arrivals feed ready to be written as Atom or RSS
+type SyndicationFeed struct {
+	// ID is a stable, unique path of the feed, e.g. "/categories/<id>"
+	ID    string
+	Title string
+	// SelfURL is the absolute address the feed was requested at
+	SelfURL string
+	Updated time.Time
+	Books   []ExportedBook
+}
+
+// LastModified is when any book of the feed was last added or edited
+func (f *SyndicationFeed) LastModified() time.Time {
+	modified := f.Updated
+	for _, book := range f.Books {
+		if book.UpdatedAt.After(modified) {
+			modified = book.UpdatedAt
+		}
+	}
+	return feedTimestamp(modified).UTC().Truncate(time.Second)
+}
+
+// WriteAtomFeed writes the feed as an Atom document
+func WriteAtomFeed(w io.Writer, feed *SyndicationFeed) error {
+	siteURL := strings.TrimRight(config.AppConfig.SiteURL, "/")
+	doc := atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      "urn:pscit:feeds" + feed.ID,
+		Title:   feed.Title,
+		Updated: feed.LastModified().Format(time.RFC3339),
+		Author:  &atomPerson{Name: catalogName, URI: siteURL},
+		Links: []atomLink{
+			{Rel: "self", Href: feed.SelfURL, Type: "application/atom+xml"},
+			{Rel: "alternate", Href: siteURL, Type: "text/html"},
+		},
+		Entries: []atomEntry{},
+	}
+
+	for i := range feed.Books {
+		book := &feed.Books[i]
+		entry := atomEntry{
+			ID:        "urn:uuid:" + book.ID.String(),
+			Title:     bookFeedTitle(book),
+			Updated:   book.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: book.CreatedAt.UTC().Format(time.RFC3339),
+			Links:     []atomLink{{Rel: "alternate", Href: BookPageURL(&book.Book), Type: "text/html"}},
+		}
+		for _, author := range book.Authors {
+			entry.Authors = append(entry.Authors, atomPerson{Name: author.Name})
+		}
+		for _, category := range book.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category.Name})
+		}
+		if book.Description != "" {
+			entry.Summary = &atomText{Type: "text", Body: book.Description}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return writeXML(w, doc)
+}
+
+// rssFeed is an RSS 2.0 document, with the Atom self link feed readers
+// expect and Dublin Core creators since RSS authors must be email addresses
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XmlnsAtom string     `xml:"xmlns:atom,attr"`
+	XmlnsDC   string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creators    []string `xml:"dc:creator"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSSFeed writes the feed as an RSS 2.0 document
+func WriteRSSFeed(w io.Writer, feed *SyndicationFeed) error {
+	siteURL := strings.TrimRight(config.AppConfig.SiteURL, "/")
+	doc := rssFeed{
+		Version:   "2.0",
+		XmlnsAtom: "http://www.w3.org/2005/Atom",
+		XmlnsDC:   "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          siteURL,
+			Description:   feed.Title + " at " + catalogName,
+			LastBuildDate: feed.LastModified().Format(time.RFC1123Z),
+			SelfLink:      atomLink{Rel: "self", Href: feed.SelfURL, Type: "application/rss+xml"},
+			Items:         []rssItem{},
+		},
+	}
+
+	for i := range feed.Books {
+		book := &feed.Books[i]
+		item := rssItem{
+			Title:       bookFeedTitle(book),
+			Link:        BookPageURL(&book.Book),
+			GUID:        rssGUID{Value: "urn:uuid:" + book.ID.String()},
+			PubDate:     book.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: book.Description,
+		}
+		for _, author := range book.Authors {
+			item.Creators = append(item.Creators, author.Name)
+		}
+		for _, category := range book.Categories {
+			item.Categories = append(item.Categories, category.Name)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+func bookFeedTitle(book *ExportedBook) string {
+	if book.Subtitle != "" {
+		return book.Title + ": " + book.Subtitle
+	}
+	return book.Title
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -40,8 +43,16 @@ func (s *TagService) GetTag(id string) (*models.Tag, error) {
 	return &tag, nil
 }
 
-func (s *TagService) CreateTag(tag *models.Tag) error {
-	if err := s.db.Create(tag).Error; err != nil {
+func (s *TagService) CreateTag(tag *models.Tag, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTrashedTagKey(tx, tag.Key, tag.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(tag).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionCreate, models.AuditEntityTags, tag.ID, nil, tag)
+	}); err != nil {
 		zap.L().Error("CreateTag: Failed to create tag", zap.String("name", tag.Name), zap.Error(err))
 		return err
 	}
@@ -49,34 +60,73 @@ func (s *TagService) CreateTag(tag *models.Tag) error {
 	return nil
 }
 
-func (s *TagService) UpdateTag(id string, tag *models.Tag) error {
-	result := s.db.Model(&models.Tag{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"key":         tag.Key,
-		"name":        tag.Name,
-		"description": tag.Description,
-	})
-	if result.Error != nil {
-		zap.L().Error("UpdateTag: Failed to update tag", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("UpdateTag: Tag not found for update", zap.String("id", id))
-		return errors.New("tag not found")
+func (s *TagService) UpdateTag(id string, tag *models.Tag, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing, updated models.Tag
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("UpdateTag: Tag not found for update", zap.String("id", id))
+				return errors.New("tag not found")
+			}
+			return err
+		}
+		if err := checkTrashedTagKey(tx, tag.Key, existing.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Tag{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"key":         tag.Key,
+			"name":        tag.Name,
+			"description": tag.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&updated, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityTags, updated.ID, &existing, &updated)
+	}); err != nil {
+		zap.L().Error("UpdateTag: Failed to update tag", zap.String("id", id), zap.Error(err))
+		return err
 	}
 	zap.L().Info("UpdateTag: Tag updated successfully", zap.String("id", id), zap.String("name", tag.Name))
 	return nil
 }
 
-func (s *TagService) DeleteTag(id string) error {
-	result := s.db.Unscoped().Delete(&models.Tag{}, "id = ?", id)
-	if result.Error != nil {
-		zap.L().Error("DeleteTag: Failed to delete tag", zap.String("id", id), zap.Error(result.Error))
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		zap.L().Warn("DeleteTag: Tag not found for deletion", zap.String("id", id))
-		return errors.New("tag not found")
+func (s *TagService) DeleteTag(id string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Tag
+		if err := tx.First(&existing, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.L().Warn("DeleteTag: Tag not found for deletion", zap.String("id", id))
+				return errors.New("tag not found")
+			}
+			return err
+		}
+		// Soft delete keeps the book links so that restoring from the trash brings them back
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionDelete, models.AuditEntityTags, existing.ID, &existing, nil)
+	}); err != nil {
+		zap.L().Error("DeleteTag: Failed to delete tag", zap.String("id", id), zap.Error(err))
+		return err
 	}
-	zap.L().Info("DeleteTag: Tag deleted successfully", zap.String("id", id))
+	zap.L().Info("DeleteTag: Tag moved to trash", zap.String("id", id))
 	return nil
 }
+
+// checkTrashedTagKey rejects a key still held by a trashed tag other than id,
+// which the unique index on the key would otherwise report as a server error
+func checkTrashedTagKey(tx *gorm.DB, key string, id uuid.UUID) error {
+	var trashed models.Tag
+	err := tx.Unscoped().Select("id", "name").
+		Where("key = ? AND id <> ? AND deleted_at IS NOT NULL", key, id).
+		First(&trashed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return FieldErrors{"key": fmt.Sprintf("used by the tag %q (%s), which is in the trash; restore it instead", trashed.Name, trashed.ID)}
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownTrashType  = errors.New("unknown trash type")
+	ErrTrashItemNotFound = errors.New("item not found in trash")
+)
+
+// Trashable entity types
+const (
+	TrashTypeBooks      = "books"
+	TrashTypeAuthors    = "authors"
+	TrashTypeCategories = "categories"
+	TrashTypeTags       = "tags"
+)
+
+// trashTables maps each trashable type to its table, its join table with
+// books, its display name column and its audit log entity type. Books have
+// several joins, purged apart.
+var trashTables = []struct {
+	kind       string
+	table      string
+	joinTable  string
+	joinColumn string
+	nameColumn string
+	auditType  string
+}{
+	{TrashTypeBooks, "books", "", "", "title", models.AuditEntityBooks},
+	{TrashTypeAuthors, "authors", "book_authors", "author_id", "name", models.AuditEntityAuthors},
+	{TrashTypeCategories, "categories", "book_categories", "category_id", "name", models.AuditEntityCategories},
+	{TrashTypeTags, "tags", "book_tags", "tag_id", "name", models.AuditEntityTags},
+}
+
+// TrashItem is a soft-deleted record waiting to be restored or purged
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type TrashService struct {
+	db        *gorm.DB
+	retention time.Duration
+}
+
+// NewTrashService creates the trash service. Items deleted longer than
+// retention ago are removed for good by PurgeExpired.
+func NewTrashService(db *gorm.DB, retention time.Duration) *TrashService {
+	return &TrashService{
+		db:        db,
+		retention: retention,
+	}
+}
+
+// GetTrash lists soft-deleted items, most recently deleted first. kind
+// narrows the list to one type; empty lists every type.
+func (s *TrashService) GetTrash(kind string, page, limit int) ([]TrashItem, int64, error) {
+	var selects []string
+	for _, t := range trashTables {
+		if kind != "" && kind != t.kind {
+			continue
+		}
+		selects = append(selects, fmt.Sprintf(
+			"SELECT '%s' AS type, id, %s AS name, deleted_at FROM %s WHERE deleted_at IS NOT NULL",
+			t.kind, t.nameColumn, t.table))
+	}
+	if len(selects) == 0 {
+		return nil, 0, ErrUnknownTrashType
+	}
+	union := strings.Join(selects, " UNION ALL ")
+
+	var total int64
+	if err := s.db.Raw("SELECT COUNT(*) FROM (" + union + ") trash").Scan(&total).Error; err != nil {
+		zap.L().Error("GetTrash: Failed to count trash", zap.String("type", kind), zap.Error(err))
+		return nil, 0, err
+	}
+	items := []TrashItem{}
+	if err := s.db.Raw(union+" ORDER BY deleted_at DESC LIMIT ? OFFSET ?", limit, (page-1)*limit).
+		Scan(&items).Error; err != nil {
+		zap.L().Error("GetTrash: Failed to get trash", zap.String("type", kind), zap.Error(err))
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Restore takes an item out of the trash. The copies deleted together with a
+// book come back with it.
+func (s *TrashService) Restore(kind, id string, actor models.Actor) error {
+	var model interface{}
+	switch kind {
+	case TrashTypeBooks:
+		return s.restoreBook(id, actor)
+	case TrashTypeAuthors:
+		model = &models.Author{}
+	case TrashTypeCategories:
+		model = &models.Category{}
+	case TrashTypeTags:
+		model = &models.Tag{}
+	default:
+		return ErrUnknownTrashType
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(model).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			UpdateColumn("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTrashItemNotFound
+		}
+		// Trash types are named after their tables, like audited entities
+		return recordAudit(tx, actor, models.AuditActionRestore, kind, uuid.MustParse(id), nil, nil)
+	}); err != nil {
+		zap.L().Error("Restore: Failed to restore item", zap.String("type", kind), zap.String("id", id), zap.Error(err))
+		return err
+	}
+	zap.L().Info("Restore: Item restored", zap.String("type", kind), zap.String("id", id))
+	return nil
+}
+
+func (s *TrashService) restoreBook(id string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var book models.Book
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&book).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTrashItemNotFound
+			}
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.BookCopy{}).
+			Where("book_id = ? AND deleted_at = ?", book.ID, book.DeletedAt.Time).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := restoreTrashed(tx, &book, &book.DeletedAt); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionRestore, models.AuditEntityBooks, book.ID, nil, nil)
+	}); err != nil {
+		zap.L().Error("restoreBook: Failed to restore book", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	zap.L().Info("restoreBook: Book restored", zap.String("id", id))
+	return nil
+}
+
+// PurgeExpired permanently deletes items that have been in the trash longer
+// than the retention period. Books whose copies appear in a reservation are
+// kept so that old reservations stay intact. Each purged record, copies of
+// purged books included, gets a delete entry in the audit log.
+func (s *TrashService) PurgeExpired(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-s.retention)
+	var total int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, t := range trashTables {
+			query := tx.Table(t.table).Where("deleted_at < ?", cutoff)
+			if t.kind == TrashTypeBooks {
+				query = query.Where(`NOT EXISTS (
+					SELECT 1 FROM reservation_book_copies rbc
+					JOIN book_copies bc ON bc.id = rbc.book_copy_id
+					WHERE bc.book_id = books.id)`)
+			}
+			var ids []uuid.UUID
+			if err := query.Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+
+			var copyIDs []uuid.UUID
+			if t.kind == TrashTypeBooks {
+				if err := tx.Unscoped().Model(&models.BookCopy{}).Where("book_id IN ?", ids).Pluck("id", &copyIDs).Error; err != nil {
+					return err
+				}
+			}
+
+			var statements []string
+			if t.kind == TrashTypeBooks {
+				statements = []string{
+					"DELETE FROM cart_items WHERE book_copy_id IN (SELECT id FROM book_copies WHERE book_id IN ?)",
+					"DELETE FROM book_copies WHERE book_id IN ?",
+					"DELETE FROM book_authors WHERE book_id IN ?",
+					"DELETE FROM book_categories WHERE book_id IN ?",
+					"DELETE FROM book_tags WHERE book_id IN ?",
+				}
+			} else {
+				statements = []string{fmt.Sprintf("DELETE FROM %s WHERE %s IN ?", t.joinTable, t.joinColumn)}
+			}
+			statements = append(statements, fmt.Sprintf("DELETE FROM %s WHERE id IN ?", t.table))
+			for _, statement := range statements {
+				if err := tx.Exec(statement, ids).Error; err != nil {
+					zap.L().Error("PurgeExpired: Failed to purge trash", zap.String("type", t.kind), zap.Error(err))
+					return err
+				}
+			}
+			for _, id := range copyIDs {
+				if err := recordAudit(tx, models.Actor{}, models.AuditActionDelete, models.AuditEntityBookCopies, id, nil, nil); err != nil {
+					return err
+				}
+			}
+			for _, id := range ids {
+				if err := recordAudit(tx, models.Actor{}, models.AuditActionDelete, t.auditType, id, nil, nil); err != nil {
+					return err
+				}
+			}
+			zap.L().Info("PurgeExpired: Purged trash", zap.String("type", t.kind), zap.Int("rows", len(ids)))
+			total += int64(len(ids))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// restoreTrashed clears the deletion mark of a soft-deleted record
+func restoreTrashed(tx *gorm.DB, model interface{}, deletedAt *gorm.DeletedAt) error {
+	if err := tx.Unscoped().Model(model).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	*deletedAt = gorm.DeletedAt{}
+	return nil
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hungcq/pscit/backend/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrWorkNotFound is returned when a work ID does not match any work
+var ErrWorkNotFound = errors.New("work not found")
+
+type WorkService struct {
+	db *gorm.DB
+}
+
+func NewWorkService(db *gorm.DB) *WorkService {
+	return &WorkService{db: db}
+}
+
+// GetWork retrieves a work with all of its editions
+func (s *WorkService) GetWork(id string) (*models.Work, error) {
+	var work models.Work
+	if err := s.db.First(&work, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			zap.L().Warn("GetWork: Work not found", zap.String("id", id))
+			return nil, ErrWorkNotFound
+		}
+		zap.L().Error("GetWork: Failed to retrieve work", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	editions, err := loadEditions(s.db, work.ID, uuid.Nil)
+	if err != nil {
+		zap.L().Error("GetWork: Failed to load editions", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	work.Editions = editions
+	return &work, nil
+}
+
+// CreateWork creates a work and attaches the given books to it as editions
+func (s *WorkService) CreateWork(work *models.Work, bookIDs []string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(work).Error; err != nil {
+			return err
+		}
+		return attachEditions(tx, work.ID, bookIDs, actor)
+	}); err != nil {
+		zap.L().Error("CreateWork: Failed to create work", zap.String("title", work.Title), zap.Error(err))
+		return err
+	}
+	zap.L().Info("CreateWork: Work created successfully", zap.String("id", work.ID.String()), zap.String("title", work.Title))
+	return nil
+}
+
+func (s *WorkService) UpdateWork(id string, work *models.Work) error {
+	result := s.db.Model(&models.Work{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"title":       work.Title,
+		"description": work.Description,
+	})
+	if result.Error != nil {
+		zap.L().Error("UpdateWork: Failed to update work", zap.String("id", id), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		zap.L().Warn("UpdateWork: Work not found for update", zap.String("id", id))
+		return ErrWorkNotFound
+	}
+	zap.L().Info("UpdateWork: Work updated successfully", zap.String("id", id), zap.String("title", work.Title))
+	return nil
+}
+
+// DeleteWork soft deletes a work; its editions become standalone books again
+func (s *WorkService) DeleteWork(id string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Work{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWorkNotFound
+		}
+		var editions []models.Book
+		if err := tx.Where("work_id = ?", id).Find(&editions).Error; err != nil {
+			return err
+		}
+		return setBooksWork(tx, editions, nil, actor)
+	}); err != nil {
+		zap.L().Error("DeleteWork: Failed to delete work", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	zap.L().Info("DeleteWork: Work deleted successfully", zap.String("id", id))
+	return nil
+}
+
+// AddEditions attaches books to a work, moving them out of any other work
+func (s *WorkService) AddEditions(id string, bookIDs []string, actor models.Actor) error {
+	workID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrWorkNotFound
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Work{}).Where("id = ?", workID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrWorkNotFound
+		}
+		return attachEditions(tx, workID, bookIDs, actor)
+	}); err != nil {
+		zap.L().Error("AddEditions: Failed to add editions", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	zap.L().Info("AddEditions: Editions added successfully", zap.String("id", id), zap.Int("count", len(bookIDs)))
+	return nil
+}
+
+// RemoveEdition detaches a book from a work
+func (s *WorkService) RemoveEdition(id, bookID string, actor models.Actor) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var editions []models.Book
+		if err := tx.Where("id = ? AND work_id = ?", bookID, id).Find(&editions).Error; err != nil {
+			return err
+		}
+		if len(editions) == 0 {
+			zap.L().Warn("RemoveEdition: Book is not an edition of the work", zap.String("id", id), zap.String("bookID", bookID))
+			return errors.New("book is not an edition of this work")
+		}
+		return setBooksWork(tx, editions, nil, actor)
+	}); err != nil {
+		zap.L().Error("RemoveEdition: Failed to remove edition", zap.String("id", id), zap.String("bookID", bookID), zap.Error(err))
+		return err
+	}
+	zap.L().Info("RemoveEdition: Edition removed successfully", zap.String("id", id), zap.String("bookID", bookID))
+	return nil
+}
+
+// GetBookEditions returns the other editions of the book's work
+func (s *BookService) GetBookEditions(book *models.Book) ([]models.BookEdition, error) {
+	if book.WorkID == nil {
+		return nil, nil
+	}
+	editions, err := loadEditions(s.db, *book.WorkID, book.ID)
+	if err != nil {
+		zap.L().Error("GetBookEditions: Failed to load editions", zap.String("id", book.ID.String()), zap.Error(err))
+		return nil, err
+	}
+	return editions, nil
+}
+
+// collapseByWork narrows a book ID subquery to one edition per work. The
+// edition shown is one with an available copy if possible, else the oldest.
+func (s *BookService) collapseByWork(ids *gorm.DB) *gorm.DB {
+	return s.db.Table("books").
+		Select("DISTINCT ON (COALESCE(books.work_id, books.id)) books.id").
+		Where("books.id IN (?)", ids).
+		Order(`COALESCE(books.work_id, books.id),
+			EXISTS (SELECT 1 FROM book_copies bc WHERE bc.book_id = books.id AND bc.status = 'available' AND bc.deleted_at IS NULL) DESC,
+			books.created_at`)
+}
+
+// setEditionCounts fills EditionCount on books that belong to a work
+func (s *BookService) setEditionCounts(books []models.Book) error {
+	var workIDs []uuid.UUID
+	for _, book := range books {
+		if book.WorkID != nil {
+			workIDs = append(workIDs, *book.WorkID)
+		}
+	}
+	if len(workIDs) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		WorkID uuid.UUID
+		Count  int64
+	}
+	if err := s.db.Model(&models.Book{}).
+		Select("work_id, COUNT(*) AS count").
+		Where("work_id IN ?", workIDs).
+		Group("work_id").
+		Scan(&rows).Error; err != nil {
+		zap.L().Error("setEditionCounts: Failed to count editions", zap.Error(err))
+		return err
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.WorkID] = row.Count
+	}
+	for i := range books {
+		if books[i].WorkID != nil {
+			books[i].EditionCount = counts[*books[i].WorkID]
+		}
+	}
+	return nil
+}
+
+// loadEditions lists the editions of a work with their copy counts, newest
+// first, leaving out exclude
+func loadEditions(db *gorm.DB, workID, exclude uuid.UUID) ([]models.BookEdition, error) {
+	var books []models.Book
+	if err := db.Where("work_id = ? AND id <> ?", workID, exclude).
+		Order("published_year DESC, created_at").
+		Find(&books).Error; err != nil {
+		return nil, err
+	}
+	counts, err := countCopies(db, books)
+	if err != nil {
+		return nil, err
+	}
+
+	editions := make([]models.BookEdition, len(books))
+	for i, book := range books {
+		editions[i] = models.BookEdition{
+			ID:              book.ID,
+			Title:           book.Title,
+			Subtitle:        book.Subtitle,
+			ISBN13:          book.ISBN13,
+			PublishedYear:   book.PublishedYear,
+			Publisher:       book.Publisher,
+			MainImage:       book.MainImage,
+			Format:          book.Format,
+			Language:        book.Language,
+			TotalCopies:     counts[book.ID].Total,
+			AvailableCopies: counts[book.ID].Available,
+		}
+	}
+	return editions, nil
+}
+
+// attachEditions points the books at the work. Unknown book IDs are reported
+// as a FieldErrors on "book_ids".
+func attachEditions(tx *gorm.DB, workID uuid.UUID, bookIDs []string, actor models.Actor) error {
+	ids := make(map[uuid.UUID]bool, len(bookIDs))
+	for _, raw := range bookIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return FieldErrors{"book_ids": fmt.Sprintf("invalid book ID %q", raw)}
+		}
+		ids[id] = true
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	unique := make([]uuid.UUID, 0, len(ids))
+	for id := range ids {
+		unique = append(unique, id)
+	}
+	var books []models.Book
+	if err := tx.Where("id IN ?", unique).Find(&books).Error; err != nil {
+		return err
+	}
+	if len(books) != len(unique) {
+		return FieldErrors{"book_ids": "one or more books not found"}
+	}
+	return setBooksWork(tx, books, &workID, actor)
+}
+
+// setBooksWork moves the books to a work, or out of any work when workID is
+// nil, recording each change in the audit log
+func setBooksWork(tx *gorm.DB, books []models.Book, workID *uuid.UUID, actor models.Actor) error {
+	for _, book := range books {
+		before := book
+		if err := tx.Model(&book).Update("work_id", workID).Error; err != nil {
+			return err
+		}
+		book.WorkID = workID
+		if err := recordAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityBooks, book.ID, &before, &book); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkWorkExists rejects a work_id that does not point at a work
+func checkWorkExists(db *gorm.DB, workID *uuid.UUID) error {
+	if workID == nil {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Work{}).Where("id = ?", *workID).Count(&count).Error; err != nil {
+		zap.L().Error("checkWorkExists: Failed to look up work", zap.String("workID", workID.String()), zap.Error(err))
+		return err
+	}
+	if count == 0 {
+		return FieldErrors{"work_id": "work not found"}
+	}
+	return nil
+}
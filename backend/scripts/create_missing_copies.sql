@@ -1,8 +1,9 @@
 -- Create a like new copy for books that don't have any copies
-INSERT INTO book_copies (id, book_id, condition, status, created_at, updated_at)
+INSERT INTO book_copies (id, book_id, accession_number, condition, status, created_at, updated_at)
 SELECT 
     gen_random_uuid(), -- Generate a new UUID for each copy
     b.id as book_id,   -- Book ID
+    'PSC' || LPAD(nextval('book_copy_accession_seq')::text, 6, '0') as accession_number, -- Next accession number
     'like_new' as condition, -- Set condition to like_new
     'available' as status,   -- Set status to available
     NOW() as created_at,     -- Set creation timestamp
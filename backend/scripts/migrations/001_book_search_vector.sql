@@ -0,0 +1,125 @@
+-- Weighted full-text search over books.
+-- Title (A), subtitle and authors (B), publisher and tags (C), description (D)
+-- are folded into books.search_vector, which is kept up to date by triggers.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- "simple" tokenizer with diacritics stripped, so "Nguyễn" and "nguyen" match
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'simple_unaccent') THEN
+        CREATE TEXT SEARCH CONFIGURATION simple_unaccent (COPY = simple);
+        ALTER TEXT SEARCH CONFIGURATION simple_unaccent
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+    END IF;
+END
+$$;
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION book_search_document(
+    p_id UUID, p_title TEXT, p_subtitle TEXT, p_publisher TEXT, p_description TEXT
+) RETURNS tsvector
+LANGUAGE sql STABLE AS $$
+    SELECT setweight(to_tsvector('simple_unaccent', coalesce(p_title, '')), 'A')
+        || setweight(to_tsvector('simple_unaccent', coalesce(p_subtitle, '')), 'B')
+        || setweight(to_tsvector('simple_unaccent', coalesce((
+               SELECT string_agg(a.name, ' ')
+               FROM book_authors ba
+               JOIN authors a ON a.id = ba.author_id
+               WHERE ba.book_id = p_id AND a.deleted_at IS NULL
+           ), '')), 'B')
+        || setweight(to_tsvector('simple_unaccent', coalesce(p_publisher, '')), 'C')
+        || setweight(to_tsvector('simple_unaccent', coalesce((
+               SELECT string_agg(t.name, ' ')
+               FROM book_tags bt
+               JOIN tags t ON t.id = bt.tag_id
+               WHERE bt.book_id = p_id AND t.deleted_at IS NULL
+           ), '')), 'C')
+        || setweight(to_tsvector('simple_unaccent', coalesce(p_description, '')), 'D')
+$$;
+
+-- Book columns changed: recompute in place
+CREATE OR REPLACE FUNCTION books_search_vector_trigger() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := book_search_document(NEW.id, NEW.title, NEW.subtitle, NEW.publisher, NEW.description);
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_books_search_vector ON books;
+CREATE TRIGGER trg_books_search_vector
+    BEFORE INSERT OR UPDATE OF title, subtitle, publisher, description ON books
+    FOR EACH ROW EXECUTE FUNCTION books_search_vector_trigger();
+
+-- Author/tag links changed: recompute the linked book
+CREATE OR REPLACE FUNCTION book_links_search_vector_trigger() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    v_book_id UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_book_id := OLD.book_id;
+    ELSE
+        v_book_id := NEW.book_id;
+    END IF;
+
+    UPDATE books
+    SET search_vector = book_search_document(id, title, subtitle, publisher, description)
+    WHERE id = v_book_id;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_book_authors_search_vector ON book_authors;
+CREATE TRIGGER trg_book_authors_search_vector
+    AFTER INSERT OR UPDATE OR DELETE ON book_authors
+    FOR EACH ROW EXECUTE FUNCTION book_links_search_vector_trigger();
+
+DROP TRIGGER IF EXISTS trg_book_tags_search_vector ON book_tags;
+CREATE TRIGGER trg_book_tags_search_vector
+    AFTER INSERT OR UPDATE OR DELETE ON book_tags
+    FOR EACH ROW EXECUTE FUNCTION book_links_search_vector_trigger();
+
+-- Author or tag renamed/deleted: recompute every book that references it
+CREATE OR REPLACE FUNCTION authors_search_vector_trigger() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE books b
+    SET search_vector = book_search_document(b.id, b.title, b.subtitle, b.publisher, b.description)
+    FROM book_authors ba
+    WHERE ba.book_id = b.id AND ba.author_id = NEW.id;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_authors_search_vector ON authors;
+CREATE TRIGGER trg_authors_search_vector
+    AFTER UPDATE OF name, deleted_at ON authors
+    FOR EACH ROW EXECUTE FUNCTION authors_search_vector_trigger();
+
+CREATE OR REPLACE FUNCTION tags_search_vector_trigger() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE books b
+    SET search_vector = book_search_document(b.id, b.title, b.subtitle, b.publisher, b.description)
+    FROM book_tags bt
+    WHERE bt.book_id = b.id AND bt.tag_id = NEW.id;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_tags_search_vector ON tags;
+CREATE TRIGGER trg_tags_search_vector
+    AFTER UPDATE OF name, deleted_at ON tags
+    FOR EACH ROW EXECUTE FUNCTION tags_search_vector_trigger();
+
+-- Backfill existing rows
+UPDATE books
+SET search_vector = book_search_document(id, title, subtitle, publisher, description);
+
+CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector);
+
+COMMIT;
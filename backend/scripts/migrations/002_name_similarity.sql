@@ -0,0 +1,21 @@
+-- Duplicate detection for authors and categories.
+-- Names are compared after folding case, accents and whitespace, and by
+-- trigram similarity of the folded form.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- unaccent() is only STABLE because its dictionary can change; pinning the
+-- dictionary makes the wrapper safe to use in expression indexes
+CREATE OR REPLACE FUNCTION normalize_name(p_name TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT lower(regexp_replace(btrim(public.unaccent('public.unaccent'::regdictionary, coalesce(p_name, ''))), '\s+', ' ', 'g'))
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_authors_name_trgm
+    ON authors USING GIN (normalize_name(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_categories_name_trgm
+    ON categories USING GIN (normalize_name(name) gin_trgm_ops);
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Run history of the in-process job scheduler
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id            UUID PRIMARY KEY,
+    job_name      VARCHAR(100) NOT NULL,
+    trigger       VARCHAR(20)  NOT NULL,
+    triggered_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+    status        VARCHAR(20)  NOT NULL,
+    started_at    TIMESTAMPTZ  NOT NULL,
+    finished_at   TIMESTAMPTZ,
+    rows_affected BIGINT       NOT NULL DEFAULT 0,
+    error         TEXT         NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_name_started_at ON job_runs (job_name, started_at DESC);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Works group the editions (formats, languages, printings) of the same title.
+-- Books without a work are shown as standalone titles.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS works (
+    id          UUID PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_works_deleted_at ON works (deleted_at);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id UUID REFERENCES works(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_books_work_id ON books (work_id);
+
+COMMIT;
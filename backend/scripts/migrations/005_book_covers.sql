@@ -0,0 +1,8 @@
+-- Uploaded book covers. cover_key is the blob store prefix of the current
+-- upload; cover_images maps each resized size to its public URL.
+BEGIN;
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover_images JSONB;
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Indexes backing cursor pagination, which orders by the sort field with the
+-- row ID as tie-breaker.
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_books_title_id ON books (title, id);
+CREATE INDEX IF NOT EXISTS idx_books_created_at_id ON books (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_reservations_created_at_id ON reservations (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_reservations_user_created_at_id ON reservations (user_id, created_at, id);
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- "Patrons who borrowed this also borrowed" pairs, rebuilt by the
+-- co_borrow_refresh job from the reservation history
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS book_co_borrows (
+    book_id         UUID        NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    related_book_id UUID        NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    score           INTEGER     NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (book_id, related_book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_co_borrows_book_id_score ON book_co_borrows (book_id, score DESC);
+
+COMMIT;
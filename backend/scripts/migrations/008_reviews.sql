@@ -0,0 +1,26 @@
+-- Patron ratings and reviews. books.average_rating and books.review_count
+-- cache the approved reviews of each book so listings can sort by rating.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id           UUID PRIMARY KEY,
+    book_id      UUID        NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    user_id      UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating       INTEGER     NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    body         TEXT        NOT NULL DEFAULT '',
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending',
+    moderated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    moderated_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_book_user ON reviews (book_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews (status);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS average_rating NUMERIC(3, 2) NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS review_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_books_average_rating ON books (average_rating, review_count);
+
+COMMIT;
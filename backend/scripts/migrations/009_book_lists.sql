@@ -0,0 +1,27 @@
+-- Named reading lists and wishlists of books kept by users
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS book_lists (
+    id          UUID PRIMARY KEY,
+    user_id     UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name        VARCHAR(100) NOT NULL,
+    description TEXT         NOT NULL DEFAULT '',
+    is_public   BOOLEAN      NOT NULL DEFAULT FALSE,
+    share_token VARCHAR(64)  NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL,
+    updated_at  TIMESTAMPTZ  NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_lists_user_name ON book_lists (user_id, name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_lists_share_token ON book_lists (share_token);
+
+CREATE TABLE IF NOT EXISTS book_list_items (
+    id           UUID PRIMARY KEY,
+    book_list_id UUID        NOT NULL REFERENCES book_lists(id) ON DELETE CASCADE,
+    book_id      UUID        NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_list_items_list_book ON book_list_items (book_list_id, book_id);
+
+COMMIT;
@@ -0,0 +1,32 @@
+-- Append-only history of catalog and reservation changes. Rows keep no
+-- foreign keys so that they survive purged entities and deleted users, and a
+-- trigger rejects any attempt to rewrite them.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          UUID PRIMARY KEY,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id   UUID        NOT NULL,
+    action      VARCHAR(20) NOT NULL,
+    actor_id    UUID,
+    actor_email TEXT        NOT NULL DEFAULT '',
+    changes     JSONB,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity_type, entity_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_logs_append_only ON audit_logs;
+CREATE TRIGGER trg_audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Parent/child relationships between categories. Loops are rejected by the
+-- application; the check only guards against a category being its own parent.
+BEGIN;
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS chk_categories_parent_not_self;
+ALTER TABLE categories ADD CONSTRAINT chk_categories_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Trigram indexes for the typeahead suggestions. Authors and categories
+-- already have theirs from 002_name_similarity.sql.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_books_title_trgm
+    ON books USING GIN (normalize_name(title) gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tags_name_trgm
+    ON tags USING GIN (normalize_name(name) gin_trgm_ops);
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- Human-readable accession numbers for book copies, drawn from a sequence.
+-- Existing copies are numbered in the order they were added, using the
+-- default ACCESSION_PREFIX and ACCESSION_DIGITS.
+BEGIN;
+
+CREATE SEQUENCE IF NOT EXISTS book_copy_accession_seq;
+
+ALTER TABLE book_copies ADD COLUMN IF NOT EXISTS accession_number VARCHAR(30);
+
+WITH numbered AS (
+    SELECT id, nextval('book_copy_accession_seq') AS n
+    FROM (SELECT id FROM book_copies WHERE accession_number IS NULL ORDER BY created_at, id) ordered
+)
+UPDATE book_copies c
+SET accession_number = 'PSC' || LPAD(numbered.n::text, 6, '0')
+FROM numbered
+WHERE c.id = numbered.id;
+
+ALTER TABLE book_copies ALTER COLUMN accession_number SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_copies_accession_number ON book_copies (accession_number);
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- When each copy of a reservation was handed out and brought back at the
+-- desk, so that a reservation is only closed once all its copies are back.
+BEGIN;
+
+ALTER TABLE reservation_book_copies ADD COLUMN IF NOT EXISTS checked_out_at TIMESTAMPTZ;
+ALTER TABLE reservation_book_copies ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMPTZ;
+
+-- Copies of reservations already returned are back
+UPDATE reservation_book_copies rbc
+SET checked_in_at = r.updated_at
+FROM reservations r
+WHERE r.id = rbc.reservation_id AND r.status = 'returned' AND rbc.checked_in_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reservation_book_copies_book_copy_id ON reservation_book_copies (book_copy_id);
+
+COMMIT;
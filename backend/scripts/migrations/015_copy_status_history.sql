@@ -0,0 +1,22 @@
+-- Copies can leave circulation as lost, damaged, in repair or withdrawn, and
+-- every status change is recorded with its reason and the acting user.
+BEGIN;
+
+ALTER TABLE book_copies DROP CONSTRAINT IF EXISTS chk_book_copies_status;
+ALTER TABLE book_copies ADD CONSTRAINT chk_book_copies_status
+    CHECK (status IN ('available', 'reserved', 'borrowed', 'lost', 'damaged', 'in_repair', 'withdrawn'));
+
+CREATE TABLE IF NOT EXISTS book_copy_status_changes (
+    id           UUID PRIMARY KEY,
+    book_copy_id UUID        NOT NULL REFERENCES book_copies(id) ON DELETE CASCADE,
+    from_status  VARCHAR(20) NOT NULL,
+    to_status    VARCHAR(20) NOT NULL,
+    reason       TEXT        NOT NULL,
+    actor_id     UUID,
+    actor_email  TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_copy_status_changes_copy ON book_copy_status_changes (book_copy_id, created_at);
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- Condition inspections of book copies, with the patron who last borrowed the
+-- copy so that damage can be traced back to them.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS copy_inspections (
+    id                 UUID PRIMARY KEY,
+    book_copy_id       UUID        NOT NULL REFERENCES book_copies(id) ON DELETE CASCADE,
+    reservation_id     UUID        REFERENCES reservations(id) ON DELETE SET NULL,
+    user_id            UUID        REFERENCES users(id) ON DELETE SET NULL,
+    previous_condition VARCHAR(20),
+    condition          VARCHAR(20) NOT NULL,
+    damaged            BOOLEAN     NOT NULL DEFAULT FALSE,
+    notes              TEXT        NOT NULL DEFAULT '',
+    photos             TEXT[],
+    inspector_id       UUID,
+    inspector_email    TEXT        NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_copy_inspections_copy ON copy_inspections (book_copy_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_copy_inspections_user_id ON copy_inspections (user_id) WHERE damaged;
+
+COMMIT;